@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/config"
+	"vinzhub-rest-api-v2/internal/repository"
+)
+
+const backfillUsage = `usage: vinzhub-api backfill-json [flags]
+
+Stores the JSON of MongoDB inventories and tombstones written before
+MONGODB_KEEP_ORIGINAL_JSON was enabled, as it reads today.
+
+Flags:
+`
+
+// runBackfillJSON implements the backfill-json subcommand for the MongoDB
+// inventory collection of every configured game, or of the one chosen with -game.
+func runBackfillJSON(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("backfill-json", flag.ContinueOnError)
+	game := flags.String("game", "", "backfill only this game's collection")
+	timeout := flags.Duration("timeout", time.Hour, "give up after this long")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), backfillUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if cfg.InventoryDB.Type != "mongodb" && cfg.InventoryDB.Type != "mongo" {
+		return fmt.Errorf("backfill-json applies to the mongodb backend, not %s", cfg.InventoryDB.Type)
+	}
+	if !cfg.InventoryDB.MongoKeepJSON {
+		return fmt.Errorf("MONGODB_KEEP_ORIGINAL_JSON is off, so the next write would drop backfilled JSON")
+	}
+
+	games := cfg.Games.List()
+	if *game != "" {
+		games = []string{*game}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	for _, g := range games {
+		dbCfg := cfg.InventoryDB.ForGame(g)
+		repo, err := repository.NewMongoDBInventoryRepository(dbCfg.MongoURI, dbCfg.MongoDatabase, dbCfg.MongoCollection)
+		if err != nil {
+			return fmt.Errorf("game %s: %w", g, err)
+		}
+		repo.SetKeepOriginalJSON(true)
+
+		filled, err := repo.BackfillOriginalJSON(ctx)
+		repo.Close()
+		if err != nil {
+			return fmt.Errorf("game %s: %w", g, err)
+		}
+		fmt.Printf("%s: stored original JSON for %d documents\n", g, filled)
+	}
+	return nil
+}
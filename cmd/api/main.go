@@ -12,12 +12,16 @@ import (
 	"time"
 
 	"vinzhub-rest-api-v2/internal/cache"
+	"vinzhub-rest-api-v2/internal/catalog"
 	"vinzhub-rest-api-v2/internal/config"
 	"vinzhub-rest-api-v2/internal/handler"
 	"vinzhub-rest-api-v2/internal/middleware"
+	"vinzhub-rest-api-v2/internal/model"
 	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/internal/retention"
 	"vinzhub-rest-api-v2/internal/router"
 	"vinzhub-rest-api-v2/internal/service"
+	"vinzhub-rest-api-v2/internal/webhook"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/redis/go-redis/v9"
@@ -29,43 +33,31 @@ func main() {
 
 	// Load configuration
 	cfg := config.MustLoad()
-	log.Printf("Environment: %s", cfg.App.Environment)
 
-	// Initialize inventory repository based on config
-	var inventoryRepo repository.InventoryRepository
-	switch cfg.InventoryDB.Type {
-	case "mongodb", "mongo":
-		mongoRepo, err := repository.NewMongoDBInventoryRepository(
-			cfg.InventoryDB.MongoURI,
-			cfg.InventoryDB.MongoDatabase,
-			cfg.InventoryDB.MongoCollection,
-		)
-		if err != nil {
-			log.Fatalf("Failed to initialize MongoDB: %v", err)
-		}
-		defer mongoRepo.Close()
-		inventoryRepo = mongoRepo
-		log.Println("MongoDB inventory repository initialized")
-	case "postgres", "postgresql":
-		pgRepo, err := repository.NewPostgresInventoryRepository(cfg.InventoryDB.PostgresDSN())
-		if err != nil {
-			log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+	// `vinzhub-api migrate ...` manages the inventory schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
 		}
-		defer pgRepo.Close()
-		inventoryRepo = pgRepo
-		log.Println("PostgreSQL inventory repository initialized")
-	default: // sqlite
-		sqliteRepo, err := repository.NewSQLiteInventoryRepository(cfg.InventoryDB.Path)
-		if err != nil {
-			log.Fatalf("Failed to initialize SQLite: %v", err)
+		return
+	}
+	// `vinzhub-api backfill-json` stores original JSON for older MongoDB documents
+	if len(os.Args) > 1 && os.Args[1] == "backfill-json" {
+		if err := runBackfillJSON(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Backfill failed: %v", err)
 		}
-		defer sqliteRepo.Close()
-		inventoryRepo = sqliteRepo
-		log.Println("SQLite inventory repository initialized")
+		return
+	}
+	log.Printf("Environment: %s", cfg.App.Environment)
+
+	// Initialize inventory repository based on config
+	inventoryRepo, closeInventoryRepo, err := openInventoryRepository(cfg.InventoryDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize inventory repository: %v", err)
 	}
+	defer closeInventoryRepo()
 
 	// Initialize MySQL connection for key accounts (optional)
-	var err error
 	var mysqlDB *sql.DB
 	var keyAccountRepo *repository.MySQLKeyAccountRepository
 
@@ -92,6 +84,11 @@ func main() {
 	if mysqlDB != nil {
 		defer mysqlDB.Close()
 	}
+	// Services check for a nil interface, not a nil *MySQLKeyAccountRepository
+	var keyAccounts repository.KeyAccountRepository
+	if keyAccountRepo != nil {
+		keyAccounts = keyAccountRepo
+	}
 
 	// Initialize Redis client
 	redisAddr := cfg.Cache.RedisAddress()
@@ -110,30 +107,116 @@ func main() {
 	}
 	cancel()
 
-	// Initialize Redis inventory buffer
-	var redisBuffer *cache.RedisInventoryBuffer
+	// Item catalog (names, tiers, sell prices) for enrichment, valuation and admin updates
+	itemCatalog, err := catalog.Load(cfg.Catalog.Path)
+	if err != nil {
+		log.Printf("Warning: item catalog unavailable: %v", err)
+	}
+	valuator := service.NewValuator(itemCatalog, service.ValuationConfig{
+		ShinyMultiplier:    cfg.Valuation.ShinyMultiplier,
+		VariantMultipliers: cfg.Valuation.VariantMultipliers,
+	})
+
+	// Hooks run after inventories are persisted (buffer flush or direct write)
+	var persistHooks []service.PersistHook
+	historyRepo, historySupported := inventoryRepo.(repository.InventoryHistoryRepository)
+	if cfg.History.Enabled && historySupported {
+		persistHooks = append(persistHooks, service.NewHistoryRecorder(historyRepo))
+		log.Printf("Inventory history enabled (retention: %v)", cfg.History.Retention)
+	}
+	if valueRepo, ok := inventoryRepo.(repository.InventoryValueRepository); ok {
+		persistHooks = append(persistHooks, service.NewValueRecorder(valueRepo, valuator))
+	}
+
+	// Leaderboards live in Redis sorted sets, updated as inventories are persisted
+	var leaderboardService *service.LeaderboardService
 	if redisClient != nil {
-		bufferCfg := cache.RedisBufferConfig{
-			Addr:          redisAddr,
-			Password:      cfg.Cache.RedisPassword,
-			DB:            cfg.Cache.RedisDB,
-			FlushInterval: 30 * time.Second,
+		leaderboardService = service.NewLeaderboardService(redisClient, inventoryRepo, valuator)
+		persistHooks = append(persistHooks, leaderboardService.PersistHook())
+	}
+
+	// "rebuild-leaderboards" recomputes all leaderboards from the database and exits
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-leaderboards" {
+		if leaderboardService == nil {
+			log.Fatal("Leaderboard rebuild requires Redis")
 		}
-		flushFunc := service.CreateFlushFunc(inventoryRepo)
-		redisBuffer, err = cache.NewRedisInventoryBuffer(bufferCfg, flushFunc)
+		ranked, err := leaderboardService.Rebuild(context.Background())
 		if err != nil {
-			log.Printf("Warning: Redis buffer initialization failed: %v", err)
-		} else {
-			log.Println("Redis inventory buffer initialized")
+			log.Fatalf("Leaderboard rebuild failed: %v", err)
 		}
+		log.Printf("Leaderboards rebuilt for %d users", ranked)
+		return
+	}
+
+	// Initialize inventory service, buffered through Redis when available
+	inventoryService, redisBuffer := newInventoryService(cfg, config.DefaultGame, inventoryRepo, keyAccounts, redisClient != nil, persistHooks)
+	if cfg.History.Enabled && historySupported {
+		inventoryService.SetHistoryRepository(historyRepo)
+	}
+
+	if itemCatalog != nil {
+		inventoryService.SetCatalog(itemCatalog)
 	}
+	inventoryService.SetValuator(valuator)
 
-	// Initialize services
-	var inventoryService *service.InventoryService
-	if redisBuffer != nil {
-		inventoryService = service.NewInventoryServiceWithBuffer(inventoryRepo, keyAccountRepo, redisBuffer)
+	// Rare catches (and any other subscribed events) are pushed to admin-registered webhooks
+	var webhookHandler *handler.WebhookHandler
+	webhookStore, err := webhook.LoadStore(cfg.Webhooks.Path)
+	if err != nil {
+		log.Printf("Warning: webhooks unavailable: %v", err)
 	} else {
-		inventoryService = service.NewInventoryService(inventoryRepo, keyAccountRepo)
+		dispatcher := webhook.NewDispatcher(webhookStore, webhook.Config{
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			Backoff:     cfg.Webhooks.RetryBackoff,
+			Timeout:     cfg.Webhooks.Timeout,
+			Workers:     cfg.Webhooks.Workers,
+			LogSize:     cfg.Webhooks.LogSize,
+		})
+		defer dispatcher.Close()
+		inventoryService.SetRareTier(model.Tier(cfg.Webhooks.RareTier))
+		inventoryService.AddEventHook(dispatcher.Notify)
+		webhookHandler = handler.NewWebhookHandler(webhookStore, dispatcher)
+	}
+
+	// Retention: pinned users are never purged; key tiers need MySQL
+	pins, err := retention.LoadPins(cfg.Retention.PinsPath)
+	if err != nil {
+		log.Fatalf("Failed to load pinned users: %v", err)
+	}
+	retentionDeps := retentionDeps{pins: pins, valuator: valuator, leaderboards: leaderboardService}
+	if keyAccountRepo != nil {
+		retentionDeps.keyTiers = keyAccountRepo
+	}
+	cleanupSchedulers := make(map[string]*service.CleanupScheduler)
+
+	// Other games get their own storage, buffer and cleanup. Catalog features
+	// (valuation, leaderboards, enrichment) are fishit-only.
+	buffers := []*cache.RedisInventoryBuffer{redisBuffer}
+	gameServices := make(map[string]*service.InventoryService)
+	for _, game := range cfg.Games.List()[1:] {
+		repo, closeRepo, err := openInventoryRepository(cfg.InventoryDB.ForGame(game))
+		if err != nil {
+			log.Fatalf("Failed to initialize inventory repository for game %s: %v", game, err)
+		}
+		defer closeRepo()
+
+		var hooks []service.PersistHook
+		gameHistoryRepo, ok := repo.(repository.InventoryHistoryRepository)
+		if cfg.History.Enabled && ok {
+			hooks = append(hooks, service.NewHistoryRecorder(gameHistoryRepo))
+		}
+		gameService, gameBuffer := newInventoryService(cfg, game, repo, keyAccounts, redisClient != nil, hooks)
+		if cfg.History.Enabled && ok {
+			gameService.SetHistoryRepository(gameHistoryRepo)
+		}
+		buffers = append(buffers, gameBuffer)
+		gameServices[game] = gameService
+
+		gameCleanup := newCleanupScheduler(cfg, game, repo, retentionDeps)
+		gameCleanup.Start()
+		defer gameCleanup.Stop()
+		cleanupSchedulers[game] = gameCleanup
+		log.Printf("Game %s initialized (schema: %s)", game, cfg.Games.Schema(game))
 	}
 
 	var tokenService *service.TokenService
@@ -142,14 +225,24 @@ func main() {
 	}
 
 	// Initialize cleanup scheduler for inactive inventory data
-	cleanupScheduler := service.NewCleanupScheduler(inventoryRepo, service.DefaultCleanupConfig())
+	cleanupScheduler := newCleanupScheduler(cfg, config.DefaultGame, inventoryRepo, retentionDeps)
 	cleanupScheduler.Start()
 	defer cleanupScheduler.Stop()
 
 	// Initialize handlers
 	healthHandler := handler.New()
 	inventoryHandler := handler.NewInventoryHandler(inventoryService)
+	inventoryHandler.AddGame(config.DefaultGame, inventoryService)
+	for game, gameService := range gameServices {
+		inventoryHandler.AddGame(game, gameService)
+	}
 	adminHandler := handler.NewAdminHandler(redisBuffer, inventoryRepo, cfg.InventoryDB.Type, cfg.App.LoginKey)
+	adminHandler.SetInventoryService(inventoryService)
+	retentionHandler := handler.NewRetentionHandler(cleanupScheduler, pins)
+	retentionHandler.AddGame(config.DefaultGame, cleanupScheduler)
+	for game, gameCleanup := range cleanupSchedulers {
+		retentionHandler.AddGame(game, gameCleanup)
+	}
 
 	var authHandler *handler.AuthHandler
 	if tokenService != nil && keyAccountRepo != nil {
@@ -159,6 +252,7 @@ func main() {
 	// Create auth middleware with injected dependencies (NO GLOBALS!)
 	authMiddleware := middleware.NewAuthMiddleware(middleware.AuthConfig{
 		TokenService: tokenService,
+		LoginKey:     cfg.App.LoginKey,
 	})
 
 	// Initialize obfuscation handler
@@ -185,10 +279,70 @@ func main() {
 		defer logRepo.Close()
 	}
 
-	obfuscationHandler := handler.NewObfuscationHandler(foxzyPath, fileUploaderURL, logRepo, redisClient)
+	// A failed connection leaves logRepo as a typed nil; handlers get a nil interface instead
+	var logs repository.LogRepository
+	var tradeHandler *handler.TradeHandler
+	if err == nil {
+		logs = logRepo
+		if cfg.EventLog.Enabled {
+			indexCtx, cancelIndex := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := logRepo.EnsureInventoryEventIndexes(indexCtx, cfg.EventLog.Retention); err != nil {
+				log.Printf("Warning: Failed to create inventory event indexes: %v", err)
+			}
+			cancelIndex()
+			inventoryService.SetEventLog(logRepo)
+			log.Printf("Inventory event log enabled (retention=%v)", cfg.EventLog.Retention)
+
+			// Transfers are matched against the event log, so they need it too
+			if cfg.Trades.Enabled {
+				indexCtx, cancelIndex := context.WithTimeout(context.Background(), 10*time.Second)
+				if err := logRepo.EnsureTradeIndexes(indexCtx); err != nil {
+					log.Printf("Warning: Failed to create item transfer indexes: %v", err)
+				}
+				cancelIndex()
+				tradeAnalyzer := service.NewTradeAnalyzer(logRepo, logRepo, cfg.Trades.MatchWindow)
+				defer tradeAnalyzer.Close()
+				inventoryService.AddEventHook(tradeAnalyzer.EventHook())
+				tradeHandler = handler.NewTradeHandler(tradeAnalyzer)
+				log.Printf("Trade detection enabled (match window=%v)", cfg.Trades.MatchWindow)
+			}
+		}
+	}
+
+	obfuscationHandler := handler.NewObfuscationHandler(foxzyPath, fileUploaderURL, logs, redisClient)
 
 	// Initialize log handler
-	logHandler := handler.NewLogHandler(logRepo, inventoryService)
+	logHandler := handler.NewLogHandler(logs, inventoryService)
+
+	// User data export and erasure reach every store holding user data
+	userDataService := service.NewUserDataService()
+	userDataService.AddGame(config.DefaultGame, inventoryService)
+	for game, gameService := range gameServices {
+		userDataService.AddGame(game, gameService)
+	}
+	if tokenService != nil {
+		userDataService.SetTokenService(tokenService)
+	}
+	if keyAccountRepo != nil {
+		userDataService.SetKeyAccounts(keyAccountRepo)
+	}
+	if logs != nil {
+		userDataService.SetLogStore(logRepo)
+	}
+	if leaderboardService != nil {
+		userDataService.SetLeaderboards(leaderboardService)
+	}
+	userDataHandler := handler.NewUserDataHandler(userDataService)
+
+	var catalogHandler *handler.CatalogHandler
+	if itemCatalog != nil {
+		catalogHandler = handler.NewCatalogHandler(itemCatalog)
+	}
+
+	var leaderboardHandler *handler.LeaderboardHandler
+	if leaderboardService != nil {
+		leaderboardHandler = handler.NewLeaderboardHandler(leaderboardService)
+	}
 
 	// Create router
 	r := router.New(router.Config{
@@ -198,7 +352,14 @@ func main() {
 		AuthHandler:        authHandler,
 		ObfuscationHandler: obfuscationHandler,
 		LogHandler:         logHandler,
+		LeaderboardHandler: leaderboardHandler,
+		CatalogHandler:     catalogHandler,
+		WebhookHandler:     webhookHandler,
+		TradeHandler:       tradeHandler,
+		RetentionHandler:   retentionHandler,
+		UserDataHandler:    userDataHandler,
 		AuthMiddleware:     authMiddleware,
+		MaxBodySize:        cfg.Server.MaxBodySize,
 	})
 
 	// Create HTTP server
@@ -226,10 +387,12 @@ func main() {
 	ctx, cancel = context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Close Redis buffer first (flushes pending data)
-	if redisBuffer != nil {
-		log.Println("Closing Redis buffer...")
-		redisBuffer.Close()
+	// Close Redis buffers first (flushes pending data)
+	for _, buffer := range buffers {
+		if buffer != nil {
+			log.Println("Closing Redis buffer...")
+			buffer.Close()
+		}
 	}
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -239,3 +402,138 @@ func main() {
 	log.Println("Server stopped")
 	fmt.Println("Goodbye!")
 }
+
+// openInventoryRepository opens the inventory store selected by dbCfg.Type.
+// The returned func closes it.
+func openInventoryRepository(dbCfg config.InventoryDBConfig) (repository.InventoryRepository, func() error, error) {
+	switch dbCfg.Type {
+	case "mongodb", "mongo":
+		mongoRepo, err := repository.NewMongoDBInventoryRepository(
+			dbCfg.MongoURI,
+			dbCfg.MongoDatabase,
+			dbCfg.MongoCollection,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("MongoDB: %w", err)
+		}
+		mongoRepo.SetKeepOriginalJSON(dbCfg.MongoKeepJSON)
+		log.Printf("MongoDB inventory repository initialized (collection: %s)", dbCfg.MongoCollection)
+		return mongoRepo, mongoRepo.Close, nil
+	case "postgres", "postgresql":
+		pgRepo, err := repository.NewPostgresInventoryRepository(dbCfg.PostgresDSN(), dbCfg.Schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("PostgreSQL: %w", err)
+		}
+		log.Println("PostgreSQL inventory repository initialized")
+		return pgRepo, pgRepo.Close, nil
+	case "memory":
+		memoryRepo, err := repository.NewMemoryInventoryRepository(dbCfg.SnapshotPath, dbCfg.SnapshotInterval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("memory: %w", err)
+		}
+		log.Println("In-memory inventory repository initialized")
+		return memoryRepo, memoryRepo.Close, nil
+	default: // sqlite
+		sqliteRepo, err := repository.NewSQLiteInventoryRepository(dbCfg.Path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("SQLite: %w", err)
+		}
+		log.Printf("SQLite inventory repository initialized (%s)", dbCfg.Path)
+		return sqliteRepo, sqliteRepo.Close, nil
+	}
+}
+
+// newInventoryService creates the inventory service for game. With Redis,
+// writes go through a buffer keyed by game; hooks run after every write to
+// repo, whether by a flush or directly (such as a restore).
+// The buffer is nil when Redis is unavailable.
+func newInventoryService(
+	cfg *config.Config,
+	game string,
+	repo repository.InventoryRepository,
+	keyAccountRepo repository.KeyAccountRepository,
+	useRedis bool,
+	hooks []service.PersistHook,
+) (*service.InventoryService, *cache.RedisInventoryBuffer) {
+	inventoryService := service.NewInventoryService(repo, keyAccountRepo)
+	for _, hook := range hooks {
+		inventoryService.AddPersistHook(hook)
+	}
+
+	var redisBuffer *cache.RedisInventoryBuffer
+	if useRedis {
+		bufferCfg := cache.RedisBufferConfig{
+			Addr:          cfg.Cache.RedisAddress(),
+			Password:      cfg.Cache.RedisPassword,
+			DB:            cfg.Cache.RedisDB,
+			FlushInterval: 30 * time.Second,
+			KeyPrefix:     "vinzhub:" + game + ":inventory",
+		}
+		var err error
+		redisBuffer, err = cache.NewRedisInventoryBuffer(bufferCfg, inventoryService.FlushFunc())
+		if err != nil {
+			log.Printf("Warning: Redis buffer initialization failed for %s: %v", game, err)
+			redisBuffer = nil
+		} else {
+			inventoryService.SetBuffer(redisBuffer)
+			log.Printf("Redis inventory buffer initialized for %s", game)
+		}
+	}
+
+	if cfg.Games.Schema(game) == config.GameSchemaGeneric {
+		inventoryService.SetPayloadParser(service.ParseGenericPayload)
+	}
+	return inventoryService, redisBuffer
+}
+
+// newCleanupScheduler creates the cleanup scheduler for game's repository.
+func newCleanupScheduler(cfg *config.Config, game string, repo repository.InventoryRepository, retentionDeps retentionDeps) *service.CleanupScheduler {
+	cleanupConfig := service.DefaultCleanupConfig()
+	cleanupConfig.Game = game
+	cleanupConfig.InactiveThreshold = cfg.Games.InactiveThreshold(game, cfg.Retention.InactiveThreshold)
+	cleanupConfig.KeyTierThresholds = cfg.Retention.KeyTierThresholds
+	cleanupConfig.DryRun = cfg.Retention.DryRun
+	cleanupConfig.TombstoneGrace = cfg.Retention.TombstoneGrace
+	if cfg.History.Enabled {
+		cleanupConfig.HistoryRetention = cfg.History.Retention
+	}
+	if game == config.DefaultGame {
+		cleanupConfig.ValueRetention = cfg.Valuation.Retention
+		// Only fishit inventories can be valued
+		valueThresholds, _ := cfg.Retention.ParsedValueThresholds() // validated by config.Load
+		for minValue, threshold := range valueThresholds {
+			cleanupConfig.ValueThresholds = append(cleanupConfig.ValueThresholds, service.ValueThreshold{MinValue: minValue, Threshold: threshold})
+		}
+	}
+
+	scheduler := service.NewCleanupScheduler(repo, cleanupConfig)
+	scheduler.SetPins(retentionDeps.pins)
+	if retentionDeps.keyTiers != nil {
+		scheduler.SetKeyTiers(retentionDeps.keyTiers)
+	}
+	if game == config.DefaultGame && retentionDeps.valuator != nil {
+		scheduler.SetValuator(retentionDeps.valuator)
+	}
+	if game == config.DefaultGame && retentionDeps.leaderboards != nil {
+		scheduler.SetLeaderboards(retentionDeps.leaderboards)
+	}
+	if cfg.Retention.ArchiveDir != "" {
+		archiver := &retention.NDJSONArchiver{Dir: cfg.Retention.ArchiveDir}
+		scheduler.SetArchiver(func(game string, at time.Time) (service.Archive, error) {
+			archive, err := archiver.NewArchive(game, at)
+			if err != nil {
+				return nil, err
+			}
+			return archive, nil
+		})
+	}
+	return scheduler
+}
+
+// retentionDeps are the retention collaborators shared by every game's cleanup scheduler.
+type retentionDeps struct {
+	pins         *retention.Pins
+	keyTiers     repository.KeyTierRepository
+	valuator     *service.Valuator
+	leaderboards *service.LeaderboardService // fishit-only, like the valuator
+}
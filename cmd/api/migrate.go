@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/config"
+	"vinzhub-rest-api-v2/internal/migrate"
+	"vinzhub-rest-api-v2/internal/repository"
+)
+
+const migrateUsage = `usage: vinzhub-api migrate <command> [flags]
+
+Commands:
+  up       apply pending migrations
+  down     revert the latest migrations (-steps, default 1)
+  status   list migrations and whether they are applied
+
+Flags:
+`
+
+// runMigrate implements the migrate subcommand. It migrates the inventory
+// database of every configured game, or of the one chosen with -game.
+func runMigrate(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	game := flags.String("game", "", "migrate only this game's database")
+	steps := flags.Int("steps", 1, "number of migrations to revert (down)")
+	timeout := flags.Duration("timeout", 10*time.Minute, "give up after this long, lock wait included")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+	command := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if command != "up" && command != "down" && command != "status" {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+	if *steps < 1 {
+		return fmt.Errorf("-steps must be at least 1")
+	}
+
+	games := cfg.Games.List()
+	if *game != "" {
+		games = []string{*game}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	for _, g := range games {
+		migrator, closeDB, err := openMigrator(cfg.InventoryDB.ForGame(g))
+		if err != nil {
+			return fmt.Errorf("game %s: %w", g, err)
+		}
+
+		switch command {
+		case "up":
+			var applied []migrate.Migration
+			if applied, err = migrator.Up(ctx); err == nil {
+				fmt.Printf("%s: applied %d migrations\n", g, len(applied))
+			}
+		case "down":
+			var reverted []migrate.Migration
+			if reverted, err = migrator.Down(ctx, *steps); err == nil {
+				fmt.Printf("%s: reverted %d migrations\n", g, len(reverted))
+			}
+		case "status":
+			var statuses []migrate.Status
+			if statuses, err = migrator.Status(ctx); err == nil {
+				printMigrationStatus(g, statuses)
+			}
+		}
+		closeDB()
+		if err != nil {
+			return fmt.Errorf("game %s: %w", g, err)
+		}
+	}
+	return nil
+}
+
+// openMigrator opens the migrator of an inventory database.
+func openMigrator(dbCfg config.InventoryDBConfig) (*migrate.Migrator, func() error, error) {
+	switch dbCfg.Type {
+	case "mongodb", "mongo":
+		return repository.NewMongoDBMigrator(dbCfg.MongoURI, dbCfg.MongoDatabase, dbCfg.MongoCollection)
+	case "postgres", "postgresql":
+		return repository.NewPostgresMigrator(dbCfg.PostgresDSN(), dbCfg.Schema)
+	case "memory":
+		return nil, nil, fmt.Errorf("the memory backend has no schema to migrate")
+	default: // sqlite
+		return repository.NewSQLiteMigrator(dbCfg.Path)
+	}
+}
+
+func printMigrationStatus(game string, statuses []migrate.Status) {
+	fmt.Printf("%s:\n", game)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Unknown:
+			state = "applied (unknown to this version)"
+		case s.Modified:
+			state = "applied (modified since)"
+		case s.Applied:
+			state = "applied"
+		}
+		appliedAt := "-"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "  %04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+}
@@ -18,10 +18,12 @@ const (
 	FlushTimeout       = 120 * time.Second  // 2 minutes for slow connections
 	StaleDataThreshold = 1 * time.Hour
 	CleanupInterval    = 5 * time.Minute
+	ContentHashTTL     = 24 * time.Hour // how long the last synced content hash is remembered
 )
 
-// FlushFunc is called to persist buffered data to database.
-type FlushFunc func(ctx context.Context, items []*model.BufferedInventory) error
+// FlushFunc is called to persist buffered data to database. It reports which
+// items were written and which were rejected; an error means none were.
+type FlushFunc func(ctx context.Context, items []*model.BufferedInventory) (*model.BatchResult, error)
 
 var deleteIfUnchangedScript = redis.NewScript(`
 	if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
@@ -33,6 +35,41 @@ var deleteIfUnchangedScript = redis.NewScript(`
 	end
 `)
 
+var quarantineIfUnchangedScript = redis.NewScript(`
+	if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+		redis.call("HDEL", KEYS[1], ARGV[1])
+		redis.call("SREM", KEYS[2], ARGV[1])
+		redis.call("HSET", KEYS[3], ARGV[1], ARGV[3])
+		return 1
+	else
+		return 0
+	end
+`)
+
+// addIfUnchangedScript buffers ARGV[3] if the entry still matches the token
+// ARGV[2]. A stored token, for an inventory read from the database, also
+// requires the last synced hash, which outlives a flush, to still be its own.
+var addIfUnchangedScript = redis.NewScript(`
+	local current = redis.call("HGET", KEYS[1], ARGV[1])
+	local unchanged
+	if string.sub(ARGV[2], 1, 7) == "stored:" then
+		local last = redis.call("GET", KEYS[3])
+		unchanged = current == false and (last == false or last == string.sub(ARGV[2], 8))
+	else
+		unchanged = (current == false and ARGV[2] == "") or current == ARGV[2]
+	end
+	if unchanged then
+		redis.call("HSET", KEYS[1], ARGV[1], ARGV[3])
+		redis.call("SADD", KEYS[2], ARGV[1])
+		if ARGV[4] ~= "" then
+			redis.call("SET", KEYS[3], ARGV[4], "EX", ARGV[5])
+		end
+		return 1
+	else
+		return 0
+	end
+`)
+
 // RedisInventoryBuffer uses Redis for write-behind caching.
 type RedisInventoryBuffer struct {
 	client        *redis.Client
@@ -102,12 +139,23 @@ func (b *RedisInventoryBuffer) pendingKey() string {
 	return b.keyPrefix + ":pending"
 }
 
-// Add buffers an inventory update in Redis.
-func (b *RedisInventoryBuffer) Add(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte) error {
+// quarantineKey holds the updates the database rejected, by user.
+func (b *RedisInventoryBuffer) quarantineKey() string {
+	return b.keyPrefix + ":quarantine"
+}
+
+func (b *RedisInventoryBuffer) hashKey(robloxUserID string) string {
+	return b.keyPrefix + ":hash:" + robloxUserID
+}
+
+// Add buffers an inventory update in Redis. contentHash is the canonical hash
+// of rawJSON, kept with the entry so reads can answer conditional requests.
+func (b *RedisInventoryBuffer) Add(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, contentHash string) error {
 	data := &model.BufferedInventory{
 		KeyAccountID: keyAccountID,
 		RobloxUserID: robloxUserID,
 		RawJSON:      rawJSON,
+		ContentHash:  contentHash,
 		UpdatedAt:    time.Now(),
 	}
 
@@ -119,6 +167,9 @@ func (b *RedisInventoryBuffer) Add(ctx context.Context, keyAccountID int64, robl
 	pipe := b.client.Pipeline()
 	pipe.HSet(ctx, b.bufferKey(), robloxUserID, jsonData)
 	pipe.SAdd(ctx, b.pendingKey(), robloxUserID)
+	if contentHash != "" {
+		pipe.Set(ctx, b.hashKey(robloxUserID), contentHash, ContentHashTTL)
+	}
 	_, err = pipe.Exec(ctx)
 	return err
 }
@@ -141,12 +192,107 @@ func (b *RedisInventoryBuffer) Get(ctx context.Context, robloxUserID string) (*m
 	return &inv, nil
 }
 
+// GetVersioned retrieves a buffered inventory together with an opaque token
+// identifying the stored entry, for use with AddIfUnchanged. The token is
+// empty when nothing is buffered for the user.
+func (b *RedisInventoryBuffer) GetVersioned(ctx context.Context, robloxUserID string) (*model.BufferedInventory, string, error) {
+	data, err := b.client.HGet(ctx, b.bufferKey(), robloxUserID).Result()
+	if err == redis.Nil {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var inv model.BufferedInventory
+	if err := json.Unmarshal([]byte(data), &inv); err != nil {
+		return nil, "", err
+	}
+
+	return &inv, data, nil
+}
+
+// StoredToken returns the token for AddIfUnchanged of an inventory read from
+// the database rather than the buffer, identified by its content hash.
+func StoredToken(contentHash string) string {
+	return "stored:" + contentHash
+}
+
+// AddIfUnchanged buffers an inventory update only if the user's buffered entry
+// still matches token (as returned by GetVersioned, or StoredToken if nothing
+// was buffered). Reports whether it was written.
+func (b *RedisInventoryBuffer) AddIfUnchanged(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, contentHash, token string) (bool, error) {
+	data := &model.BufferedInventory{
+		KeyAccountID: keyAccountID,
+		RobloxUserID: robloxUserID,
+		RawJSON:      rawJSON,
+		ContentHash:  contentHash,
+		UpdatedAt:    time.Now(),
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	written, err := addIfUnchangedScript.Run(ctx, b.client,
+		[]string{b.bufferKey(), b.pendingKey(), b.hashKey(robloxUserID)},
+		robloxUserID, token, jsonData, contentHash, int(ContentHashTTL.Seconds())).Int()
+	if err != nil {
+		return false, err
+	}
+	return written == 1, nil
+}
+
+// GetContentHash returns the content hash of the user's last synced
+// inventory, or "" if it is not cached. Entries outlive the buffered data, so
+// unchanged syncs can be detected after a flush without reading the database.
+func (b *RedisInventoryBuffer) GetContentHash(ctx context.Context, robloxUserID string) (string, error) {
+	hash, err := b.client.Get(ctx, b.hashKey(robloxUserID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return hash, err
+}
+
+// RememberContentHash caches the content hash of the user's last synced inventory.
+func (b *RedisInventoryBuffer) RememberContentHash(ctx context.Context, robloxUserID, contentHash string) error {
+	return b.client.Set(ctx, b.hashKey(robloxUserID), contentHash, ContentHashTTL).Err()
+}
+
+// Delete drops a user's buffered and quarantined inventory, pending flag and
+// content hash. Reports whether an inventory was buffered or quarantined.
+func (b *RedisInventoryBuffer) Delete(ctx context.Context, robloxUserID string) (bool, error) {
+	pipe := b.client.TxPipeline()
+	removed := pipe.HDel(ctx, b.bufferKey(), robloxUserID)
+	pipe.SRem(ctx, b.pendingKey(), robloxUserID)
+	quarantined := pipe.HDel(ctx, b.quarantineKey(), robloxUserID)
+	pipe.Del(ctx, b.hashKey(robloxUserID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return removed.Val() > 0 || quarantined.Val() > 0, nil
+}
+
+// IsPending reports whether the user has an inventory waiting to be flushed.
+func (b *RedisInventoryBuffer) IsPending(ctx context.Context, robloxUserID string) (bool, error) {
+	return b.client.SIsMember(ctx, b.pendingKey(), robloxUserID).Result()
+}
+
 // Count returns the number of pending items.
 func (b *RedisInventoryBuffer) Count(ctx context.Context) (int64, error) {
 	return b.client.SCard(ctx, b.pendingKey()).Result()
 }
 
-// FlushBatch writes up to MaxBatchSize items to the database.
+// QuarantineCount returns the number of quarantined items.
+func (b *RedisInventoryBuffer) QuarantineCount(ctx context.Context) (int64, error) {
+	return b.client.HLen(ctx, b.quarantineKey()).Result()
+}
+
+// FlushBatch writes up to MaxBatchSize items to the database. Items written
+// are cleared and items the database rejects are quarantined, unless they
+// were updated meanwhile; the rest stay pending. Returns how many items were
+// cleared or quarantined.
 func (b *RedisInventoryBuffer) FlushBatch(ctx context.Context) (int, error) {
 	userIDs, err := b.client.SRandMemberN(ctx, b.pendingKey(), MaxBatchSize).Result()
 	if err != nil {
@@ -170,6 +316,7 @@ func (b *RedisInventoryBuffer) FlushBatch(ctx context.Context) (int, error) {
 
 	items := make([]*model.BufferedInventory, 0, len(userIDs))
 	originalData := make(map[string]string)
+	byUser := make(map[string]*model.BufferedInventory, len(userIDs))
 
 	for i, val := range values {
 		userID := userIDs[i]
@@ -203,28 +350,60 @@ func (b *RedisInventoryBuffer) FlushBatch(ctx context.Context) (int, error) {
 			continue
 		}
 		items = append(items, &inv)
+		byUser[userID] = &inv
 	}
 
 	if len(items) == 0 {
 		return 0, nil
 	}
 
-	if err := b.flushFunc(ctx, items); err != nil {
+	result, err := b.flushFunc(ctx, items)
+	if err != nil {
 		log.Printf("[RedisInventoryBuffer] Flush error: %v", err)
 		return 0, err
 	}
 
+	keys := []string{b.bufferKey(), b.pendingKey()}
+	quarantineKeys := []string{b.bufferKey(), b.pendingKey(), b.quarantineKey()}
+	// Eval, not Run: a pipeline cannot fall back when Redis lacks the script
 	pipe := b.client.Pipeline()
-	for userID, rawJSON := range originalData {
-		deleteIfUnchangedScript.Run(ctx, pipe, []string{b.bufferKey(), b.pendingKey()}, userID, rawJSON)
+	written := 0
+	for _, userID := range result.Succeeded {
+		if rawJSON, ok := originalData[userID]; ok {
+			deleteIfUnchangedScript.Eval(ctx, pipe, keys, userID, rawJSON)
+			written++
+		}
+	}
+	quarantined := 0
+	for _, failure := range result.Failed {
+		rawJSON, ok := originalData[failure.RobloxUserID]
+		if !ok {
+			continue
+		}
+		entry, err := json.Marshal(&model.QuarantinedInventory{
+			Inventory:     byUser[failure.RobloxUserID],
+			Reason:        failure.Reason,
+			QuarantinedAt: time.Now(),
+		})
+		if err != nil {
+			log.Printf("[RedisInventoryBuffer] Error quarantining %s: %v", failure.RobloxUserID, err)
+			continue
+		}
+		log.Printf("[RedisInventoryBuffer] Quarantining %s: %s", failure.RobloxUserID, failure.Reason)
+		quarantineIfUnchangedScript.Eval(ctx, pipe, quarantineKeys, failure.RobloxUserID, rawJSON, entry)
+		quarantined++
 	}
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		log.Printf("[RedisInventoryBuffer] Error clearing Redis: %v", err)
 	}
 
-	log.Printf("[RedisInventoryBuffer] Successfully flushed %d items", len(items))
-	return len(items), nil
+	if left := len(items) - written - quarantined; left > 0 {
+		log.Printf("[RedisInventoryBuffer] Flushed %d items, quarantined %d, %d left pending", written, quarantined, left)
+	} else {
+		log.Printf("[RedisInventoryBuffer] Successfully flushed %d items, quarantined %d", written, quarantined)
+	}
+	return written + quarantined, nil
 }
 
 // Flush writes all buffered items to database.
@@ -0,0 +1,384 @@
+// Package catalog holds the server-side item catalog: names, tiers, sell
+// prices and icons keyed by game item ID. It is loaded from a JSON or CSV
+// file and kept in memory; admin updates are written back to the same file.
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// Supported catalog file formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvHeader is the column order used when reading and writing CSV catalogs.
+var csvHeader = []string{"id", "category", "name", "tier", "sell_price", "icon"}
+
+// Item is a catalog entry.
+type Item struct {
+	ID        int64      `json:"id"`
+	Category  string     `json:"category,omitempty"`
+	Name      string     `json:"name"`
+	Tier      model.Tier `json:"tier,omitempty"`
+	SellPrice float64    `json:"sell_price"`
+	Icon      string     `json:"icon,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"` // set by admin updates
+}
+
+// Validate checks an item before it is added to the catalog.
+func (it *Item) Validate() error {
+	var details []apierror.FieldError
+	if it.ID <= 0 {
+		details = append(details, apierror.FieldError{Field: "id", Message: "must be a positive integer"})
+	}
+	if strings.TrimSpace(it.Name) == "" {
+		details = append(details, apierror.FieldError{Field: "name", Message: "is required"})
+	}
+	if it.Category != "" && !model.IsInventoryCategory(it.Category) {
+		details = append(details, apierror.FieldError{Field: "category", Message: "unknown category"})
+	}
+	if it.Tier < 0 || it.Tier > model.MaxTier {
+		details = append(details, apierror.FieldError{Field: "tier", Message: fmt.Sprintf("must be between 0 and %d", model.MaxTier)})
+	}
+	if it.SellPrice < 0 {
+		details = append(details, apierror.FieldError{Field: "sell_price", Message: "must not be negative"})
+	}
+	if len(details) > 0 {
+		return apierror.ValidationError(fmt.Sprintf("invalid catalog item %d", it.ID), details...)
+	}
+	return nil
+}
+
+// Filter selects catalog items. Zero values mean "any".
+type Filter struct {
+	Category string
+	Tier     model.Tier
+	Query    string // case-insensitive name substring
+}
+
+func (f *Filter) matches(it *Item) bool {
+	if f.Category != "" && it.Category != f.Category {
+		return false
+	}
+	if f.Tier != 0 && it.Tier != f.Tier {
+		return false
+	}
+	if f.Query != "" && !strings.Contains(strings.ToLower(it.Name), strings.ToLower(f.Query)) {
+		return false
+	}
+	return true
+}
+
+// Catalog is a concurrency-safe in-memory item catalog.
+type Catalog struct {
+	mu    sync.RWMutex
+	items map[int64]*Item
+	path  string // file admin updates are saved to; empty keeps them in memory only
+}
+
+// New creates an empty catalog that saves updates to path.
+func New(path string) *Catalog {
+	return &Catalog{
+		items: make(map[int64]*Item),
+		path:  path,
+	}
+}
+
+// Load reads a catalog from a JSON or CSV file, chosen by extension.
+// A missing file yields an empty catalog that will be created on the first update.
+func Load(path string) (*Catalog, error) {
+	c := New(path)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		log.Printf("[Catalog] %s not found, starting with an empty catalog", path)
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+	defer f.Close()
+
+	items, err := Decode(f, formatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load catalog %s: %w", path, err)
+	}
+	for i := range items {
+		c.items[items[i].ID] = &items[i]
+	}
+
+	log.Printf("[Catalog] Loaded %d items from %s", len(items), path)
+	return c, nil
+}
+
+func formatOf(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return FormatCSV
+	}
+	return FormatJSON
+}
+
+// Decode parses catalog items in the given format and validates them.
+func Decode(r io.Reader, format string) ([]Item, error) {
+	var items []Item
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.NewDecoder(r).Decode(&items)
+		if err != nil {
+			err = apierror.BadRequest("invalid catalog JSON: " + err.Error())
+		}
+	case FormatCSV:
+		items, err = decodeCSV(r)
+	default:
+		return nil, apierror.BadRequest("unsupported catalog format: " + format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool, len(items))
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			return nil, err
+		}
+		if seen[items[i].ID] {
+			return nil, apierror.BadRequest(fmt.Sprintf("duplicate catalog item %d", items[i].ID))
+		}
+		seen[items[i].ID] = true
+	}
+	return items, nil
+}
+
+// decodeCSV reads a CSV catalog. The header row names the columns; id and
+// name are required, the others are optional.
+func decodeCSV(r io.Reader) ([]Item, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, apierror.BadRequest("catalog CSV is missing a header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, apierror.BadRequest("catalog CSV is missing the " + required + " column")
+		}
+	}
+
+	var items []Item
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, apierror.BadRequest(fmt.Sprintf("catalog CSV line %d: %v", line, err))
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		var it Item
+		if it.ID, err = strconv.ParseInt(field("id"), 10, 64); err != nil {
+			return nil, apierror.BadRequest(fmt.Sprintf("catalog CSV line %d: invalid id", line))
+		}
+		it.Category = field("category")
+		it.Name = field("name")
+		it.Icon = field("icon")
+		if v := field("tier"); v != "" {
+			if err := it.Tier.UnmarshalJSON(strconv.AppendQuote(nil, v)); err != nil {
+				return nil, apierror.BadRequest(fmt.Sprintf("catalog CSV line %d: invalid tier", line))
+			}
+		}
+		if v := field("sell_price"); v != "" {
+			if it.SellPrice, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, apierror.BadRequest(fmt.Sprintf("catalog CSV line %d: invalid sell_price", line))
+			}
+		}
+		items = append(items, it)
+	}
+}
+
+// Encode writes items in the given format.
+func Encode(w io.Writer, items []Item, format string) error {
+	if format == FormatCSV {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, it := range items {
+			if err := writer.Write([]string{
+				strconv.FormatInt(it.ID, 10),
+				it.Category,
+				it.Name,
+				strconv.Itoa(int(it.Tier)),
+				strconv.FormatFloat(it.SellPrice, 'f', -1, 64),
+				it.Icon,
+			}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(items)
+}
+
+// Len returns the number of catalog items.
+func (c *Catalog) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
+
+// Get returns a copy of the item with the given ID, or nil.
+func (c *Catalog) Get(id int64) *Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	it, ok := c.items[id]
+	if !ok {
+		return nil
+	}
+	copied := *it
+	return &copied
+}
+
+// List returns a page of items matching f, ordered by ID, and the number of matches.
+func (c *Catalog) List(f Filter, offset, limit int) ([]Item, int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	matched := make([]Item, 0, len(c.items))
+	for _, it := range c.items {
+		if f.matches(it) {
+			matched = append(matched, *it)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].ID < matched[j].ID })
+
+	total := len(matched)
+	if offset >= total {
+		return []Item{}, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return matched[offset:end], total
+}
+
+// Upsert adds or replaces items and saves the catalog.
+// With replace set, items not in the list are removed.
+func (c *Catalog) Upsert(items []Item, replace bool) error {
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next := make(map[int64]*Item, len(c.items)+len(items))
+	if !replace {
+		for id, it := range c.items {
+			next[id] = it
+		}
+	}
+	now := time.Now().UTC()
+	for i := range items {
+		it := items[i]
+		it.UpdatedAt = &now
+		next[it.ID] = &it
+	}
+
+	if err := c.save(next); err != nil {
+		return err
+	}
+	c.items = next
+	return nil
+}
+
+// Delete removes an item and saves the catalog. Reports whether it existed.
+func (c *Catalog) Delete(id int64) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[id]; !ok {
+		return false, nil
+	}
+
+	next := make(map[int64]*Item, len(c.items))
+	for itemID, it := range c.items {
+		if itemID != id {
+			next[itemID] = it
+		}
+	}
+
+	if err := c.save(next); err != nil {
+		return false, err
+	}
+	c.items = next
+	return true, nil
+}
+
+// save writes items to the catalog file atomically. Called with mu held.
+func (c *Catalog) save(items map[int64]*Item) error {
+	if c.path == "" {
+		return nil
+	}
+
+	list := make([]Item, 0, len(items))
+	for _, it := range items {
+		list = append(list, *it)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, list, formatOf(c.path)); err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create catalog directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to replace catalog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// Enrich fills in name, tier, icon and price for inventory items that carry
+// only an ID. Values sent by the client are kept; items not in the catalog are
+// left untouched. Everything else in the document is passed through as is.
+func (c *Catalog) Enrich(rawJSON []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(rawJSON))
+	dec.UseNumber()
+
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	for _, category := range model.InventoryCategories {
+		entries, ok := doc[category].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, entry := range entries {
+			obj, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			it, ok := c.items[entryID(obj, model.CategoryIDFields[category])]
+			if !ok {
+				continue
+			}
+			setIfMissing(obj, "name", it.Name)
+			if it.Tier != 0 {
+				setIfMissing(obj, "tier", int(it.Tier))
+			}
+			setIfMissing(obj, "icon", it.Icon)
+			setIfMissing(obj, "price", it.SellPrice)
+		}
+	}
+	c.mu.RUnlock()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// entryID returns an item's game ID, preferring item_id like InventoryEntry.ID.
+func entryID(obj map[string]interface{}, idField string) int64 {
+	for _, key := range []string{"item_id", idField} {
+		if n, ok := obj[key].(json.Number); ok {
+			if id, err := strconv.ParseInt(n.String(), 10, 64); err == nil && id != 0 {
+				return id
+			}
+		}
+	}
+	return 0
+}
+
+func setIfMissing(obj map[string]interface{}, key string, value interface{}) {
+	if s, ok := value.(string); ok && s == "" {
+		return
+	}
+	switch current := obj[key].(type) {
+	case nil:
+		obj[key] = value
+	case string:
+		if current == "" {
+			obj[key] = value
+		}
+	}
+}
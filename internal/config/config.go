@@ -2,6 +2,10 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -20,6 +24,14 @@ type Config struct {
 	Cache       CacheConfig
 	Database    DatabaseConfig
 	InventoryDB InventoryDBConfig
+	History     HistoryConfig
+	EventLog    EventLogConfig
+	Webhooks    WebhookConfig
+	Trades      TradeConfig
+	Retention   RetentionConfig
+	Catalog     CatalogConfig
+	Valuation   ValuationConfig
+	Games       GamesConfig
 }
 
 // ServerConfig holds HTTP server settings.
@@ -29,6 +41,7 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `envconfig:"SERVER_READ_TIMEOUT" default:"15s"`
 	WriteTimeout    time.Duration `envconfig:"SERVER_WRITE_TIMEOUT" default:"3000s"`
 	ShutdownTimeout time.Duration `envconfig:"SERVER_SHUTDOWN_TIMEOUT" default:"30s"`
+	MaxBodySize     int64         `envconfig:"SERVER_MAX_BODY_SIZE" default:"16777216"` // max decompressed request body (16 MiB)
 }
 
 // AppConfig holds application-level settings.
@@ -62,7 +75,7 @@ type DatabaseConfig struct {
 
 // InventoryDBConfig holds inventory database settings.
 type InventoryDBConfig struct {
-	Type     string `envconfig:"INVENTORY_DB_TYPE" default:"sqlite"` // sqlite, postgres, or mongodb
+	Type     string `envconfig:"INVENTORY_DB_TYPE" default:"sqlite"` // sqlite, postgres, mongodb, or memory
 	Path     string `envconfig:"INVENTORY_DB_PATH" default:"./data/inventory.db"`
 	// PostgreSQL settings
 	Host     string `envconfig:"INVENTORY_DB_HOST" default:"localhost"`
@@ -75,12 +88,189 @@ type InventoryDBConfig struct {
 	MongoURI        string `envconfig:"MONGODB_URI" default:""`
 	MongoDatabase   string `envconfig:"MONGODB_DATABASE" default:"vinzhub"`
 	MongoCollection string `envconfig:"MONGODB_COLLECTION" default:"fishit_inventory"`
+	MongoKeepJSON   bool   `envconfig:"MONGODB_KEEP_ORIGINAL_JSON" default:"false"` // also store synced JSON bytes (gzipped), served byte for byte
+	// Memory backend settings; an empty snapshot path keeps inventories in memory only
+	SnapshotPath     string        `envconfig:"INVENTORY_SNAPSHOT_PATH" default:""`
+	SnapshotInterval time.Duration `envconfig:"INVENTORY_SNAPSHOT_INTERVAL" default:"1m"` // 0 saves on shutdown only
+	// PostgreSQL schema for the inventory tables; set by ForGame, empty means public
+	Schema string `ignored:"true"`
+}
+
+// HistoryConfig holds inventory history settings.
+type HistoryConfig struct {
+	Enabled   bool          `envconfig:"HISTORY_ENABLED" default:"true"`
+	Retention time.Duration `envconfig:"HISTORY_RETENTION" default:"720h"` // 30 days; latest two versions are always kept
+}
+
+// EventLogConfig holds inventory event log settings.
+type EventLogConfig struct {
+	Enabled   bool          `envconfig:"EVENT_LOG_ENABLED" default:"true"`
+	Retention time.Duration `envconfig:"EVENT_LOG_RETENTION" default:"720h"` // 0 keeps events forever
+}
+
+// WebhookConfig holds rare catch detection and webhook delivery settings.
+type WebhookConfig struct {
+	Path         string        `envconfig:"WEBHOOKS_PATH" default:"./data/webhooks.json"`
+	RareTier     int           `envconfig:"RARE_CATCH_MIN_TIER" default:"6"` // Mythical and above; 0 disables rare catch events
+	MaxAttempts  int           `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"5"`
+	RetryBackoff time.Duration `envconfig:"WEBHOOK_RETRY_BACKOFF" default:"2s"` // doubles after each failed attempt
+	Timeout      time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+	Workers      int           `envconfig:"WEBHOOK_WORKERS" default:"2"`
+	LogSize      int           `envconfig:"WEBHOOK_DELIVERY_LOG_SIZE" default:"1000"`
+}
+
+// TradeConfig holds item transfer detection settings. Detection needs the
+// inventory event log.
+type TradeConfig struct {
+	Enabled     bool          `envconfig:"TRADES_ENABLED" default:"true"`
+	MatchWindow time.Duration `envconfig:"TRADE_MATCH_WINDOW" default:"24h"` // max time between the sender's and the receiver's syncs
+}
+
+// RetentionConfig holds the policy for purging inactive inventories.
+// A key tier threshold replaces the default threshold; a value threshold
+// keeps inventories worth at least that much for at least that long.
+type RetentionConfig struct {
+	InactiveThreshold time.Duration            `envconfig:"RETENTION_INACTIVE_THRESHOLD" default:"720h"`    // games without GAME_INACTIVE_THRESHOLDS
+	KeyTierThresholds map[string]time.Duration `envconfig:"RETENTION_KEY_TIER_THRESHOLDS" default:""`       // e.g. "free:168h,premium:2160h"
+	ValueThresholds   map[string]time.Duration `envconfig:"RETENTION_VALUE_THRESHOLDS" default:""`          // min inventory value, e.g. "100000:2160h"
+	ArchiveDir        string                   `envconfig:"RETENTION_ARCHIVE_DIR" default:"./data/archive"` // empty deletes without archiving
+	PinsPath          string                   `envconfig:"RETENTION_PINS_PATH" default:"./data/pinned_users.json"`
+	DryRun            bool                     `envconfig:"RETENTION_DRY_RUN" default:"false"`        // scheduled runs only log what they would purge
+	TombstoneGrace    time.Duration            `envconfig:"RETENTION_TOMBSTONE_GRACE" default:"720h"` // purged inventories stay restorable this long; 0 keeps them
+}
+
+// ParsedValueThresholds returns ValueThresholds keyed by minimum value.
+func (c *RetentionConfig) ParsedValueThresholds() (map[float64]time.Duration, error) {
+	parsed := make(map[float64]time.Duration, len(c.ValueThresholds))
+	for minValue, threshold := range c.ValueThresholds {
+		v, err := strconv.ParseFloat(minValue, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("RETENTION_VALUE_THRESHOLDS: invalid minimum value %q", minValue)
+		}
+		parsed[v] = threshold
+	}
+	return parsed, nil
+}
+
+// CatalogConfig holds item catalog settings.
+type CatalogConfig struct {
+	Path string `envconfig:"CATALOG_PATH" default:"./data/catalog.json"` // .json or .csv; admin updates are saved here
+}
+
+// ValuationConfig holds inventory valuation settings.
+type ValuationConfig struct {
+	ShinyMultiplier    float64            `envconfig:"VALUATION_SHINY_MULTIPLIER" default:"1"`
+	VariantMultipliers map[string]float64 `envconfig:"VALUATION_VARIANT_MULTIPLIERS" default:""` // e.g. "Gold:2,Rainbow:5"
+	Retention          time.Duration      `envconfig:"VALUATION_RETENTION" default:"2160h"`      // 90 days; latest point is always kept
+}
+
+// DefaultGame is the game served by the un-namespaced /inventory routes.
+// It keeps the storage settings of InventoryDBConfig as configured.
+const DefaultGame = "fishit"
+
+// Inventory payload schemas a game can use.
+const (
+	GameSchemaFishIt  = "fishit"  // fish, rods, baits, ... validated field by field
+	GameSchemaGeneric = "generic" // any JSON object
+)
+
+var gameNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// GamesConfig holds multi-game settings. Every game gets its own inventory
+// storage (see InventoryDBConfig.ForGame), buffer keys and cleanup policy.
+type GamesConfig struct {
+	Names              []string                 `envconfig:"GAMES" default:"fishit"`              // fishit is always served
+	Schemas            map[string]string        `envconfig:"GAME_SCHEMAS" default:""`             // e.g. "pets:generic"
+	InactiveThresholds map[string]time.Duration `envconfig:"GAME_INACTIVE_THRESHOLDS" default:""` // e.g. "pets:720h"
+}
+
+// List returns the configured games, DefaultGame first, without duplicates.
+func (g *GamesConfig) List() []string {
+	games := []string{DefaultGame}
+	seen := map[string]bool{DefaultGame: true}
+	for _, name := range g.Names {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			games = append(games, name)
+		}
+	}
+	return games
+}
+
+// Schema returns the payload schema of game. DefaultGame uses the fishit
+// schema and other games accept any JSON object unless configured otherwise.
+func (g *GamesConfig) Schema(game string) string {
+	if schema, ok := g.Schemas[game]; ok {
+		return schema
+	}
+	if game == DefaultGame {
+		return GameSchemaFishIt
+	}
+	return GameSchemaGeneric
+}
+
+// InactiveThreshold returns how long game's inventories may go unsynced
+// before cleanup deletes them, or fallback if not configured.
+func (g *GamesConfig) InactiveThreshold(game string, fallback time.Duration) time.Duration {
+	if d, ok := g.InactiveThresholds[game]; ok && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+func (g *GamesConfig) validate() error {
+	games := g.List()
+	known := make(map[string]bool, len(games))
+	for _, game := range games {
+		if !gameNamePattern.MatchString(game) {
+			return fmt.Errorf("invalid game name %q: use lowercase letters, digits and underscores", game)
+		}
+		known[game] = true
+	}
+	for game, schema := range g.Schemas {
+		if !known[game] {
+			return fmt.Errorf("GAME_SCHEMAS names unknown game %q", game)
+		}
+		if schema != GameSchemaFishIt && schema != GameSchemaGeneric {
+			return fmt.Errorf("invalid schema %q for game %q: use %s or %s", schema, game, GameSchemaFishIt, GameSchemaGeneric)
+		}
+	}
+	for game := range g.InactiveThresholds {
+		if !known[game] {
+			return fmt.Errorf("GAME_INACTIVE_THRESHOLDS names unknown game %q", game)
+		}
+	}
+	return nil
+}
+
+// ForGame returns the storage settings for game. DefaultGame uses the
+// settings as configured; other games get their own SQLite file
+// (inventory_<game>.db next to INVENTORY_DB_PATH), PostgreSQL schema
+// (<game>), MongoDB collection (<game>_inventory) or memory snapshot.
+func (i InventoryDBConfig) ForGame(game string) InventoryDBConfig {
+	if game == DefaultGame {
+		return i
+	}
+	ext := filepath.Ext(i.Path)
+	i.Path = strings.TrimSuffix(i.Path, ext) + "_" + game + ext
+	if i.SnapshotPath != "" {
+		ext := filepath.Ext(i.SnapshotPath)
+		i.SnapshotPath = strings.TrimSuffix(i.SnapshotPath, ext) + "_" + game + ext
+	}
+	i.Schema = game
+	i.MongoCollection = game + "_inventory"
+	return i
 }
 
 // PostgresDSN returns the PostgreSQL connection string.
 func (i *InventoryDBConfig) PostgresDSN() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
 		i.User, i.Password, i.Host, i.Port, i.Name, i.SSLMode)
+	if i.Schema != "" {
+		dsn += "&search_path=" + i.Schema
+	}
+	return dsn
 }
 
 // Address returns the server address in host:port format.
@@ -116,6 +306,12 @@ func Load() (*Config, error) {
 	if err := envconfig.Process("", &cfg); err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
+	if err := cfg.Games.validate(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if _, err := cfg.Retention.ParsedValueThresholds(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
 
 	return &cfg, nil
 }
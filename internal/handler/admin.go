@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"runtime"
 	"time"
 
 	"vinzhub-rest-api-v2/internal/cache"
+	"vinzhub-rest-api-v2/internal/middleware"
 	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/internal/service"
 	"vinzhub-rest-api-v2/pkg/response"
 )
 
@@ -16,6 +19,7 @@ type AdminHandler struct {
 	inventoryRepo repository.InventoryRepository // Interface instead of concrete type
 	dbType        string                          // Database type: sqlite, postgres, mongodb
 	loginKey      string                          // Admin dashboard login key
+	inventory     *service.InventoryService       // Optional: sync dedupe stats
 	startTime     time.Time
 }
 
@@ -35,6 +39,11 @@ func NewAdminHandler(
 	}
 }
 
+// SetInventoryService enables sync statistics in GetStats.
+func (h *AdminHandler) SetInventoryService(s *service.InventoryService) {
+	h.inventory = s
+}
+
 // GetStats handles GET /api/v1/admin/stats
 func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -62,10 +71,15 @@ func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 	// Redis buffer stats
 	if h.redisBuffer != nil {
 		count, err := h.redisBuffer.Count(ctx)
+		var quarantined int64
+		if err == nil {
+			quarantined, err = h.redisBuffer.QuarantineCount(ctx)
+		}
 		if err == nil {
 			stats["redis_buffer"] = map[string]interface{}{
-				"pending_items": count,
-				"status":        "connected",
+				"pending_items":     count,
+				"quarantined_items": quarantined, // rejected by the database, not retried
+				"status":            "connected",
 			}
 		} else {
 			stats["redis_buffer"] = map[string]interface{}{
@@ -97,6 +111,14 @@ func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Sync dedupe: share of syncs whose content matched the stored inventory
+	if h.inventory != nil {
+		stats["syncs"] = h.inventory.SyncStats()
+	}
+
+	// Request body sizes by Content-Encoding (wire vs decompressed)
+	stats["request_bodies"] = middleware.BodyStats()
+
 	// Runtime info
 	stats["runtime"] = map[string]interface{}{
 		"go_version": runtime.Version(),
@@ -123,7 +145,7 @@ func (h *AdminHandler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
 		key = r.Header.Get("X-API-Key") // Fallback to X-API-Key header
 	}
 	
-	if key == "" || key != h.loginKey {
+	if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(h.loginKey)) != 1 {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(401)
 		w.Write([]byte(`{"success":false,"error":"invalid login key"}`))
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/catalog"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxCatalogImportSize bounds admin catalog uploads.
+const maxCatalogImportSize = 10 << 20
+
+// CatalogHandler handles item catalog HTTP requests.
+type CatalogHandler struct {
+	catalog *catalog.Catalog
+}
+
+// NewCatalogHandler creates a new catalog handler.
+func NewCatalogHandler(c *catalog.Catalog) *CatalogHandler {
+	return &CatalogHandler{
+		catalog: c,
+	}
+}
+
+// ListItems handles GET /api/v1/catalog/items
+// Filters: category, tier, q (name substring).
+func (h *CatalogHandler) ListItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := catalog.Filter{
+		Category: query.Get("category"),
+		Query:    query.Get("q"),
+	}
+	if v := query.Get("tier"); v != "" {
+		tier, err := parseTier(v)
+		if err != nil {
+			response.Error(w, apierror.BadRequest("tier must be a tier number (1-8) or name"))
+			return
+		}
+		filter.Tier = tier
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	items, total := h.catalog.List(filter, (page-1)*limit, limit)
+	response.JSONWithMeta(w, http.StatusOK, items, page, limit, int64(total))
+}
+
+// GetItem handles GET /api/v1/catalog/items/{id}
+func (h *CatalogHandler) GetItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := catalogItemID(w, r)
+	if !ok {
+		return
+	}
+
+	item := h.catalog.Get(id)
+	if item == nil {
+		response.Error(w, apierror.NotFound("catalog item not found"))
+		return
+	}
+
+	response.OK(w, item)
+}
+
+// PutItem handles PUT /api/v1/admin/catalog/items/{id}
+func (h *CatalogHandler) PutItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := catalogItemID(w, r)
+	if !ok {
+		return
+	}
+
+	var item catalog.Item
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCatalogImportSize)).Decode(&item); err != nil {
+		response.Error(w, apierror.BadRequest("invalid JSON body"))
+		return
+	}
+	defer r.Body.Close()
+
+	if item.ID != 0 && item.ID != id {
+		response.Error(w, apierror.BadRequest("id in body does not match the URL"))
+		return
+	}
+	item.ID = id
+
+	if err := h.catalog.Upsert([]catalog.Item{item}, false); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, h.catalog.Get(id))
+}
+
+// DeleteItem handles DELETE /api/v1/admin/catalog/items/{id}
+func (h *CatalogHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := catalogItemID(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.catalog.Delete(id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if !deleted {
+		response.Error(w, apierror.NotFound("catalog item not found"))
+		return
+	}
+
+	response.OK(w, map[string]interface{}{"status": "deleted", "id": id})
+}
+
+// ImportItems handles POST /api/v1/admin/catalog/import
+// Accepts a JSON array of items or a CSV file (Content-Type: text/csv).
+// ?replace=true replaces the whole catalog instead of merging.
+func (h *CatalogHandler) ImportItems(w http.ResponseWriter, r *http.Request) {
+	format := catalog.FormatJSON
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		format = catalog.FormatCSV
+	}
+	replace, _ := strconv.ParseBool(r.URL.Query().Get("replace"))
+
+	items, err := catalog.Decode(http.MaxBytesReader(w, r.Body, maxCatalogImportSize), format)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.catalog.Upsert(items, replace); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"status":   "imported",
+		"imported": len(items),
+		"total":    h.catalog.Len(),
+	})
+}
+
+// catalogItemID parses the {id} URL parameter, writing an error response if invalid.
+func catalogItemID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		response.Error(w, apierror.BadRequest("id must be a positive integer"))
+		return 0, false
+	}
+	return id, true
+}
@@ -2,9 +2,17 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"vinzhub-rest-api-v2/internal/model"
 	"vinzhub-rest-api-v2/internal/service"
 	"vinzhub-rest-api-v2/pkg/apierror"
 	"vinzhub-rest-api-v2/pkg/response"
@@ -13,18 +21,48 @@ import (
 )
 
 // InventoryHandler handles inventory-related HTTP requests.
+// Requests under /games/{game} are served by that game's service; the
+// un-namespaced routes use the default one.
 type InventoryHandler struct {
 	inventoryService *service.InventoryService
+	games            map[string]*service.InventoryService
 }
 
 // NewInventoryHandler creates a new inventory handler.
 func NewInventoryHandler(inventoryService *service.InventoryService) *InventoryHandler {
 	return &InventoryHandler{
 		inventoryService: inventoryService,
+		games:            make(map[string]*service.InventoryService),
 	}
 }
 
+// AddGame registers the inventory service serving /games/{game}.
+func (h *InventoryHandler) AddGame(game string, inventoryService *service.InventoryService) {
+	h.games[game] = inventoryService
+}
+
+// RequireGame rejects requests for a {game} that has not been registered.
+func (h *InventoryHandler) RequireGame(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		game := chi.URLParam(r, "game")
+		if _, ok := h.games[game]; !ok {
+			response.Error(w, apierror.NotFound("unknown game: "+game))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// service returns the inventory service for the request's game.
+func (h *InventoryHandler) service(r *http.Request) *service.InventoryService {
+	if s, ok := h.games[chi.URLParam(r, "game")]; ok {
+		return s
+	}
+	return h.inventoryService
+}
+
 // SyncRawInventory handles POST /api/v1/inventory/{roblox_user_id}/sync
+// An optional If-Match with the stored content hash makes the write conditional (412 on mismatch).
 func (h *InventoryHandler) SyncRawInventory(w http.ResponseWriter, r *http.Request) {
 	robloxUserID := chi.URLParam(r, "roblox_user_id")
 	if robloxUserID == "" {
@@ -32,33 +70,85 @@ func (h *InventoryHandler) SyncRawInventory(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
+	body, err := readBody(r)
 	if err != nil {
-		response.Error(w, apierror.BadRequest("failed to read request body"))
+		response.Error(w, err)
 		return
 	}
-	defer r.Body.Close()
 
-	var jsonData json.RawMessage
-	if err := json.Unmarshal(body, &jsonData); err != nil {
-		response.Error(w, apierror.BadRequest("invalid JSON"))
+	result, err := h.service(r).SyncRawInventory(r.Context(), robloxUserID, body, r.Header.Get("If-Match"))
+	if err != nil {
+		response.Error(w, err)
 		return
 	}
 
-	err = h.inventoryService.SyncRawInventory(r.Context(), robloxUserID, body)
+	writeSyncResult(w, robloxUserID, result)
+}
+
+// PatchInventory handles PATCH /api/v1/inventory/{roblox_user_id}
+// Accepts a JSON Merge Patch or JSON Patch against the version named by If-Match.
+func (h *InventoryHandler) PatchInventory(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	patchType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	body, err := readBody(r)
 	if err != nil {
 		response.Error(w, err)
 		return
 	}
 
-	response.OK(w, map[string]interface{}{
-		"status":  "synced",
+	result, err := h.service(r).PatchInventory(r.Context(), robloxUserID, patchType, body, r.Header.Get("If-Match"))
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	writeSyncResult(w, robloxUserID, result)
+}
+
+// readBody reads the whole request body. Bodies cut off by the
+// decompressed size limit are reported as 413.
+func readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, apierror.PayloadTooLarge(fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+		}
+		return nil, apierror.BadRequest("failed to read request body")
+	}
+	return body, nil
+}
+
+// writeSyncResult writes the response for an accepted sync or patch.
+// The content hash doubles as the ETag for the next patch's If-Match.
+func writeSyncResult(w http.ResponseWriter, robloxUserID string, result *model.SyncResult) {
+	resp := map[string]interface{}{
+		"status":  result.Status,
 		"user_id": robloxUserID,
-		"size":    len(body),
-	})
+		"size":    result.Size,
+	}
+	if result.ContentHash != "" {
+		resp["content_hash"] = result.ContentHash
+		w.Header().Set("ETag", `"`+result.ContentHash+`"`)
+	}
+	if len(result.UnknownFields) > 0 {
+		resp["unknown_fields"] = result.UnknownFields
+	}
+	response.OK(w, resp)
 }
 
 // GetRawInventory handles GET /api/v1/inventory/{roblox_user_id}
+// ?fields=stats,fish returns only those top-level fields.
+// ?enrich=true fills in item names, tiers, icons and prices from the catalog.
+// Responses carry ETag (the content hash) and Last-Modified, and answer
+// If-None-Match / If-Modified-Since with 304 Not Modified.
 func (h *InventoryHandler) GetRawInventory(w http.ResponseWriter, r *http.Request) {
 	robloxUserID := chi.URLParam(r, "roblox_user_id")
 	if robloxUserID == "" {
@@ -66,15 +156,489 @@ func (h *InventoryHandler) GetRawInventory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	data, syncedAt, err := h.inventoryService.GetRawInventory(r.Context(), robloxUserID)
+	var fields []string
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	var inv *model.RawInventory
+	var err error
+	if len(fields) > 0 {
+		inv, err = h.service(r).GetInventoryFields(r.Context(), robloxUserID, fields)
+	} else {
+		inv, err = h.service(r).GetInventory(r.Context(), robloxUserID)
+	}
 	if err != nil {
 		response.Error(w, err)
 		return
 	}
+	if inv == nil {
+		response.OK(w, map[string]interface{}{
+			"roblox_user_id": robloxUserID,
+			"inventory":      json.RawMessage(nil),
+			"synced_at":      nil,
+		})
+		return
+	}
+
+	// Projected and enriched responses are derived from the content, so they
+	// carry distinct weak tags
+	data := inv.InventoryJSON
+	etag := `"` + inv.ContentHash + `"`
+	var variant []string
+	if len(fields) > 0 {
+		variant = append(variant, "fields="+strings.Join(fields, ","))
+	}
+	if enrich, _ := strconv.ParseBool(r.URL.Query().Get("enrich")); enrich {
+		if data, err = h.service(r).EnrichInventory(data); err != nil {
+			response.Error(w, err)
+			return
+		}
+		variant = append(variant, "enriched")
+	}
+	if len(variant) > 0 {
+		etag = `W/"` + inv.ContentHash + `-` + strings.Join(variant, "-") + `"`
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", inv.SyncedAt.UTC().Format(http.TimeFormat))
+	if notModified(r, etag, inv.SyncedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
 	response.OK(w, map[string]interface{}{
 		"roblox_user_id": robloxUserID,
 		"inventory":      json.RawMessage(data),
-		"synced_at":      syncedAt,
+		"content_hash":   inv.ContentHash,
+		"synced_at":      inv.SyncedAt,
+		"last_seen_at":   inv.LastSeenAt,
 	})
 }
+
+// GetInventoryCategory handles GET /api/v1/inventory/{roblox_user_id}/{category}
+// Lists one category's items, filtered by ?tier= (or tier>=, min_tier, max_tier),
+// ?favorited= and ?is_shiny=, sorted by ?sort=tier|name|price|quantity
+// (prefix "-" for descending; default is stored order) and paged with ?page=&limit=.
+func (h *InventoryHandler) GetInventoryCategory(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	query := r.URL.Query()
+	q := model.CategoryQuery{Category: chi.URLParam(r, "category")}
+
+	var err error
+	if q.Tiers, err = parseTierRange(query); err != nil {
+		response.Error(w, err)
+		return
+	}
+	if q.Favorited, err = parseOptionalBool(query, "favorited"); err != nil {
+		response.Error(w, err)
+		return
+	}
+	if q.Shiny, err = parseOptionalBool(query, "is_shiny"); err != nil {
+		response.Error(w, err)
+		return
+	}
+	if sort := query.Get("sort"); sort != "" {
+		q.Sort = strings.TrimPrefix(sort, "-")
+		q.Desc = strings.HasPrefix(sort, "-")
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	q.Limit, q.Offset = limit, (page-1)*limit
+
+	items, err := h.service(r).GetCategoryItems(r.Context(), robloxUserID, q)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if items == nil {
+		response.Error(w, apierror.NotFound("inventory not found"))
+		return
+	}
+
+	response.JSONWithMeta(w, http.StatusOK, items.Items, page, limit, items.Total)
+}
+
+// notModified evaluates If-None-Match and If-Modified-Since (RFC 9110 13.2.2):
+// If-Modified-Since is only considered when If-None-Match is absent.
+func notModified(r *http.Request, etag string, modifiedAt time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modifiedAt.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// GetInventoryHistory handles GET /api/v1/inventory/{roblox_user_id}/history
+// Lists stored versions, or returns the version current at ?at=<timestamp>.
+func (h *InventoryHandler) GetInventoryHistory(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, err := parseTimestamp(at)
+		if err != nil {
+			response.Error(w, apierror.BadRequest("at must be an RFC 3339 timestamp or unix seconds"))
+			return
+		}
+		snap, err := h.service(r).GetHistoryAt(r.Context(), robloxUserID, t)
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		response.OK(w, snap)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	snapshots, total, err := h.service(r).ListHistory(r.Context(), robloxUserID, limit, (page-1)*limit)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSONWithMeta(w, http.StatusOK, snapshots, page, limit, total)
+}
+
+// GetInventoryVersion handles GET /api/v1/inventory/{roblox_user_id}/history/{version}
+func (h *InventoryHandler) GetInventoryVersion(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	version, err := strconv.ParseInt(chi.URLParam(r, "version"), 10, 64)
+	if err != nil || version < 1 {
+		response.Error(w, apierror.BadRequest("version must be a positive integer"))
+		return
+	}
+
+	snap, err := h.service(r).GetHistoryVersion(r.Context(), robloxUserID, version)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, snap)
+}
+
+// GetInventoryDiff handles GET /api/v1/inventory/{roblox_user_id}/diff
+// Compares two stored versions given by ?from=&to=, or by default the current
+// inventory with the previous version.
+func (h *InventoryHandler) GetInventoryDiff(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	fromParam, toParam := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if fromParam == "" && toParam == "" {
+		result, err := h.service(r).DiffLatest(r.Context(), robloxUserID)
+		if err != nil {
+			response.Error(w, err)
+			return
+		}
+		response.OK(w, result)
+		return
+	}
+
+	from, err := strconv.ParseInt(fromParam, 10, 64)
+	if err != nil || from < 1 {
+		response.Error(w, apierror.BadRequest("from must be a positive integer"))
+		return
+	}
+	to, err := strconv.ParseInt(toParam, 10, 64)
+	if err != nil || to < 1 {
+		response.Error(w, apierror.BadRequest("to must be a positive integer"))
+		return
+	}
+
+	result, err := h.service(r).DiffVersions(r.Context(), robloxUserID, from, to)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, result)
+}
+
+// DiffInventoryPayloads handles POST /api/v1/inventory/{roblox_user_id}/diff
+// Compares two inventory payloads supplied as {"before": {...}, "after": {...}}.
+func (h *InventoryHandler) DiffInventoryPayloads(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	var req struct {
+		Before json.RawMessage `json:"before"`
+		After  json.RawMessage `json:"after"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, apierror.BadRequest("invalid JSON body"))
+		return
+	}
+	defer r.Body.Close()
+
+	if len(req.Before) == 0 || len(req.After) == 0 {
+		response.Error(w, apierror.BadRequest("before and after are required"))
+		return
+	}
+
+	result, err := h.service(r).DiffPayloads(robloxUserID, req.Before, req.After)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, result)
+}
+
+// SearchItems handles GET /api/v1/inventory/search
+// Filters: item_id, category, tier (exact), tier>=/min_tier, max_tier, shiny, variant_id.
+func (h *InventoryHandler) SearchItems(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var q model.ItemSearchQuery
+
+	if v := query.Get("item_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 1 {
+			response.Error(w, apierror.BadRequest("item_id must be a positive integer"))
+			return
+		}
+		q.ItemID = id
+	}
+	q.Category = query.Get("category")
+	q.VariantID = query.Get("variant_id")
+
+	tiers, err := parseTierRange(query)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	q.MinTier, q.MaxTier = tiers.Min, tiers.Max
+
+	if q.Shiny, err = parseOptionalBool(query, "shiny"); err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	q.Limit, q.Offset = limit, (page-1)*limit
+
+	results, total, err := h.service(r).SearchItems(r.Context(), q)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSONWithMeta(w, http.StatusOK, results, page, limit, total)
+}
+
+// parseTier accepts a tier number or a tier name.
+func parseTier(value string) (model.Tier, error) {
+	var tier model.Tier
+	if err := tier.UnmarshalJSON(strconv.AppendQuote(nil, value)); err != nil {
+		return 0, err
+	}
+	if tier < 1 || tier > model.MaxTier {
+		return 0, strconv.ErrRange
+	}
+	return tier, nil
+}
+
+// parseTierRange reads a tier filter from ?tier=, ?tier>=, ?min_tier= and ?max_tier=.
+func parseTierRange(query url.Values) (model.TierRange, error) {
+	var tiers model.TierRange
+	// "?tier>=7" arrives as the key "tier>" with value "7"
+	tierParams := []struct {
+		key      string
+		min, max bool
+	}{
+		{"tier", true, true},
+		{"tier>", true, false},
+		{"min_tier", true, false},
+		{"max_tier", false, true},
+	}
+	for _, p := range tierParams {
+		v := query.Get(p.key)
+		if v == "" {
+			continue
+		}
+		tier, err := parseTier(v)
+		if err != nil {
+			return tiers, apierror.BadRequest(p.key + " must be a tier number (1-8) or name")
+		}
+		if p.min {
+			tiers.Min = tier
+		}
+		if p.max {
+			tiers.Max = tier
+		}
+	}
+	return tiers, nil
+}
+
+// parseOptionalBool reads a true/false query parameter; nil if absent.
+func parseOptionalBool(query url.Values, key string) (*bool, error) {
+	v := query.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, apierror.BadRequest(key + " must be true or false")
+	}
+	return &b, nil
+}
+
+// parseTimestamp accepts RFC 3339 timestamps or unix seconds.
+func parseTimestamp(value string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetInventoryValue handles GET /api/v1/inventory/{roblox_user_id}/value
+// Returns the inventory's total value broken down by category and tier, with
+// the ?top=N (default 10, max 100) most valuable items.
+func (h *InventoryHandler) GetInventoryValue(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	top := 10
+	if v := r.URL.Query().Get("top"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 100 {
+			response.Error(w, apierror.BadRequest("top must be an integer between 0 and 100"))
+			return
+		}
+		top = n
+	}
+
+	valuation, err := h.service(r).GetInventoryValue(r.Context(), robloxUserID, top)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, valuation)
+}
+
+// GetInventoryValueHistory handles GET /api/v1/inventory/{roblox_user_id}/value/history
+// Returns recorded values oldest first, optionally bounded by ?from= and ?to=.
+func (h *InventoryHandler) GetInventoryValueHistory(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	from, to := time.Time{}, time.Now()
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := parseTimestamp(v)
+		if err != nil {
+			response.Error(w, apierror.BadRequest("from must be an RFC 3339 timestamp or unix seconds"))
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := parseTimestamp(v)
+		if err != nil {
+			response.Error(w, apierror.BadRequest("to must be an RFC 3339 timestamp or unix seconds"))
+			return
+		}
+		to = t
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 1000 {
+		limit = 500
+	}
+
+	points, err := h.service(r).GetValueHistory(r.Context(), robloxUserID, from, to, limit)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, points)
+}
+
+// RestoreInventory handles POST /api/v1/admin/inventory/{roblox_user_id}/restore
+// Brings back the user's latest purged inventory of ?game= (default fishit).
+// Responds 409 if a newer live inventory exists, which is kept.
+func (h *InventoryHandler) RestoreInventory(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+
+	inventoryService := h.inventoryService
+	if game := r.URL.Query().Get("game"); game != "" {
+		var ok bool
+		if inventoryService, ok = h.games[game]; !ok {
+			response.Error(w, apierror.NotFound("unknown game: "+game))
+			return
+		}
+	}
+
+	result, err := inventoryService.RestoreInventory(r.Context(), robloxUserID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if result == nil {
+		response.Error(w, apierror.NotFound("no deleted inventory to restore"))
+		return
+	}
+	if !result.Restored {
+		response.Error(w, apierror.Conflict(fmt.Sprintf("a newer inventory (synced %s) exists",
+			result.LiveSyncedAt.UTC().Format(time.RFC3339))))
+		return
+	}
+
+	response.OK(w, result)
+}
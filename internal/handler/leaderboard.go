@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/service"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// LeaderboardHandler handles leaderboard HTTP requests.
+type LeaderboardHandler struct {
+	leaderboardService *service.LeaderboardService
+}
+
+// NewLeaderboardHandler creates a new leaderboard handler.
+func NewLeaderboardHandler(leaderboardService *service.LeaderboardService) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		leaderboardService: leaderboardService,
+	}
+}
+
+// GetLeaderboard handles GET /api/v1/leaderboards/{metric}
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	metric := chi.URLParam(r, "metric")
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	entries, total, err := h.leaderboardService.Top(r.Context(), metric, (page-1)*limit, limit)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.JSONWithMeta(w, http.StatusOK, entries, page, limit, total)
+}
+
+// GetUserRank handles GET /api/v1/leaderboards/{metric}/users/{roblox_user_id}
+func (h *LeaderboardHandler) GetUserRank(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	if robloxUserID == "" {
+		response.Error(w, apierror.BadRequest("roblox_user_id is required"))
+		return
+	}
+
+	entry, err := h.leaderboardService.Rank(r.Context(), chi.URLParam(r, "metric"), robloxUserID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, entry)
+}
+
+// RebuildLeaderboards handles POST /api/v1/admin/leaderboards/rebuild
+func (h *LeaderboardHandler) RebuildLeaderboards(w http.ResponseWriter, r *http.Request) {
+	ranked, err := h.leaderboardService.Rebuild(r.Context())
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"status":       "rebuilt",
+		"users_ranked": ranked,
+	})
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	
+	"strings"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
 	"vinzhub-rest-api-v2/internal/repository"
 	"vinzhub-rest-api-v2/internal/service"
 	"vinzhub-rest-api-v2/pkg/apierror"
@@ -50,16 +54,65 @@ func (h *LogHandler) GetObfuscationLogs(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// GetInventoryLogs returns inventory snapshot (assuming 1:1 for now)
-// Since we don't have historical inventory transactions yet, we just return the current inventory list from MongoDB
+// GetInventoryLogs handles GET /api/v1/logs/inventory
+// Lists inventory change events, newest first. Filters: ?user=, ?type= (comma
+// separated), ?tier= (or tier>=, min_tier, max_tier), ?from=&to=. Pages are
+// fetched with ?limit= and the next_cursor of the previous page as ?cursor=.
 func (h *LogHandler) GetInventoryLogs(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement proper historical logging if MongoDB collection supports it.
-	// For now, this endpoint placeholder can return nothing or generic info.
-	// The user asked for "Inventory Logs", usually meaning "who has what".
-	// Since listing ALL users' inventory is heavy, we might need a specific repo method.
-	
+	eventLog, ok := h.LogRepo.(repository.InventoryEventLog)
+	if h.LogRepo == nil || !ok {
+		response.Error(w, apierror.ServiceUnavailable("inventory event log is not enabled"))
+		return
+	}
+
+	query := r.URL.Query()
+	q := model.InventoryEventQuery{
+		RobloxUserID: query.Get("user"),
+		Cursor:       query.Get("cursor"),
+	}
+	for _, t := range strings.Split(query.Get("type"), ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if !model.IsInventoryEventType(t) {
+			response.Error(w, apierror.BadRequest("unknown event type: "+t))
+			return
+		}
+		q.Types = append(q.Types, t)
+	}
+
+	var err error
+	if q.Tiers, err = parseTierRange(query); err != nil {
+		response.Error(w, err)
+		return
+	}
+	for key, dst := range map[string]*time.Time{"from": &q.From, "to": &q.To} {
+		if v := query.Get(key); v != "" {
+			if *dst, err = parseTimestamp(v); err != nil {
+				response.Error(w, apierror.BadRequest(key+" must be an RFC 3339 timestamp or unix seconds"))
+				return
+			}
+		}
+	}
+
+	q.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if q.Limit < 1 || q.Limit > 200 {
+		q.Limit = 50
+	}
+
+	events, next, err := eventLog.ListInventoryEvents(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			response.Error(w, apierror.BadRequest("invalid cursor"))
+			return
+		}
+		response.Error(w, apierror.InternalError("Failed to fetch logs"))
+		return
+	}
+
 	response.OK(w, map[string]interface{}{
-		"message": "Inventory logs endpoint ready. Filter by user_id to see items.",
-		"data": []string{},
+		"data":        events,
+		"next_cursor": next,
+		"limit":       q.Limit,
 	})
 }
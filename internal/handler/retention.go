@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/retention"
+	"vinzhub-rest-api-v2/internal/service"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxPinBodySize bounds pin requests.
+const maxPinBodySize = 4 << 10
+
+// RetentionHandler handles admin retention HTTP requests.
+type RetentionHandler struct {
+	scheduler  *service.CleanupScheduler
+	schedulers map[string]*service.CleanupScheduler
+	pins       *retention.Pins
+}
+
+// NewRetentionHandler creates a new retention handler. The scheduler is used
+// when no ?game= is given.
+func NewRetentionHandler(scheduler *service.CleanupScheduler, pins *retention.Pins) *RetentionHandler {
+	return &RetentionHandler{
+		scheduler:  scheduler,
+		schedulers: make(map[string]*service.CleanupScheduler),
+		pins:       pins,
+	}
+}
+
+// AddGame registers the cleanup scheduler of a game for ?game=.
+func (h *RetentionHandler) AddGame(game string, scheduler *service.CleanupScheduler) {
+	h.schedulers[game] = scheduler
+}
+
+// RunRetention handles POST /api/v1/admin/retention/run
+// Applies the retention policy of ?game= (default fishit) now. It is a dry
+// run unless ?dry_run=false, and returns the report either way.
+func (h *RetentionHandler) RunRetention(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	game := query.Get("game")
+	scheduler := h.scheduler
+	if game != "" {
+		var ok bool
+		if scheduler, ok = h.schedulers[game]; !ok {
+			response.Error(w, apierror.NotFound("unknown game: "+game))
+			return
+		}
+	}
+
+	dryRun := true
+	if v := query.Get("dry_run"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			response.Error(w, apierror.BadRequest("dry_run must be true or false"))
+			return
+		}
+		dryRun = parsed
+	}
+
+	report, err := scheduler.Run(r.Context(), dryRun)
+	if err != nil {
+		log.Printf("[RetentionHandler] Retention run failed: %v", err)
+		if report != nil && report.Purged > 0 {
+			response.Error(w, apierror.InternalError(fmt.Sprintf("Retention run failed after purging %d inventories", report.Purged)))
+			return
+		}
+		response.Error(w, apierror.InternalError("Retention run failed"))
+		return
+	}
+
+	response.OK(w, report)
+}
+
+// ListPins handles GET /api/v1/admin/retention/pins
+func (h *RetentionHandler) ListPins(w http.ResponseWriter, r *http.Request) {
+	response.OK(w, h.pins.List())
+}
+
+// PinUser handles PUT /api/v1/admin/retention/pins/{roblox_user_id}
+// Takes an optional {"reason": "..."} body.
+func (h *RetentionHandler) PinUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPinBodySize)).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(w, apierror.BadRequest("invalid JSON body"))
+		return
+	}
+
+	pin, err := h.pins.Pin(chi.URLParam(r, "roblox_user_id"), body.Reason)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.OK(w, pin)
+}
+
+// UnpinUser handles DELETE /api/v1/admin/retention/pins/{roblox_user_id}
+func (h *RetentionHandler) UnpinUser(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+	unpinned, err := h.pins.Unpin(robloxUserID)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if !unpinned {
+		response.Error(w, apierror.NotFound("user is not pinned"))
+		return
+	}
+
+	response.OK(w, map[string]interface{}{"status": "unpinned", "roblox_user_id": robloxUserID})
+}
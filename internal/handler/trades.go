@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/internal/service"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TradeHandler handles item transfer HTTP requests.
+type TradeHandler struct {
+	analyzer *service.TradeAnalyzer
+}
+
+// NewTradeHandler creates a new trade handler.
+func NewTradeHandler(analyzer *service.TradeAnalyzer) *TradeHandler {
+	return &TradeHandler{
+		analyzer: analyzer,
+	}
+}
+
+// ListTrades handles GET /api/v1/trades
+// Lists detected transfers, newest first. ?user= matches the sender or the
+// receiver, ?uuid= one item; pages follow ?cursor= with ?limit= (default 50, max 200).
+func (h *TradeHandler) ListTrades(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := model.ItemTransferQuery{
+		RobloxUserID: query.Get("user"),
+		UUID:         query.Get("uuid"),
+		Cursor:       query.Get("cursor"),
+	}
+	q.Limit, _ = strconv.Atoi(query.Get("limit"))
+	if q.Limit < 1 || q.Limit > 200 {
+		q.Limit = 50
+	}
+
+	transfers, next, err := h.analyzer.ListTransfers(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			response.Error(w, apierror.BadRequest("invalid cursor"))
+			return
+		}
+		response.Error(w, apierror.InternalError("Failed to fetch trades"))
+		return
+	}
+
+	response.OK(w, map[string]interface{}{
+		"data":        transfers,
+		"next_cursor": next,
+		"limit":       q.Limit,
+	})
+}
+
+// GetItemHistory handles GET /api/v1/items/{uuid}/history
+// Returns the item's transfers and inventory events, oldest first
+// (?limit= most recent of each, default 100, max 500).
+func (h *TradeHandler) GetItemHistory(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+
+	history, err := h.analyzer.ItemHistory(r.Context(), chi.URLParam(r, "uuid"), limit)
+	if err != nil {
+		response.Error(w, apierror.InternalError("Failed to fetch item history"))
+		return
+	}
+	if len(history.Transfers) == 0 && len(history.Events) == 0 {
+		response.Error(w, apierror.NotFound("no history for this item"))
+		return
+	}
+
+	response.OK(w, history)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"vinzhub-rest-api-v2/internal/middleware"
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/service"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// UserDataHandler handles admin user data export and erasure requests.
+type UserDataHandler struct {
+	userDataService *service.UserDataService
+}
+
+// NewUserDataHandler creates a new user data handler.
+func NewUserDataHandler(userDataService *service.UserDataService) *UserDataHandler {
+	return &UserDataHandler{userDataService: userDataService}
+}
+
+// ExportUser handles GET /api/v1/admin/users/{roblox_user_id}/export
+// Returns everything stored about the user as JSON, or with ?format=zip as
+// a ZIP archive with one JSON file per source.
+func (h *UserDataHandler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		response.Error(w, apierror.BadRequest("format must be json or zip"))
+		return
+	}
+
+	export, err := h.userDataService.Export(r.Context(), robloxUserID)
+	if err != nil {
+		log.Printf("[UserDataHandler] Export of %s failed: %v", robloxUserID, err)
+		response.Error(w, apierror.InternalError("Failed to export user data"))
+		return
+	}
+
+	if format != "zip" {
+		response.OK(w, export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.zip"`, robloxUserID))
+	w.WriteHeader(http.StatusOK)
+	if err := writeExportZip(w, export); err != nil {
+		// Headers are sent; the truncated archive fails to open
+		log.Printf("[UserDataHandler] Failed to write export of %s: %v", robloxUserID, err)
+	}
+}
+
+// writeExportZip writes an export as manifest.json plus one file per source.
+func writeExportZip(w http.ResponseWriter, export *model.UserDataExport) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"manifest.json", map[string]interface{}{
+			"roblox_user_id": export.RobloxUserID,
+			"exported_at":    export.ExportedAt,
+			"unavailable":    export.Unavailable,
+		}},
+		{"inventories.json", export.Games},
+		{"sessions.json", export.Sessions},
+		{"key_accounts.json", export.KeyAccounts},
+		{"obfuscation_logs.json", export.ObfuscationLogs},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// DeleteUser handles DELETE /api/v1/admin/users/{roblox_user_id}
+// Erases everything stored about the user and reports what was deleted.
+// A partial erasure still responds 200, with complete=false and the errors;
+// the request can be repeated.
+func (h *UserDataHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	robloxUserID := chi.URLParam(r, "roblox_user_id")
+
+	erasure := h.userDataService.Erase(r.Context(), robloxUserID, requester(r))
+	response.OK(w, erasure)
+}
+
+// requester describes who made a request, for audit logs.
+func requester(r *http.Request) string {
+	who := "anonymous"
+	if p := middleware.GetPrincipal(r.Context()); p != nil {
+		who = p.String()
+	}
+	ip := r.Header.Get("X-Forwarded-For")
+	if ip == "" {
+		ip = r.RemoteAddr
+	}
+	return fmt.Sprintf("%s from %s (request %s)", who, ip, middleware.GetRequestID(r.Context()))
+}
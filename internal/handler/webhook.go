@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/webhook"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxWebhookBodySize bounds webhook create and update requests.
+const maxWebhookBodySize = 64 << 10
+
+// WebhookHandler handles admin webhook management HTTP requests.
+type WebhookHandler struct {
+	store      *webhook.Store
+	dispatcher *webhook.Dispatcher
+}
+
+// NewWebhookHandler creates a new webhook handler.
+func NewWebhookHandler(store *webhook.Store, dispatcher *webhook.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		store:      store,
+		dispatcher: dispatcher,
+	}
+}
+
+// ListWebhooks handles GET /api/v1/admin/webhooks
+// Secrets are redacted.
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks := h.store.List()
+	for i := range webhooks {
+		webhooks[i] = webhooks[i].Redacted()
+	}
+	response.OK(w, webhooks)
+}
+
+// CreateWebhook handles POST /api/v1/admin/webhooks
+// The response is the only one that includes the signing secret.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.store.Create(hook)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+
+	response.Created(w, created)
+}
+
+// GetWebhook handles GET /api/v1/admin/webhooks/{id}
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook := h.store.Get(chi.URLParam(r, "id"))
+	if hook == nil {
+		response.Error(w, apierror.NotFound("webhook not found"))
+		return
+	}
+
+	response.OK(w, hook.Redacted())
+}
+
+// UpdateWebhook handles PUT /api/v1/admin/webhooks/{id}
+// An empty secret keeps the current one.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := decodeWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.store.Update(chi.URLParam(r, "id"), hook)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if updated == nil {
+		response.Error(w, apierror.NotFound("webhook not found"))
+		return
+	}
+
+	response.OK(w, updated.Redacted())
+}
+
+// DeleteWebhook handles DELETE /api/v1/admin/webhooks/{id}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	deleted, err := h.store.Delete(id)
+	if err != nil {
+		response.Error(w, err)
+		return
+	}
+	if !deleted {
+		response.Error(w, apierror.NotFound("webhook not found"))
+		return
+	}
+
+	response.OK(w, map[string]interface{}{"status": "deleted", "id": id})
+}
+
+// TestWebhook handles POST /api/v1/admin/webhooks/{id}/test
+// Sends a sample rare_catch event once and returns the attempt.
+func (h *WebhookHandler) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	attempt, err := h.dispatcher.Test(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		response.Error(w, apierror.InternalError("Failed to send test event"))
+		return
+	}
+	if attempt == nil {
+		response.Error(w, apierror.NotFound("webhook not found"))
+		return
+	}
+
+	response.OK(w, attempt)
+}
+
+// GetDeliveries handles GET /api/v1/admin/webhooks/{id}/deliveries
+// Returns the most recent delivery attempts, newest first (?limit=, default 50, max 500).
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if h.store.Get(id) == nil {
+		response.Error(w, apierror.NotFound("webhook not found"))
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	response.OK(w, h.dispatcher.Attempts(id, limit))
+}
+
+// decodeWebhook reads a webhook from the request body, writing an error response if invalid.
+func decodeWebhook(w http.ResponseWriter, r *http.Request) (webhook.Webhook, bool) {
+	var hook webhook.Webhook
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&hook); err != nil {
+		response.Error(w, apierror.BadRequest("invalid JSON body"))
+		return hook, false
+	}
+	return hook, true
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"os"
 	"strings"
@@ -18,6 +19,7 @@ const TokenDataKey contextKey = "token_data"
 type AuthConfig struct {
 	TokenService *service.TokenService
 	APIKeys      []string
+	LoginKey     string // admin credential sent as X-Login-Key; empty disables it
 }
 
 // NewAuthMiddleware creates an authentication middleware with injected dependencies.
@@ -49,14 +51,21 @@ func NewAuthMiddleware(cfg AuthConfig) func(http.Handler) http.Handler {
 				return
 			}
 			
-			// Allow admin endpoints with X-Login-Key (handled by AdminHandler)
-			if strings.HasPrefix(r.URL.Path, "/api/v1/admin") {
-				// /admin/login and /admin/stats with X-Login-Key bypass regular auth
-				loginKey := r.Header.Get("X-Login-Key")
-				if loginKey != "" || r.URL.Path == "/api/v1/admin/login" {
-					next.ServeHTTP(w, r)
+			// Admin login verifies the key itself (handled by AdminHandler)
+			if r.URL.Path == "/api/v1/admin/login" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Admin credentials (dashboard login key)
+			if loginKey := r.Header.Get("X-Login-Key"); loginKey != "" {
+				if cfg.LoginKey == "" || !secretEqual(loginKey, cfg.LoginKey) {
+					writeError(w, apierror.Unauthorized("Invalid login key"))
 					return
 				}
+				ctx := context.WithValue(r.Context(), PrincipalKey, &Principal{Kind: PrincipalAdmin})
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
 			}
 
 			// Try X-Token first (session tokens)
@@ -69,6 +78,9 @@ func NewAuthMiddleware(cfg AuthConfig) func(http.Handler) http.Handler {
 				}
 
 				ctx := context.WithValue(r.Context(), TokenDataKey, tokenData)
+				ctx = context.WithValue(ctx, PrincipalKey, &Principal{Kind: PrincipalSession, Token: tokenData})
+				// Writes made with a session are attributed to its key account
+				ctx = service.WithKeyAccount(ctx, tokenData.KeyAccountID)
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
@@ -98,7 +110,8 @@ func NewAuthMiddleware(cfg AuthConfig) func(http.Handler) http.Handler {
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), PrincipalKey, &Principal{Kind: PrincipalAPIKey})
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -130,12 +143,18 @@ func getAPIKeysFromEnv() []string {
 
 // isValidKey checks if the provided key is in the valid keys list.
 func isValidKey(key string, validKeys []string) bool {
-	for _, valid := range validKeys {
-		if key == valid {
-			return true
+	valid := false
+	for _, k := range validKeys {
+		if secretEqual(key, k) {
+			valid = true
 		}
 	}
-	return false
+	return valid
+}
+
+// secretEqual compares secrets in time that does not depend on their content.
+func secretEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
 }
 
 // GetTokenDataFromContext retrieves token data from request context.
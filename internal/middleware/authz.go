@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/apierror"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PrincipalKey is the key for storing the authenticated principal in request context.
+const PrincipalKey contextKey = "principal"
+
+// Principal kinds set by the auth middleware.
+const (
+	PrincipalSession = "session" // X-Token, bound to one Roblox user and key account
+	PrincipalAPIKey  = "api_key" // X-API-Key or Bearer key
+	PrincipalAdmin   = "admin"   // X-Login-Key
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Kind  string
+	Token *model.TokenData // set for PrincipalSession
+}
+
+// String describes the principal for logs.
+func (p *Principal) String() string {
+	if p.Kind == PrincipalSession && p.Token != nil {
+		return "session(roblox_user_id=" + p.Token.RobloxUserID + ")"
+	}
+	return p.Kind
+}
+
+// GetPrincipal retrieves the authenticated principal from request context.
+// Returns nil for requests that skipped authentication.
+func GetPrincipal(ctx context.Context) *Principal {
+	if p, ok := ctx.Value(PrincipalKey).(*Principal); ok {
+		return p
+	}
+	return nil
+}
+
+// Policy decides whether principal p may perform request r.
+// It returns nil to allow the request or the reason it is denied.
+type Policy func(p *Principal, r *http.Request) error
+
+// OwnRobloxUser lets session tokens act only on the Roblox user named by the
+// URL parameter param. API keys and admin credentials may act on any user.
+func OwnRobloxUser(param string) Policy {
+	return func(p *Principal, r *http.Request) error {
+		if p.Kind != PrincipalSession {
+			return nil
+		}
+		if p.Token == nil || chi.URLParam(r, param) != p.Token.RobloxUserID {
+			return errors.New("session token may only access its own Roblox user")
+		}
+		return nil
+	}
+}
+
+// Privileged allows API keys and admin credentials but not session tokens.
+func Privileged(p *Principal, r *http.Request) error {
+	if p.Kind == PrincipalSession {
+		return errors.New("session tokens cannot access this endpoint")
+	}
+	return nil
+}
+
+// Authorize enforces policy on requests authenticated by NewAuthMiddleware.
+// Denials are answered with 403 and logged with the request ID.
+func Authorize(policy Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := GetPrincipal(r.Context())
+			if p == nil {
+				writeError(w, apierror.Unauthorized("Authentication required. Use X-Token or X-API-Key header."))
+				return
+			}
+			if err := policy(p, r); err != nil {
+				log.Printf("[Authz] Denied %s %s for %s: %v (request_id=%s)",
+					r.Method, r.URL.Path, p, err, GetRequestID(r.Context()))
+				writeError(w, apierror.Forbidden(err.Error()))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	session = &Principal{Kind: PrincipalSession, Token: &model.TokenData{RobloxUserID: "100", KeyAccountID: 7}}
+	apiKey  = &Principal{Kind: PrincipalAPIKey}
+	admin   = &Principal{Kind: PrincipalAdmin}
+)
+
+// serve routes a request as principal p through policy on pattern.
+func serve(t *testing.T, p *Principal, pattern string, policy Policy, path string) int {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if p != nil {
+				req = req.WithContext(context.WithValue(req.Context(), PrincipalKey, p))
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	r.With(Authorize(policy)).Get(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestOwnRobloxUser(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Principal
+		path string
+		want int
+	}{
+		{"session own user", session, "/inventory/100", http.StatusNoContent},
+		{"session other user", session, "/inventory/200", http.StatusForbidden},
+		{"session without token data", &Principal{Kind: PrincipalSession}, "/inventory/100", http.StatusForbidden},
+		{"api key any user", apiKey, "/inventory/200", http.StatusNoContent},
+		{"admin any user", admin, "/inventory/200", http.StatusNoContent},
+		{"unauthenticated", nil, "/inventory/100", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serve(t, tt.p, "/inventory/{roblox_user_id}", OwnRobloxUser("roblox_user_id"), tt.path)
+			if got != tt.want {
+				t.Errorf("status = %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrivileged(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Principal
+		want int
+	}{
+		{"session", session, http.StatusForbidden},
+		{"api key", apiKey, http.StatusNoContent},
+		{"admin", admin, http.StatusNoContent},
+		{"unauthenticated", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(t, tt.p, "/trades", Privileged, "/trades"); got != tt.want {
+				t.Errorf("status = %d; want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareCredentials(t *testing.T) {
+	auth := NewAuthMiddleware(AuthConfig{APIKeys: []string{"key-1", "key-2"}, LoginKey: "login"})
+	var got *Principal
+	h := auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = GetPrincipal(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		wantCode int
+		wantKind string
+	}{
+		{"login key", "X-Login-Key", "login", http.StatusOK, PrincipalAdmin},
+		{"wrong login key", "X-Login-Key", "logi", http.StatusUnauthorized, ""},
+		{"api key", "X-API-Key", "key-2", http.StatusOK, PrincipalAPIKey},
+		{"bearer key", "Authorization", "Bearer key-1", http.StatusOK, PrincipalAPIKey},
+		{"wrong api key", "X-API-Key", "key-3", http.StatusUnauthorized, ""},
+		{"no credentials", "", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/inventory/100", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d; want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantKind == "" {
+				if got != nil {
+					t.Errorf("handler reached as %v; want rejected", got)
+				}
+			} else if got == nil || got.Kind != tt.wantKind {
+				t.Errorf("principal = %v; want %s", got, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareLoginKeyDisabled(t *testing.T) {
+	h := NewAuthMiddleware(AuthConfig{APIKeys: []string{"key"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler reached with a login key while login keys are disabled")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/inventory/100", nil)
+	req.Header.Set("X-Login-Key", "anything")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d; want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
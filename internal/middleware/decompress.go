@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"vinzhub-rest-api-v2/pkg/apierror"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// BodyEncodingStats holds request body totals for one Content-Encoding.
+type BodyEncodingStats struct {
+	Requests          int64   `json:"requests"`
+	WireBytes         int64   `json:"wire_bytes"`         // bytes received, as sent
+	DecompressedBytes int64   `json:"decompressed_bytes"` // bytes handed to handlers
+	SavedRatio        float64 `json:"saved_ratio"`        // 1 - wire/decompressed
+}
+
+// bodyCounters accumulates BodyEncodingStats.
+type bodyCounters struct {
+	requests, wire, decompressed atomic.Int64
+}
+
+var bodyStats sync.Map // encoding -> *bodyCounters
+
+func recordBody(encoding string, wire, decompressed int64) {
+	v, _ := bodyStats.LoadOrStore(encoding, &bodyCounters{})
+	c := v.(*bodyCounters)
+	c.requests.Add(1)
+	c.wire.Add(wire)
+	c.decompressed.Add(decompressed)
+}
+
+// BodyStats returns request body size totals since startup, keyed by
+// Content-Encoding ("identity" for uncompressed bodies).
+func BodyStats() map[string]BodyEncodingStats {
+	stats := make(map[string]BodyEncodingStats)
+	bodyStats.Range(func(key, value interface{}) bool {
+		c := value.(*bodyCounters)
+		s := BodyEncodingStats{
+			Requests:          c.requests.Load(),
+			WireBytes:         c.wire.Load(),
+			DecompressedBytes: c.decompressed.Load(),
+		}
+		if s.DecompressedBytes > 0 {
+			s.SavedRatio = 1 - float64(s.WireBytes)/float64(s.DecompressedBytes)
+		}
+		stats[key.(string)] = s
+		return true
+	})
+	return stats
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	eof bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err == io.EOF {
+		c.eof = true
+	}
+	return n, err
+}
+
+// decodedBody is a decompressing request body. Close releases the decoder and
+// the underlying body.
+type decodedBody struct {
+	io.Reader
+	closeFn func()
+	body    io.Closer
+}
+
+func (d *decodedBody) Close() error {
+	d.closeFn()
+	return d.body.Close()
+}
+
+// Decompress accepts request bodies sent with Content-Encoding gzip or zstd
+// and hands handlers the decoded bytes. Decoding stops with 413 once more
+// than maxSize bytes come out, so a small compressed body cannot expand into
+// an unbounded one. Unsupported encodings are rejected with 415.
+// Wire and decoded sizes are recorded for BodyStats.
+func Decompress(maxSize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+			if encoding == "" {
+				encoding = "identity"
+			}
+
+			wire := &countingReader{r: r.Body}
+			var decoded io.Reader
+			closeFn := func() {}
+
+			switch encoding {
+			case "identity":
+				decoded = wire
+			case "gzip", "x-gzip":
+				encoding = "gzip"
+				zr, err := gzip.NewReader(wire)
+				if err != nil {
+					writeError(w, apierror.BadRequest("invalid gzip request body"))
+					return
+				}
+				decoded, closeFn = zr, func() { zr.Close() }
+			case "zstd":
+				zr, err := zstd.NewReader(wire, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+				if err != nil {
+					writeError(w, apierror.BadRequest("invalid zstd request body"))
+					return
+				}
+				decoded, closeFn = zr, zr.Close
+			default:
+				writeError(w, apierror.UnsupportedMediaType("unsupported Content-Encoding: "+encoding+" (use gzip or zstd)"))
+				return
+			}
+
+			out := &countingReader{r: decoded}
+			body := io.ReadCloser(&decodedBody{Reader: out, closeFn: closeFn, body: r.Body})
+			if encoding != "identity" {
+				// Handlers see a plain body of unknown length
+				body = http.MaxBytesReader(w, body, maxSize)
+				r.Header.Del("Content-Encoding")
+				r.Header.Del("Content-Length")
+				r.ContentLength = -1
+			}
+			r.Body = body
+
+			next.ServeHTTP(w, r)
+
+			// Only bodies read to the end are counted, so rejected ones don't skew the ratio
+			if out.eof {
+				recordBody(encoding, wire.n, out.n)
+			}
+		})
+	}
+}
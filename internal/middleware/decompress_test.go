@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const testMaxBodySize = 1024
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, data []byte) []byte {
+	t.Helper()
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatalf("zstd: %v", err)
+	}
+	defer zw.Close()
+	return zw.EncodeAll(data, nil)
+}
+
+// decompressResult is what the handler behind Decompress saw.
+type decompressResult struct {
+	code          int
+	reached       bool
+	body          []byte
+	encoding      string
+	contentLength int64
+}
+
+// serveDecompress sends body with the given Content-Encoding through
+// Decompress to a handler that reads it like the inventory handlers do.
+func serveDecompress(t *testing.T, encoding string, body []byte) decompressResult {
+	t.Helper()
+	var got decompressResult
+	h := Decompress(testMaxBodySize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.reached = true
+		got.encoding = r.Header.Get("Content-Encoding")
+		got.contentLength = r.ContentLength
+		defer r.Body.Close()
+		data, err := io.ReadAll(r.Body)
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			t.Errorf("reading body: %v", err)
+		}
+		got.body = data
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/inventory/100", bytes.NewReader(body))
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	got.code = rec.Code
+	return got
+}
+
+func TestDecompress(t *testing.T) {
+	payload := []byte(`{"fish":[{"uuid":"a","fish_id":1}]}`)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"gzip", "gzip", gzipped(t, payload)},
+		{"x-gzip", "x-gzip", gzipped(t, payload)},
+		{"zstd", "zstd", zstded(t, payload)},
+		{"case and spaces", " GZIP ", gzipped(t, payload)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serveDecompress(t, tt.encoding, tt.body)
+			if got.code != http.StatusOK {
+				t.Fatalf("status = %d; want %d", got.code, http.StatusOK)
+			}
+			if !bytes.Equal(got.body, payload) {
+				t.Errorf("body = %q; want %q", got.body, payload)
+			}
+			if got.encoding != "" || got.contentLength != -1 {
+				t.Errorf("handler saw Content-Encoding %q, length %d; want a plain body of unknown length", got.encoding, got.contentLength)
+			}
+		})
+	}
+}
+
+func TestDecompressIdentity(t *testing.T) {
+	// Identity bodies are passed through untouched, even past the limit:
+	// handlers apply their own
+	payload := bytes.Repeat([]byte("a"), 2*testMaxBodySize)
+	for _, encoding := range []string{"", "identity"} {
+		got := serveDecompress(t, encoding, payload)
+		if got.code != http.StatusOK {
+			t.Fatalf("Content-Encoding %q: status = %d; want %d", encoding, got.code, http.StatusOK)
+		}
+		if !bytes.Equal(got.body, payload) {
+			t.Errorf("Content-Encoding %q: body changed", encoding)
+		}
+		if got.contentLength != int64(len(payload)) {
+			t.Errorf("Content-Encoding %q: length = %d; want %d", encoding, got.contentLength, len(payload))
+		}
+	}
+}
+
+func TestDecompressOversized(t *testing.T) {
+	// Compresses to a few dozen bytes, well under the limit on the wire
+	bomb := bytes.Repeat([]byte{'0'}, 64*testMaxBodySize)
+
+	before := BodyStats()
+	for _, tt := range []struct {
+		encoding string
+		body     []byte
+	}{
+		{"gzip", gzipped(t, bomb)},
+		{"zstd", zstded(t, bomb)},
+	} {
+		if len(tt.body) >= testMaxBodySize {
+			t.Fatalf("%s body is %d bytes on the wire; want under %d", tt.encoding, len(tt.body), testMaxBodySize)
+		}
+		got := serveDecompress(t, tt.encoding, tt.body)
+		if got.code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: status = %d; want %d", tt.encoding, got.code, http.StatusRequestEntityTooLarge)
+		}
+	}
+
+	// Rejected bodies are not counted
+	after := BodyStats()
+	for _, encoding := range []string{"gzip", "zstd"} {
+		if after[encoding].Requests != before[encoding].Requests {
+			t.Errorf("%s requests counted %d -> %d; want unchanged", encoding, before[encoding].Requests, after[encoding].Requests)
+		}
+	}
+}
+
+func TestDecompressRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		want     int
+	}{
+		{"unsupported encoding", "br", []byte("anything"), http.StatusUnsupportedMediaType},
+		{"stacked encodings", "gzip, zstd", []byte("anything"), http.StatusUnsupportedMediaType},
+		{"invalid gzip", "gzip", []byte("not gzip"), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := serveDecompress(t, tt.encoding, tt.body)
+			if got.code != tt.want {
+				t.Errorf("status = %d; want %d", got.code, tt.want)
+			}
+			if got.reached {
+				t.Error("handler reached; want the request rejected")
+			}
+		})
+	}
+}
+
+func TestDecompressStats(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"coins":1}`, 50))
+	before := BodyStats()["gzip"]
+
+	wire := gzipped(t, payload)
+	if got := serveDecompress(t, "gzip", wire); got.code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", got.code, http.StatusOK)
+	}
+
+	after := BodyStats()["gzip"]
+	if after.Requests != before.Requests+1 {
+		t.Errorf("requests = %d; want %d", after.Requests, before.Requests+1)
+	}
+	if d := after.WireBytes - before.WireBytes; d != int64(len(wire)) {
+		t.Errorf("wire bytes grew by %d; want %d", d, len(wire))
+	}
+	if d := after.DecompressedBytes - before.DecompressedBytes; d != int64(len(payload)) {
+		t.Errorf("decompressed bytes grew by %d; want %d", d, len(payload))
+	}
+}
+
+func TestDecompressPatch(t *testing.T) {
+	// Delta syncs are write endpoints too: the patch media type must survive
+	patch := []byte(`{"stats":{"coins":20}}`)
+	var contentType string
+	var body []byte
+	h := Decompress(testMaxBodySize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/inventory/100", bytes.NewReader(zstded(t, patch)))
+	req.Header.Set("Content-Encoding", "zstd")
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rec.Code, http.StatusOK)
+	}
+	if contentType != "application/merge-patch+json" || !bytes.Equal(body, patch) {
+		t.Errorf("handler saw %q %q; want the decompressed merge patch", contentType, body)
+	}
+}
@@ -0,0 +1,274 @@
+// Package migrate applies numbered schema migrations embedded in the binary.
+//
+// A migration is a pair of files NNNN_name.up.<ext> and NNNN_name.down.<ext>
+// (the down file is optional). What the files contain is up to the Driver
+// running them: SQL for SQLDriver, index specs for the MongoDB driver.
+// Applied migrations are recorded with the checksum of their up file, and a
+// migration edited after it was applied stops further runs.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Migration is one numbered schema change.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // empty if the migration cannot be reverted
+	Checksum string // hex SHA-256 of Up
+}
+
+// String returns the migration's file name stem, e.g. "0001_inventory".
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Record is an applied migration as stored in the database.
+type Record struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Status describes one migration, known to the binary, applied, or both.
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Modified  bool       `json:"modified,omitempty"` // applied with a different checksum
+	Unknown   bool       `json:"unknown,omitempty"`  // applied by a newer binary
+}
+
+// Driver runs migrations against one database.
+type Driver interface {
+	// Lock keeps other processes from migrating the database until the
+	// returned function is called.
+	Lock(ctx context.Context) (unlock func(), err error)
+
+	// Applied returns the applied migrations in version order.
+	Applied(ctx context.Context) ([]Record, error)
+
+	// Apply runs m's up or down file and records or forgets m with it.
+	// Applying a migration that is already in the wanted state is a no-op,
+	// so a driver may check again under its own transaction.
+	Apply(ctx context.Context, m Migration, up bool) error
+}
+
+// ErrChecksumMismatch is returned when an applied migration's file changed.
+var ErrChecksumMismatch = errors.New("migration was modified after it was applied")
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.[a-z]+$`)
+
+// Load reads the migrations in the root of fsys, ordered by version.
+// Other files are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	downs := make(map[int64]string)
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s", e.Name())
+		}
+		content, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+
+		if match[3] == "down" {
+			if _, dup := downs[version]; dup {
+				return nil, fmt.Errorf("duplicate down migration %d", version)
+			}
+			downs[version] = string(content)
+			continue
+		}
+		if _, dup := byVersion[version]; dup {
+			return nil, fmt.Errorf("duplicate migration %d", version)
+		}
+		sum := sha256.Sum256(content)
+		byVersion[version] = &Migration{
+			Version:  version,
+			Name:     match[2],
+			Up:       string(content),
+			Checksum: hex.EncodeToString(sum[:]),
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version, down := range downs {
+		m, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("down migration %d has no up migration", version)
+		}
+		m.Down = down
+	}
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies a set of migrations through a driver.
+type Migrator struct {
+	driver     Driver
+	migrations []Migration
+	label      string
+}
+
+// New creates a migrator. label names the database in logs.
+func New(driver Driver, migrations []Migration, label string) *Migrator {
+	return &Migrator{driver: driver, migrations: migrations, label: label}
+}
+
+// Up applies every pending migration in version order and returns them.
+// Migrations applied by a newer binary are left alone.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	unlock, err := m.driver.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := m.driver.Apply(ctx, mig, true); err != nil {
+			return done, fmt.Errorf("migration %s failed: %w", mig, err)
+		}
+		log.Printf("[Migrate] %s: applied %s", m.label, mig)
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first, and
+// returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	unlock, err := m.driver.Lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	var done []Migration
+	for _, version := range versions {
+		if len(done) == steps {
+			break
+		}
+		mig, ok := m.find(version)
+		if !ok {
+			return done, fmt.Errorf("migration %d (%s) is not known to this binary and cannot be reverted", version, applied[version].Name)
+		}
+		if mig.Down == "" {
+			return done, fmt.Errorf("migration %s cannot be reverted", mig)
+		}
+		if err := m.driver.Apply(ctx, mig, false); err != nil {
+			return done, fmt.Errorf("reverting migration %s failed: %w", mig, err)
+		}
+		log.Printf("[Migrate] %s: reverted %s", m.label, mig)
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Status lists the known and applied migrations in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+
+	var statuses []Status
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if r, ok := applied[mig.Version]; ok {
+			appliedAt := r.AppliedAt
+			s.Applied = true
+			s.AppliedAt = &appliedAt
+			s.Modified = r.Checksum != mig.Checksum
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range applied {
+		appliedAt := r.AppliedAt
+		statuses = append(statuses, Status{Version: r.Version, Name: r.Name, Applied: true, AppliedAt: &appliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// applied returns the applied migrations by version, failing if a known one
+// was modified since.
+func (m *Migrator) applied(ctx context.Context) (map[int64]Record, error) {
+	records, err := m.driver.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+		mig, ok := m.find(r.Version)
+		if !ok {
+			log.Printf("[Migrate] %s: migration %d (%s) was applied by a newer version", m.label, r.Version, r.Name)
+			continue
+		}
+		if r.Checksum != mig.Checksum {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
@@ -0,0 +1,177 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Dialect holds the SQL a database needs to track and lock migrations.
+type Dialect struct {
+	CreateTable string             // creates schema_migrations if missing
+	Placeholder func(n int) string // the nth query argument, from 1
+	Begin       string             // starts a migration's transaction
+	Lock        string             // takes a session lock for the run; empty if Begin suffices
+	Unlock      string             // releases Lock
+}
+
+// SQLite runs each migration in a BEGIN IMMEDIATE transaction, which holds
+// the database's write lock.
+var SQLite = Dialect{
+	CreateTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+	Placeholder: func(int) string { return "?" },
+	Begin:       "BEGIN IMMEDIATE",
+}
+
+// Postgres holds an advisory lock, keyed by the current schema, for the
+// whole run.
+var Postgres = Dialect{
+	CreateTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	Placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	Begin:       "BEGIN",
+	Lock:        "SELECT pg_advisory_lock(hashtext(COALESCE(current_schema(), '') || '.schema_migrations'))",
+	Unlock:      "SELECT pg_advisory_unlock(hashtext(COALESCE(current_schema(), '') || '.schema_migrations'))",
+}
+
+// SQLDriver runs SQL migrations, recording them in a schema_migrations table.
+// Each migration runs in its own transaction. It is not safe for concurrent use.
+type SQLDriver struct {
+	db      *sql.DB
+	dialect Dialect
+	conn    *sql.Conn // held between Lock and unlock
+}
+
+// NewSQLDriver creates a driver for db.
+func NewSQLDriver(db *sql.DB, dialect Dialect) *SQLDriver {
+	return &SQLDriver{db: db, dialect: dialect}
+}
+
+// Lock reserves a connection for the run and takes the dialect's lock on it.
+func (d *SQLDriver) Lock(ctx context.Context) (func(), error) {
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	if d.dialect.Lock != "" {
+		if _, err := conn.ExecContext(ctx, d.dialect.Lock); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to lock migrations: %w", err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, d.dialect.CreateTable); err != nil {
+		d.release(conn)
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	d.conn = conn
+	return func() {
+		d.conn = nil
+		d.release(conn)
+	}, nil
+}
+
+func (d *SQLDriver) release(conn *sql.Conn) {
+	if d.dialect.Unlock != "" {
+		// A fresh context, so a cancelled run still unlocks
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn.ExecContext(ctx, d.dialect.Unlock)
+	}
+	conn.Close()
+}
+
+// Applied returns the recorded migrations in version order.
+func (d *SQLDriver) Applied(ctx context.Context) ([]Record, error) {
+	var q interface {
+		ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+		QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	} = d.db
+	if d.conn != nil {
+		q = d.conn
+	}
+
+	if _, err := q.ExecContext(ctx, d.dialect.CreateTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	rows, err := q.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.Version, &r.Name, &r.Checksum, &r.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Apply runs the migration and its record change in one transaction on the
+// locked connection.
+func (d *SQLDriver) Apply(ctx context.Context, m Migration, up bool) error {
+	if d.conn == nil {
+		return fmt.Errorf("migrations are not locked")
+	}
+	conn := d.conn
+	p := d.dialect.Placeholder
+
+	if _, err := conn.ExecContext(ctx, d.dialect.Begin); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			conn.ExecContext(context.Background(), "ROLLBACK")
+		}
+	}()
+
+	// Another process may have got here first while SQLite was unlocked
+	var count int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations WHERE version = "+p(1), m.Version).Scan(&count); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	if (count > 0) == up {
+		return nil
+	}
+
+	var err error
+	if up {
+		if _, err := conn.ExecContext(ctx, m.Up); err != nil {
+			return err
+		}
+		_, err = conn.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (%s, %s, %s, %s)", p(1), p(2), p(3), p(4)),
+			m.Version, m.Name, m.Checksum, time.Now().UTC())
+	} else {
+		if _, err := conn.ExecContext(ctx, m.Down); err != nil {
+			return err
+		}
+		_, err = conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = "+p(1), m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// Ensure SQLDriver implements Driver
+var _ Driver = (*SQLDriver)(nil)
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// ItemChange describes one item that was added, removed or modified between
+// two inventory versions. Items are matched by category and uuid.
+type ItemChange struct {
+	Category string          `json:"category"`
+	UUID     string          `json:"uuid,omitempty"`
+	ItemID   int64           `json:"item_id,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Tier     Tier            `json:"tier,omitempty"`
+	Fields   []string        `json:"fields,omitempty"` // changed fields, for modified items
+	Before   *InventoryEntry `json:"before,omitempty"`
+	After    *InventoryEntry `json:"after,omitempty"`
+}
+
+// StatChange is the before/after value of a numeric player stat.
+type StatChange struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Delta  float64 `json:"delta"`
+}
+
+// InventoryDiff is the set of changes between two inventory versions.
+type InventoryDiff struct {
+	Added   []ItemChange `json:"added"`
+	Removed []ItemChange `json:"removed"`
+	Changed []ItemChange `json:"changed"`
+	Coins   StatChange   `json:"coins"`
+	Level   StatChange   `json:"level"`
+}
+
+// IsEmpty reports whether the diff contains no item or stat changes.
+func (d *InventoryDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 &&
+		d.Coins.Delta == 0 && d.Level.Delta == 0
+}
+
+// DiffSide identifies one of the two inventories that were compared.
+type DiffSide struct {
+	Source      string     `json:"source"` // "history", "buffer" or "payload"
+	Version     int64      `json:"version,omitempty"`
+	ContentHash string     `json:"content_hash,omitempty"`
+	CapturedAt  *time.Time `json:"captured_at,omitempty"`
+}
+
+// InventoryDiffResult is a diff together with what was compared.
+type InventoryDiffResult struct {
+	RobloxUserID string         `json:"roblox_user_id"`
+	From         *DiffSide      `json:"from,omitempty"`
+	To           *DiffSide      `json:"to,omitempty"`
+	Diff         *InventoryDiff `json:"diff"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// InventorySnapshot is one immutable version of a player's inventory.
+// Versions are numbered per player starting at 1 and only advance when the
+// content hash changes.
+type InventorySnapshot struct {
+	RobloxUserID  string          `json:"roblox_user_id"`
+	Version       int64           `json:"version"`
+	KeyAccountID  int64           `json:"key_account_id"`
+	ContentHash   string          `json:"content_hash"`
+	InventoryJSON json.RawMessage `json:"inventory,omitempty"`
+	Size          int             `json:"size"`
+	CapturedAt    time.Time       `json:"captured_at"`
+}
@@ -4,12 +4,14 @@ import "time"
 
 // RawInventory represents raw JSON inventory data.
 type RawInventory struct {
-	ID            int64     `json:"id"`
-	KeyAccountID  int64     `json:"key_account_id"`
-	RobloxUserID  string    `json:"roblox_user_id"`
-	InventoryJSON []byte    `json:"inventory_json"`
-	SyncedAt      time.Time `json:"synced_at"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64      `json:"id"`
+	KeyAccountID  int64      `json:"key_account_id"`
+	RobloxUserID  string     `json:"roblox_user_id"`
+	InventoryJSON []byte     `json:"inventory_json"`
+	ContentHash   string     `json:"content_hash"`
+	SyncedAt      time.Time  `json:"synced_at"`
+	LastSeenAt    *time.Time `json:"last_seen_at,omitempty"` // last sync, including unchanged ones
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
 // InventoryItem represents a single inventory record for batch operations.
@@ -17,6 +19,7 @@ type InventoryItem struct {
 	KeyAccountID int64
 	RobloxUserID string
 	RawJSON      []byte
+	ContentHash  string // canonical hash of RawJSON; computed on write if empty
 	SyncedAt     time.Time
 }
 
@@ -25,5 +28,27 @@ type BufferedInventory struct {
 	KeyAccountID int64     `json:"key_account_id"`
 	RobloxUserID string    `json:"roblox_user_id"`
 	RawJSON      []byte    `json:"raw_json"`
+	ContentHash  string    `json:"content_hash,omitempty"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
+
+// BatchResult reports what became of each item of a batch write. Items in
+// neither list were not written and may succeed if retried.
+type BatchResult struct {
+	Succeeded []string       // Roblox user IDs written
+	Failed    []BatchFailure // items rejected on their own; retrying will not help
+}
+
+// BatchFailure is a batch item that could not be written, and why.
+type BatchFailure struct {
+	RobloxUserID string `json:"roblox_user_id"`
+	Reason       string `json:"reason"`
+}
+
+// QuarantinedInventory is a buffered inventory update the database rejected,
+// set aside instead of being retried.
+type QuarantinedInventory struct {
+	Inventory     *BufferedInventory `json:"inventory"`
+	Reason        string             `json:"reason"`
+	QuarantinedAt time.Time          `json:"quarantined_at"`
+}
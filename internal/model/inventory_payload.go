@@ -0,0 +1,334 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Inventory categories sent by InventorySync.lua, in payload order.
+const (
+	CategoryFish     = "fish"
+	CategoryRods     = "rods"
+	CategoryBaits    = "baits"
+	CategoryPotions  = "potions"
+	CategoryStones   = "stones"
+	CategoryGears    = "gears"
+	CategoryTrophies = "trophies"
+	CategoryBoats    = "boats"
+	CategoryTotems   = "totems"
+	CategoryLanterns = "lanterns"
+)
+
+// InventoryCategories lists every item category of an inventory payload.
+var InventoryCategories = []string{
+	CategoryFish,
+	CategoryRods,
+	CategoryBaits,
+	CategoryPotions,
+	CategoryStones,
+	CategoryGears,
+	CategoryTrophies,
+	CategoryBoats,
+	CategoryTotems,
+	CategoryLanterns,
+}
+
+// InventoryPayloadKeys lists every top-level key the sync payload is known to carry.
+var InventoryPayloadKeys = append([]string{"player", "stats", "synced_at"}, InventoryCategories...)
+
+// IsInventoryCategory reports whether name is a known item category.
+func IsInventoryCategory(name string) bool {
+	for _, c := range InventoryCategories {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Tier is an item rarity (1=Common ... 7=Secret, 8=Exotic).
+// The game reports tiers either as numbers or as names, so both are accepted.
+type Tier int
+
+// TierNames maps the tier names used by the game to their numeric value.
+// Mirrors TierOrder in InventorySync.lua.
+var TierNames = map[string]Tier{
+	"Common":    1,
+	"Uncommon":  2,
+	"Rare":      3,
+	"Epic":      4,
+	"Legendary": 5,
+	"Mythical":  6,
+	"Secret":    7,
+	"Exotic":    8,
+}
+
+// Tiers referenced by name in code.
+const (
+	TierSecret Tier = 7
+	MaxTier    Tier = 8 // highest known tier
+)
+
+// Name returns the game's name for t, or "" if t is not a known tier.
+func (t Tier) Name() string {
+	for name, tier := range TierNames {
+		if tier == t {
+			return name
+		}
+	}
+	return ""
+}
+
+// UnmarshalJSON accepts a tier number or a tier name.
+func (t *Tier) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = 0
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var name string
+		if err := json.Unmarshal(data, &name); err != nil {
+			return err
+		}
+		if n, err := strconv.Atoi(name); err == nil {
+			*t = Tier(n)
+			return nil
+		}
+		tier, ok := TierNames[name]
+		if !ok {
+			return fmt.Errorf("unknown tier %q", name)
+		}
+		*t = tier
+		return nil
+	}
+	var n float64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("tier must be a number or a tier name")
+	}
+	if n != float64(int64(n)) {
+		return fmt.Errorf("tier must be a whole number")
+	}
+	*t = Tier(n)
+	return nil
+}
+
+// FlexString holds a value the client may send as either a string or a number
+// (variant IDs are names in some clients and numeric IDs in others).
+type FlexString string
+
+// UnmarshalJSON accepts a JSON string or number.
+func (s *FlexString) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*s = ""
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var v string
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		*s = FlexString(v)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("must be a string or a number")
+	}
+	*s = FlexString(n.String())
+	return nil
+}
+
+func isEmptyJSONArray(data []byte) bool {
+	if len(data) < 2 || data[0] != '[' || data[len(data)-1] != ']' {
+		return false
+	}
+	return len(bytes.TrimSpace(data[1:len(data)-1])) == 0
+}
+
+// PlayerInfo identifies the Roblox player the inventory belongs to.
+type PlayerInfo struct {
+	UserID      FlexString `json:"user_id"`
+	Username    string     `json:"username"`
+	DisplayName string     `json:"display_name"`
+}
+
+// PlayerStats holds the stats block of the sync payload.
+type PlayerStats struct {
+	Coins             float64         `json:"coins"`
+	Level             float64         `json:"level"`
+	EquippedItems     json.RawMessage `json:"equipped_items,omitempty"`
+	EquippedBaitID    json.RawMessage `json:"equipped_bait_id,omitempty"`
+	AutoSellThreshold json.RawMessage `json:"auto_sell_threshold,omitempty"`
+}
+
+// RodStats holds the computed rod stats sent for fishing rods.
+type RodStats struct {
+	Luck      float64 `json:"luck"`
+	Speed     float64 `json:"speed"`
+	MaxWeight float64 `json:"max_weight"`
+}
+
+// InventoryEntry is a single item of any category.
+// Categories share most fields; category-specific IDs (fish_id, rod_id, ...)
+// are kept separately and resolved through ID().
+type InventoryEntry struct {
+	UUID      string          `json:"uuid,omitempty"`
+	ItemID    int64           `json:"item_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Tier      Tier            `json:"tier,omitempty"`
+	Icon      string          `json:"icon,omitempty"`
+	Price     float64         `json:"price,omitempty"`
+	Favorited bool            `json:"favorited,omitempty"`
+	IsShiny   bool            `json:"is_shiny,omitempty"`
+	VariantID FlexString      `json:"variant_id,omitempty"`
+	Quantity  float64         `json:"quantity,omitempty"`
+	Equipped  bool            `json:"equipped,omitempty"`
+	IsSkin    bool            `json:"is_skin,omitempty"`
+	BaitType  string          `json:"bait_type,omitempty"`
+	Stats     *RodStats       `json:"stats,omitempty"`
+	Modifiers json.RawMessage `json:"modifiers,omitempty"`
+	Metadata  json.RawMessage `json:"metadata,omitempty"`
+
+	FishID    int64 `json:"fish_id,omitempty"`
+	RodID     int64 `json:"rod_id,omitempty"`
+	BaitID    int64 `json:"bait_id,omitempty"`
+	PotionID  int64 `json:"potion_id,omitempty"`
+	StoneID   int64 `json:"stone_id,omitempty"`
+	GearID    int64 `json:"gear_id,omitempty"`
+	TrophyID  int64 `json:"trophy_id,omitempty"`
+	BoatID    int64 `json:"boat_id,omitempty"`
+	TotemID   int64 `json:"totem_id,omitempty"`
+	LanternID int64 `json:"lantern_id,omitempty"`
+}
+
+// ID returns the game item ID, preferring item_id over the category-specific ID.
+func (e *InventoryEntry) ID() int64 {
+	if e.ItemID != 0 {
+		return e.ItemID
+	}
+	for _, id := range []int64{e.FishID, e.RodID, e.BaitID, e.PotionID, e.StoneID,
+		e.GearID, e.TrophyID, e.BoatID, e.TotemID, e.LanternID} {
+		if id != 0 {
+			return id
+		}
+	}
+	return 0
+}
+
+// Count returns the stack size of the entry. Items without a quantity count as one.
+func (e *InventoryEntry) Count() float64 {
+	if e.Quantity <= 0 {
+		return 1
+	}
+	return e.Quantity
+}
+
+// InventoryPayload is the typed form of the document posted by InventorySync.lua.
+// The raw bytes are what gets stored; this type is used for validation and
+// for features that need to look inside the inventory.
+type InventoryPayload struct {
+	Player   PlayerInfo       `json:"player"`
+	Fish     []InventoryEntry `json:"fish"`
+	Rods     []InventoryEntry `json:"rods"`
+	Baits    []InventoryEntry `json:"baits"`
+	Potions  []InventoryEntry `json:"potions"`
+	Stones   []InventoryEntry `json:"stones"`
+	Gears    []InventoryEntry `json:"gears"`
+	Trophies []InventoryEntry `json:"trophies"`
+	Boats    []InventoryEntry `json:"boats"`
+	Totems   []InventoryEntry `json:"totems"`
+	Lanterns []InventoryEntry `json:"lanterns"`
+	Stats    PlayerStats      `json:"stats"`
+	SyncedAt int64            `json:"synced_at,omitempty"`
+
+	// UnknownFields lists top-level keys not recognised by this server version.
+	// They are kept in the stored document untouched.
+	UnknownFields []string `json:"-"`
+}
+
+// Category returns the items of the named category, or nil if unknown.
+func (p *InventoryPayload) Category(name string) []InventoryEntry {
+	if ptr := p.categoryPtr(name); ptr != nil {
+		return *ptr
+	}
+	return nil
+}
+
+// categoryPtr returns a pointer to the slice backing the named category.
+func (p *InventoryPayload) categoryPtr(name string) *[]InventoryEntry {
+	switch strings.ToLower(name) {
+	case CategoryFish:
+		return &p.Fish
+	case CategoryRods:
+		return &p.Rods
+	case CategoryBaits:
+		return &p.Baits
+	case CategoryPotions:
+		return &p.Potions
+	case CategoryStones:
+		return &p.Stones
+	case CategoryGears:
+		return &p.Gears
+	case CategoryTrophies:
+		return &p.Trophies
+	case CategoryBoats:
+		return &p.Boats
+	case CategoryTotems:
+		return &p.Totems
+	case CategoryLanterns:
+		return &p.Lanterns
+	}
+	return nil
+}
+
+// SetCategory replaces the items of the named category. Unknown names are ignored.
+func (p *InventoryPayload) SetCategory(name string, items []InventoryEntry) {
+	if ptr := p.categoryPtr(name); ptr != nil {
+		*ptr = items
+	}
+}
+
+// UnmarshalJSON accepts Roblox's empty-array encoding for an empty stats table.
+func (s *PlayerStats) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(bytes.TrimSpace(data)) {
+		*s = PlayerStats{}
+		return nil
+	}
+	type plain PlayerStats
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// UnmarshalJSON accepts Roblox's empty-array encoding for an empty player table.
+func (p *PlayerInfo) UnmarshalJSON(data []byte) error {
+	if isEmptyJSONArray(bytes.TrimSpace(data)) {
+		*p = PlayerInfo{}
+		return nil
+	}
+	type plain PlayerInfo
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// Sync statuses reported in SyncResult.Status.
+const (
+	SyncStatusSynced    = "synced"
+	SyncStatusUnchanged = "unchanged" // content matched the stored inventory; only last seen was bumped
+	SyncStatusPatched   = "patched"
+)
+
+// SyncResult describes the outcome of an accepted inventory sync.
+type SyncResult struct {
+	Status        string   `json:"status"`
+	Size          int      `json:"size"`
+	ContentHash   string   `json:"content_hash,omitempty"`
+	UnknownFields []string `json:"unknown_fields,omitempty"`
+}
+
+// SyncStats counts syncs handled since startup and how many were unchanged.
+type SyncStats struct {
+	Total       int64   `json:"total"`
+	Unchanged   int64   `json:"unchanged"`
+	DedupeRatio float64 `json:"dedupe_ratio"` // unchanged / total
+}
@@ -0,0 +1,34 @@
+package model
+
+// Leaderboard metrics.
+const (
+	MetricCoins           = "coins"
+	MetricLevel           = "level"
+	MetricSecretFishCount = "secret_fish_count"
+	MetricInventoryValue  = "inventory_value"
+)
+
+// LeaderboardMetrics lists every ranked metric.
+var LeaderboardMetrics = []string{
+	MetricCoins,
+	MetricLevel,
+	MetricSecretFishCount,
+	MetricInventoryValue,
+}
+
+// IsLeaderboardMetric reports whether name is a ranked metric.
+func IsLeaderboardMetric(name string) bool {
+	for _, m := range LeaderboardMetrics {
+		if m == name {
+			return true
+		}
+	}
+	return false
+}
+
+// LeaderboardEntry is one user's position on a leaderboard. Rank starts at 1.
+type LeaderboardEntry struct {
+	Rank         int64   `json:"rank"`
+	RobloxUserID string  `json:"roblox_user_id"`
+	Score        float64 `json:"score"`
+}
@@ -20,3 +20,90 @@ type ObfuscationLog struct {
 	ExecutionTimeMs int64              `bson:"execution_time_ms" json:"execution_time_ms"`
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 }
+
+// Inventory event types, derived by comparing an accepted sync with the
+// inventory it replaced.
+const (
+	EventItemAcquired     = "item_acquired"
+	EventItemRemoved      = "item_removed"
+	EventFavoritedToggled = "favorited_toggled"
+	EventCoinsChanged     = "coins_changed"
+	EventLevelUp          = "level_up"
+	EventRareCatch        = "rare_catch" // an acquired item at or above the rare tier threshold
+)
+
+// IsInventoryEventType reports whether t is a known inventory event type.
+func IsInventoryEventType(t string) bool {
+	switch t {
+	case EventItemAcquired, EventItemRemoved, EventFavoritedToggled, EventCoinsChanged, EventLevelUp, EventRareCatch:
+		return true
+	}
+	return false
+}
+
+// InventoryEvent is one change in a user's inventory. Item events carry the
+// item fields; stat events carry Before and After.
+type InventoryEvent struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RobloxUserID string             `bson:"roblox_user_id" json:"roblox_user_id"`
+	Type         string             `bson:"type" json:"type"`
+	Category     string             `bson:"category,omitempty" json:"category,omitempty"`
+	UUID         string             `bson:"uuid,omitempty" json:"uuid,omitempty"`
+	ItemID       int64              `bson:"item_id,omitempty" json:"item_id,omitempty"`
+	Name         string             `bson:"name,omitempty" json:"name,omitempty"`
+	Tier         int                `bson:"tier,omitempty" json:"tier,omitempty"`
+	Quantity     float64            `bson:"quantity,omitempty" json:"quantity,omitempty"`
+	Favorited    *bool              `bson:"favorited,omitempty" json:"favorited,omitempty"`
+	Before       *float64           `bson:"before,omitempty" json:"before,omitempty"`
+	After        *float64           `bson:"after,omitempty" json:"after,omitempty"`
+	ContentHash  string             `bson:"content_hash" json:"content_hash"` // version that produced the event
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// InventoryEventQuery selects inventory events, newest first.
+// Zero values mean "any".
+type InventoryEventQuery struct {
+	RobloxUserID string
+	UUID         string
+	Types        []string
+	Tiers        TierRange
+	From         time.Time
+	To           time.Time
+	Cursor       string // opaque; from a previous page's next cursor
+	Limit        int
+}
+
+// ItemTransfer is an item that was removed from one player's inventory and
+// acquired by another's, matched by its uuid.
+type ItemTransfer struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UUID             string             `bson:"uuid" json:"uuid"`
+	ItemID           int64              `bson:"item_id,omitempty" json:"item_id,omitempty"`
+	Name             string             `bson:"name,omitempty" json:"name,omitempty"`
+	Category         string             `bson:"category,omitempty" json:"category,omitempty"`
+	Tier             int                `bson:"tier,omitempty" json:"tier,omitempty"`
+	Quantity         float64            `bson:"quantity,omitempty" json:"quantity,omitempty"`
+	FromRobloxUserID string             `bson:"from_roblox_user_id" json:"from"`
+	ToRobloxUserID   string             `bson:"to_roblox_user_id" json:"to"`
+	RemovedAt        time.Time          `bson:"removed_at" json:"removed_at"`
+	AcquiredAt       time.Time          `bson:"acquired_at" json:"acquired_at"`
+	RemovedEventID   primitive.ObjectID `bson:"removed_event_id" json:"removed_event_id"`
+	AcquiredEventID  primitive.ObjectID `bson:"acquired_event_id" json:"acquired_event_id"`
+	CreatedAt        time.Time          `bson:"created_at" json:"detected_at"`
+}
+
+// ItemTransferQuery selects item transfers, newest first.
+// Zero values mean "any".
+type ItemTransferQuery struct {
+	RobloxUserID string // sender or receiver
+	UUID         string
+	Cursor       string // opaque; from a previous page's next cursor
+	Limit        int
+}
+
+// ItemHistory is the provenance of one item, oldest first.
+type ItemHistory struct {
+	UUID      string           `json:"uuid"`
+	Transfers []ItemTransfer   `json:"transfers"`
+	Events    []InventoryEvent `json:"events"`
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Item sort keys accepted by CategoryQuery.
+const (
+	ItemSortTier     = "tier"     // tier number; unknown tiers sort as 0
+	ItemSortName     = "name"     // byte order; missing names sort as ""
+	ItemSortPrice    = "price"    // missing prices sort as 0
+	ItemSortQuantity = "quantity" // stack size; items without a quantity count as one
+)
+
+// IsItemSortKey reports whether key is a supported item sort key.
+func IsItemSortKey(key string) bool {
+	switch key {
+	case ItemSortTier, ItemSortName, ItemSortPrice, ItemSortQuantity:
+		return true
+	}
+	return false
+}
+
+// CategoryQuery selects the items of one category of a user's inventory.
+// Zero values mean "any". Items keep their stored order unless Sort is set;
+// ties are broken by stored order.
+type CategoryQuery struct {
+	Category  string
+	Tiers     TierRange
+	Favorited *bool
+	Shiny     *bool
+	Sort      string // one of the ItemSort keys, "" for stored order
+	Desc      bool
+	Limit     int
+	Offset    int
+}
+
+// Matches reports whether e passes the query's filters.
+func (q *CategoryQuery) Matches(e *InventoryEntry) bool {
+	if q.Tiers.Active() && !q.Tiers.Contains(e.Tier) {
+		return false
+	}
+	if q.Favorited != nil && e.Favorited != *q.Favorited {
+		return false
+	}
+	if q.Shiny != nil && e.IsShiny != *q.Shiny {
+		return false
+	}
+	return true
+}
+
+// SortTier returns the tier e sorts by: its tier if known, otherwise 0.
+func (e *InventoryEntry) SortTier() Tier {
+	if e.Tier < 1 || e.Tier > MaxTier {
+		return 0
+	}
+	return e.Tier
+}
+
+// CategoryItems is a page of one category's items.
+type CategoryItems struct {
+	Items       []json.RawMessage // stored item objects, as stored
+	Total       int64             // items matching the filters
+	ContentHash string            // hash of the whole inventory
+	SyncedAt    time.Time
+}
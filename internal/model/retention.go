@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// RetentionReport describes one cleanup run: how many inactive inventories
+// were looked at and which ones were (or, in a dry run, would be) purged.
+type RetentionReport struct {
+	Game        string               `json:"game,omitempty"`
+	DryRun      bool                 `json:"dry_run"`
+	StartedAt   time.Time            `json:"started_at"`
+	Evaluated   int                  `json:"evaluated"` // inactive past the shortest threshold
+	Kept        int                  `json:"kept"`      // inactive, but within their own threshold
+	Pinned      int                  `json:"pinned"`
+	Purged      int                  `json:"purged"`
+	ArchiveFile string               `json:"archive_file,omitempty"`
+	Candidates  []RetentionCandidate `json:"candidates"`
+}
+
+// RetentionCandidate is an inventory selected for purging.
+type RetentionCandidate struct {
+	RobloxUserID string    `json:"roblox_user_id"`
+	KeyAccountID int64     `json:"key_account_id"`
+	KeyTier      string    `json:"key_tier,omitempty"`
+	Value        float64   `json:"value,omitempty"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	Threshold    string    `json:"threshold"` // e.g. "720h0m0s"
+	Rule         string    `json:"rule"`      // which setting chose the threshold
+	Purged       bool      `json:"purged"`    // false in dry runs and when the user came back meanwhile
+}
+
+// RestoreResult reports the outcome of restoring a soft-deleted inventory.
+type RestoreResult struct {
+	RobloxUserID string     `json:"roblox_user_id"`
+	Restored     bool       `json:"restored"`
+	KeyAccountID int64      `json:"key_account_id"`
+	ContentHash  string     `json:"content_hash"`
+	SyncedAt     time.Time  `json:"synced_at"` // of the tombstoned copy
+	DeletedAt    time.Time  `json:"deleted_at"`
+	LiveSyncedAt *time.Time `json:"live_synced_at,omitempty"` // the newer live inventory that was kept instead
+}
+
+// LastActiveAt returns when the inventory was last synced, changed or not.
+func (inv *RawInventory) LastActiveAt() time.Time {
+	if inv.LastSeenAt != nil && inv.LastSeenAt.After(inv.SyncedAt) {
+		return *inv.LastSeenAt
+	}
+	return inv.SyncedAt
+}
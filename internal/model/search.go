@@ -0,0 +1,106 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CategoryIDFields maps each inventory category to its category-specific item ID field.
+var CategoryIDFields = map[string]string{
+	CategoryFish:     "fish_id",
+	CategoryRods:     "rod_id",
+	CategoryBaits:    "bait_id",
+	CategoryPotions:  "potion_id",
+	CategoryStones:   "stone_id",
+	CategoryGears:    "gear_id",
+	CategoryTrophies: "trophy_id",
+	CategoryBoats:    "boat_id",
+	CategoryTotems:   "totem_id",
+	CategoryLanterns: "lantern_id",
+}
+
+// ItemSearchQuery selects inventory items across all users.
+// Zero values mean "any".
+type ItemSearchQuery struct {
+	ItemID    int64
+	Category  string // restrict to one category
+	MinTier   Tier
+	MaxTier   Tier
+	Shiny     *bool
+	VariantID string
+	Limit     int
+	Offset    int
+}
+
+// Categories returns the categories the query covers.
+func (q *ItemSearchQuery) Categories() []string {
+	if q.Category != "" {
+		return []string{q.Category}
+	}
+	return InventoryCategories
+}
+
+// HasTierFilter reports whether the query restricts tiers.
+func (q *ItemSearchQuery) HasTierFilter() bool {
+	return TierRange{q.MinTier, q.MaxTier}.Active()
+}
+
+// TierValues returns every stored representation of the tiers the query accepts.
+func (q *ItemSearchQuery) TierValues() (numbers []int, names []string) {
+	return TierRange{q.MinTier, q.MaxTier}.Values()
+}
+
+// TierRange is an inclusive tier range; zero bounds are open.
+type TierRange struct {
+	Min Tier
+	Max Tier
+}
+
+// Active reports whether the range excludes any tier.
+func (r TierRange) Active() bool {
+	return r.Min > 0 || (r.Max > 0 && r.Max < MaxTier)
+}
+
+// Bounds returns the range clamped to the known tiers.
+func (r TierRange) Bounds() (lo, hi Tier) {
+	lo, hi = r.Min, r.Max
+	if lo < 1 {
+		lo = 1
+	}
+	if hi < 1 || hi > MaxTier {
+		hi = MaxTier
+	}
+	return lo, hi
+}
+
+// Contains reports whether t lies in the range.
+func (r TierRange) Contains(t Tier) bool {
+	lo, hi := r.Bounds()
+	return t >= lo && t <= hi
+}
+
+// Values returns every stored representation of the tiers in the range.
+// Payloads carry tiers as numbers or names, so backends match stored
+// values against this list instead of comparing numerically.
+func (r TierRange) Values() (numbers []int, names []string) {
+	lo, hi := r.Bounds()
+	for t := lo; t <= hi; t++ {
+		numbers = append(numbers, int(t))
+		names = append(names, strconv.Itoa(int(t)))
+	}
+	for name, t := range TierNames {
+		if t >= lo && t <= hi {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return numbers, names
+}
+
+// ItemOwnership is one user's holdings of the items matched by a search.
+type ItemOwnership struct {
+	RobloxUserID string    `json:"roblox_user_id"`
+	ItemCount    float64   `json:"item_count"` // sum of stack sizes
+	SyncedAt     time.Time `json:"synced_at"`
+}
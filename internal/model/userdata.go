@@ -0,0 +1,78 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// UserDataExport bundles everything stored about a Roblox user, for data requests.
+type UserDataExport struct {
+	RobloxUserID    string           `json:"roblox_user_id"`
+	ExportedAt      time.Time        `json:"exported_at"`
+	Games           []UserGameData   `json:"games"`
+	Sessions        []TokenSession   `json:"sessions"`
+	KeyAccounts     []KeyAccount     `json:"key_accounts"`
+	ObfuscationLogs []ObfuscationLog `json:"obfuscation_logs"`
+	Unavailable     []string         `json:"unavailable,omitempty"` // sources that could not be read
+}
+
+// UserGameData is a user's stored and buffered inventory in one game.
+type UserGameData struct {
+	Game      string             `json:"game"`
+	Inventory *ExportedInventory `json:"inventory"`
+	Buffered  *ExportedInventory `json:"buffered,omitempty"` // sync not yet written to the database
+}
+
+// ExportedInventory is an inventory with its JSON inlined.
+type ExportedInventory struct {
+	KeyAccountID int64           `json:"key_account_id"`
+	ContentHash  string          `json:"content_hash"`
+	SyncedAt     time.Time       `json:"synced_at"`
+	LastSeenAt   *time.Time      `json:"last_seen_at,omitempty"`
+	Inventory    json.RawMessage `json:"inventory"`
+}
+
+// KeyAccount links a Roblox user to a license key.
+type KeyAccount struct {
+	ID             int64      `json:"id"`
+	KeyID          int64      `json:"key_id"`
+	RobloxUserID   string     `json:"roblox_user_id"`
+	RobloxUsername string     `json:"roblox_username,omitempty"`
+	HWID           string     `json:"hwid,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	FirstUsedAt    *time.Time `json:"first_used_at,omitempty"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+// TokenSession describes a session token without revealing it.
+type TokenSession struct {
+	TokenHint string `json:"token_hint"` // the token's first characters
+	TokenData
+}
+
+// UserErasure reports what was deleted for a user. Complete is false if any
+// source failed; Errors says which, and the erasure can be repeated.
+type UserErasure struct {
+	RobloxUserID string            `json:"roblox_user_id"`
+	ErasedAt     time.Time         `json:"erased_at"`
+	RequestedBy  string            `json:"requested_by"`
+	Games        []UserGameErasure `json:"games"`
+	Sessions     int               `json:"sessions_revoked"`
+	UserLogErasure
+	Complete bool     `json:"complete"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// UserLogErasure counts the log entries deleted for a user.
+type UserLogErasure struct {
+	ObfuscationLogs int64 `json:"obfuscation_logs_deleted"`
+	InventoryEvents int64 `json:"inventory_events_deleted"`
+	ItemTransfers   int64 `json:"item_transfers_deleted"`
+}
+
+// UserGameErasure reports what was deleted for a user in one game.
+type UserGameErasure struct {
+	Game     string `json:"game"`
+	Records  int64  `json:"records_deleted"`  // inventory, tombstones, history and value points
+	Buffered bool   `json:"buffered_deleted"` // buffered or quarantined sync
+}
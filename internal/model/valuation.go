@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Price sources for a valued item.
+const (
+	PriceSourceCatalog = "catalog" // server-side catalog sell price
+	PriceSourcePayload = "payload" // price reported by the client
+)
+
+// ValuedItem is an inventory item with its computed value.
+type ValuedItem struct {
+	Category    string  `json:"category"`
+	UUID        string  `json:"uuid,omitempty"`
+	ItemID      int64   `json:"item_id"`
+	Name        string  `json:"name,omitempty"`
+	Tier        Tier    `json:"tier,omitempty"`
+	Quantity    float64 `json:"quantity"`
+	IsShiny     bool    `json:"is_shiny,omitempty"`
+	VariantID   string  `json:"variant_id,omitempty"`
+	UnitPrice   float64 `json:"unit_price"` // after shiny/variant multipliers
+	Value       float64 `json:"value"`
+	PriceSource string  `json:"price_source"`
+}
+
+// InventoryValuation is the value of a user's inventory.
+type InventoryValuation struct {
+	RobloxUserID  string             `json:"roblox_user_id"`
+	Total         float64            `json:"total"`
+	ByCategory    map[string]float64 `json:"by_category"`
+	ByTier        map[Tier]float64   `json:"by_tier"`
+	TopItems      []ValuedItem       `json:"top_items"`
+	ItemCount     int                `json:"item_count"`
+	UnpricedItems int                `json:"unpriced_items"` // items with no catalog or client price
+	ComputedAt    time.Time          `json:"computed_at"`
+}
+
+// ValuePoint is a user's inventory value at a point in time.
+type ValuePoint struct {
+	RobloxUserID string    `json:"roblox_user_id"`
+	Total        float64   `json:"total"`
+	ItemCount    int       `json:"item_count"`
+	RecordedAt   time.Time `json:"recorded_at"`
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// screenBatch splits items into those worth writing and a result holding the
+// ones no backend could store, so a bad item fails alone instead of taking
+// its batch down with it.
+func screenBatch(items []model.InventoryItem) ([]model.InventoryItem, *model.BatchResult) {
+	result := &model.BatchResult{}
+	valid := make([]model.InventoryItem, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item.RobloxUserID == "":
+			result.Failed = append(result.Failed, model.BatchFailure{Reason: "missing roblox user id"})
+		case !json.Valid(item.RawJSON):
+			result.Failed = append(result.Failed, model.BatchFailure{RobloxUserID: item.RobloxUserID, Reason: "invalid JSON"})
+		default:
+			valid = append(valid, item)
+		}
+	}
+	return valid, result
+}
+
+// succeeded records items as written.
+func succeeded(result *model.BatchResult, items []model.InventoryItem) {
+	for _, item := range items {
+		result.Succeeded = append(result.Succeeded, item.RobloxUserID)
+	}
+}
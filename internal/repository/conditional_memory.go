@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// ReplaceInventoryIfHash replaces the user's inventory only if its content
+// hash is ifHash.
+func (r *MemoryInventoryRepository) ReplaceInventoryIfHash(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, ifHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.inventories[robloxUserID]
+	if !ok || inv.ContentHash != ifHash {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	r.upsert(keyAccountID, robloxUserID, rawJSON, contentHash("", rawJSON), now, now)
+	return true, nil
+}
+
+// Ensure MemoryInventoryRepository implements InventoryConditionalRepository
+var _ InventoryConditionalRepository = (*MemoryInventoryRepository)(nil)
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReplaceInventoryIfHash replaces the user's inventory only if its content
+// hash is ifHash, matched in the update filter. Documents written before
+// hashes were stored are hashed here and matched by their sync time instead.
+func (r *MongoDBInventoryRepository) ReplaceInventoryIfHash(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, ifHash string) (bool, error) {
+	inventoryData, err := jsonToBSON(rawJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse inventory JSON: %w", err)
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"key_account_id": keyAccountID,
+			"inventory_json": inventoryData,
+			"content_hash":   contentHash("", rawJSON),
+			"synced_at":      now,
+			"last_seen_at":   now,
+		},
+	}
+	if err := r.setOriginalJSON(update, rawJSON); err != nil {
+		return false, err
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"roblox_user_id": robloxUserID, "content_hash": ifHash}, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace inventory: %w", err)
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	var doc InventoryDocument
+	err = r.collection.FindOne(ctx, bson.M{"roblox_user_id": robloxUserID, "content_hash": bson.M{"$exists": false}}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get inventory: %w", err)
+	}
+	jsonBytes, err := doc.rawJSON()
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal inventory to JSON: %w", err)
+	}
+	if contentHash("", jsonBytes) != ifHash {
+		return false, nil
+	}
+
+	// Any write since the read stores a hash, so this matches only if there was none
+	legacy := bson.M{"roblox_user_id": robloxUserID, "content_hash": bson.M{"$exists": false}, "synced_at": doc.SyncedAt}
+	result, err = r.collection.UpdateOne(ctx, legacy, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace inventory: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryConditionalRepository
+var _ InventoryConditionalRepository = (*MongoDBInventoryRepository)(nil)
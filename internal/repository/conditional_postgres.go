@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ReplaceInventoryIfHash replaces the user's inventory only if its content
+// hash is ifHash. The row is locked between the check and the update; rows
+// written before hashes were stored are hashed here.
+func (r *PostgresInventoryRepository) ReplaceInventoryIfHash(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, ifHash string) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var storedJSON []byte
+	var storedHash string
+	err = tx.QueryRowContext(ctx,
+		`SELECT inventory_json, content_hash FROM fishit_inventory_raw WHERE roblox_user_id = $1 FOR UPDATE`, robloxUserID).
+		Scan(&storedJSON, &storedHash)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get content hash: %w", err)
+	}
+	if contentHash(storedHash, storedJSON) != ifHash {
+		return false, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE fishit_inventory_raw SET
+			key_account_id = $1,
+			inventory_json = $2,
+			content_hash = $3,
+			synced_at = NOW(),
+			last_seen_at = NOW()
+		WHERE roblox_user_id = $4`,
+		keyAccountID, rawJSON, contentHash("", rawJSON), robloxUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace inventory: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// Ensure PostgresInventoryRepository implements InventoryConditionalRepository
+var _ InventoryConditionalRepository = (*PostgresInventoryRepository)(nil)
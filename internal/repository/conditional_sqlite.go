@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ReplaceInventoryIfHash replaces the user's inventory only if its content
+// hash is ifHash. Holding mu keeps other writes out between the check and the
+// update; rows written before hashes were stored are hashed here.
+func (r *SQLiteInventoryRepository) ReplaceInventoryIfHash(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, ifHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var storedJSON, storedHash string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT inventory_json, content_hash FROM fishit_inventory_raw WHERE roblox_user_id = ?`, robloxUserID).
+		Scan(&storedJSON, &storedHash)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get content hash: %w", err)
+	}
+	if contentHash(storedHash, []byte(storedJSON)) != ifHash {
+		return false, nil
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE fishit_inventory_raw SET
+			key_account_id = ?1,
+			inventory_json = ?2,
+			content_hash = ?3,
+			synced_at = ?4,
+			last_seen_at = ?4
+		WHERE roblox_user_id = ?5`,
+		keyAccountID, string(rawJSON), contentHash("", rawJSON), time.Now().UTC(), robloxUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to replace inventory: %w", err)
+	}
+	return true, nil
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryConditionalRepository
+var _ InventoryConditionalRepository = (*SQLiteInventoryRepository)(nil)
@@ -0,0 +1,247 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/internal/repository/repotest"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Backends that need a server run only when given one, in a scratch schema
+// or database that is dropped afterwards.
+const (
+	postgresDSNEnv = "INVENTORY_TEST_POSTGRES_DSN" // e.g. postgres://postgres@localhost:5432/vinzhub_test?sslmode=disable
+	mongoURIEnv    = "INVENTORY_TEST_MONGODB_URI"  // e.g. mongodb://localhost:27017
+)
+
+var scratchSeq atomic.Int64
+
+// scratchName returns a name no other test run uses.
+func scratchName() string {
+	return fmt.Sprintf("conformance_%d_%d", time.Now().Unix(), scratchSeq.Add(1))
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	paths := make(map[repository.InventoryRepository]string)
+	repotest.TestInventoryRepository(t, repotest.Backend{
+		Open: func(t *testing.T) repository.InventoryRepository {
+			path := filepath.Join(t.TempDir(), "inventory.db")
+			repo, err := repository.NewSQLiteInventoryRepository(path)
+			if err != nil {
+				t.Fatalf("NewSQLiteInventoryRepository: %v", err)
+			}
+			paths[repo] = path
+			return repo
+		},
+		ExactJSON: true,
+		RejectUsers: func(t *testing.T, repo repository.InventoryRepository, robloxUserIDs ...string) {
+			db, err := sql.Open("sqlite", paths[repo])
+			if err != nil {
+				t.Fatalf("open %s: %v", paths[repo], err)
+			}
+			defer db.Close()
+			ids := make([]string, len(robloxUserIDs))
+			for i, id := range robloxUserIDs {
+				ids[i] = pq.QuoteLiteral(id)
+			}
+			for _, event := range []string{"INSERT", "UPDATE"} {
+				trigger := fmt.Sprintf(`CREATE TRIGGER reject_%s BEFORE %s ON fishit_inventory_raw
+					WHEN NEW.roblox_user_id IN (%s)
+					BEGIN SELECT RAISE(ABORT, 'rejected by test'); END`, strings.ToLower(event), event, strings.Join(ids, ", "))
+				if _, err := db.Exec(trigger); err != nil {
+					t.Fatalf("create trigger: %v", err)
+				}
+			}
+		},
+	})
+}
+
+func TestMemoryConformance(t *testing.T) {
+	repotest.TestInventoryRepository(t, repotest.Backend{
+		Open: func(t *testing.T) repository.InventoryRepository {
+			repo, err := repository.NewMemoryInventoryRepository("", 0)
+			if err != nil {
+				t.Fatalf("NewMemoryInventoryRepository: %v", err)
+			}
+			return repo
+		},
+		ExactJSON: true,
+	})
+}
+
+// TestMemorySnapshotConformance runs the suite against repositories reloaded
+// from a snapshot of the one written to.
+func TestMemorySnapshotConformance(t *testing.T) {
+	repotest.TestInventoryRepository(t, repotest.Backend{
+		Open: func(t *testing.T) repository.InventoryRepository {
+			path := filepath.Join(t.TempDir(), "inventory.snapshot")
+			repo, err := repository.NewMemoryInventoryRepository(path, time.Millisecond)
+			if err != nil {
+				t.Fatalf("NewMemoryInventoryRepository: %v", err)
+			}
+			return &reloadingRepository{MemoryInventoryRepository: repo, path: path, t: t}
+		},
+		ExactJSON: true,
+	})
+}
+
+// reloadingRepository closes and reloads the memory repository before every
+// GetInventory, so those reads see what a restart would.
+type reloadingRepository struct {
+	*repository.MemoryInventoryRepository
+	path string
+	t    *testing.T
+}
+
+func (r *reloadingRepository) reload() {
+	if err := r.MemoryInventoryRepository.Close(); err != nil {
+		r.t.Fatalf("Close: %v", err)
+	}
+	repo, err := repository.NewMemoryInventoryRepository(r.path, time.Millisecond)
+	if err != nil {
+		r.t.Fatalf("NewMemoryInventoryRepository: %v", err)
+	}
+	r.MemoryInventoryRepository = repo
+}
+
+func (r *reloadingRepository) GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error) {
+	r.reload()
+	return r.MemoryInventoryRepository.GetInventory(ctx, robloxUserID)
+}
+
+func TestPostgresConformance(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+
+	schemas := make(map[repository.InventoryRepository]string)
+	repotest.TestInventoryRepository(t, repotest.Backend{
+		Open: func(t *testing.T) repository.InventoryRepository {
+			schema := scratchName()
+			t.Cleanup(func() { dropPostgresSchema(t, dsn, schema) })
+
+			sep := " "
+			if strings.Contains(dsn, "://") {
+				sep = "?"
+				if strings.Contains(dsn, "?") {
+					sep = "&"
+				}
+			}
+			repo, err := repository.NewPostgresInventoryRepository(dsn+sep+"search_path="+schema, schema)
+			if err != nil {
+				t.Fatalf("NewPostgresInventoryRepository: %v", err)
+			}
+			schemas[repo] = schema
+			return repo
+		},
+		RejectUsers: func(t *testing.T, repo repository.InventoryRepository, robloxUserIDs ...string) {
+			db, err := sql.Open("postgres", dsn)
+			if err != nil {
+				t.Fatalf("open %s: %v", dsn, err)
+			}
+			defer db.Close()
+			ids := make([]string, len(robloxUserIDs))
+			for i, id := range robloxUserIDs {
+				ids[i] = pq.QuoteLiteral(id)
+			}
+			// NOT VALID: rows already stored are kept, writes are checked
+			constraint := fmt.Sprintf("ALTER TABLE %s.fishit_inventory_raw ADD CONSTRAINT reject_users CHECK (roblox_user_id NOT IN (%s)) NOT VALID",
+				pq.QuoteIdentifier(schemas[repo]), strings.Join(ids, ", "))
+			if _, err := db.Exec(constraint); err != nil {
+				t.Fatalf("add constraint: %v", err)
+			}
+		},
+	})
+}
+
+func dropPostgresSchema(t *testing.T, dsn, schema string) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Errorf("drop schema %s: %v", schema, err)
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec("DROP SCHEMA IF EXISTS " + pq.QuoteIdentifier(schema) + " CASCADE"); err != nil {
+		t.Errorf("drop schema %s: %v", schema, err)
+	}
+}
+
+func TestMongoDBConformance(t *testing.T) {
+	testMongoDB(t, false)
+}
+
+// TestMongoDBOriginalJSONConformance checks that MongoDB returns JSON byte
+// for byte when it keeps the original.
+func TestMongoDBOriginalJSONConformance(t *testing.T) {
+	testMongoDB(t, true)
+}
+
+func testMongoDB(t *testing.T, keepOriginal bool) {
+	uri := os.Getenv(mongoURIEnv)
+	if uri == "" {
+		t.Skipf("%s not set", mongoURIEnv)
+	}
+
+	databases := make(map[repository.InventoryRepository]string)
+	repotest.TestInventoryRepository(t, repotest.Backend{
+		Open: func(t *testing.T) repository.InventoryRepository {
+			database := scratchName()
+			t.Cleanup(func() { dropMongoDatabase(t, uri, database) })
+
+			repo, err := repository.NewMongoDBInventoryRepository(uri, database, "fishit_inventory")
+			if err != nil {
+				t.Fatalf("NewMongoDBInventoryRepository: %v", err)
+			}
+			repo.SetKeepOriginalJSON(keepOriginal)
+			databases[repo] = database
+			return repo
+		},
+		ExactJSON: keepOriginal,
+		RejectUsers: func(t *testing.T, repo repository.InventoryRepository, robloxUserIDs ...string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+			if err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+			defer client.Disconnect(ctx)
+			// Documents failing the validator are refused on insert and update
+			err = client.Database(databases[repo]).RunCommand(ctx, bson.D{
+				{Key: "collMod", Value: "fishit_inventory"},
+				{Key: "validator", Value: bson.M{"roblox_user_id": bson.M{"$nin": robloxUserIDs}}},
+			}).Err()
+			if err != nil {
+				t.Fatalf("collMod: %v", err)
+			}
+		},
+	})
+}
+
+func dropMongoDatabase(t *testing.T, uri, database string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Errorf("drop database %s: %v", database, err)
+		return
+	}
+	defer client.Disconnect(ctx)
+	if err := client.Database(database).Drop(ctx); err != nil {
+		t.Errorf("drop database %s: %v", database, err)
+	}
+}
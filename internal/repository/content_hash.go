@@ -0,0 +1,14 @@
+package repository
+
+import "vinzhub-rest-api-v2/pkg/canonjson"
+
+// contentHash returns hash if the caller already computed it, otherwise the
+// canonical hash of rawJSON. Stored hashes let reads answer conditional
+// requests without re-hashing, and survive backends that re-encode the JSON.
+func contentHash(hash string, rawJSON []byte) string {
+	if hash != "" {
+		return hash
+	}
+	hash, _ = canonjson.Hash(rawJSON)
+	return hash
+}
@@ -0,0 +1,35 @@
+package repository
+
+import "context"
+
+// EraseUser deletes the user's inventory, tombstones, history and value points.
+func (r *MemoryInventoryRepository) EraseUser(ctx context.Context, robloxUserID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var erased int64
+	if _, ok := r.inventories[robloxUserID]; ok {
+		delete(r.inventories, robloxUserID)
+		erased++
+	}
+	kept := r.tombstones[:0]
+	for _, tomb := range r.tombstones {
+		if tomb.Inventory.RobloxUserID == robloxUserID {
+			erased++
+			continue
+		}
+		kept = append(kept, tomb)
+	}
+	r.tombstones = kept
+	erased += int64(len(r.history[robloxUserID]) + len(r.values[robloxUserID]))
+	delete(r.history, robloxUserID)
+	delete(r.values, robloxUserID)
+
+	if erased > 0 {
+		r.changes++
+	}
+	return erased, nil
+}
+
+// Ensure MemoryInventoryRepository implements InventoryEraser
+var _ InventoryEraser = (*MemoryInventoryRepository)(nil)
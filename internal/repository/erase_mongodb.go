@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EraseUser deletes the user's documents from the inventory collection and
+// its history, value and tombstone collections.
+func (r *MongoDBInventoryRepository) EraseUser(ctx context.Context, robloxUserID string) (int64, error) {
+	filter := bson.M{"roblox_user_id": robloxUserID}
+
+	var erased int64
+	for _, coll := range []*mongo.Collection{r.collection, r.tombstones, r.history, r.values} {
+		result, err := coll.DeleteMany(ctx, filter)
+		if err != nil {
+			return erased, fmt.Errorf("failed to erase %s: %w", coll.Name(), err)
+		}
+		erased += result.DeletedCount
+	}
+	return erased, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryEraser
+var _ InventoryEraser = (*MongoDBInventoryRepository)(nil)
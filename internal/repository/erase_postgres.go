@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// EraseUser deletes the user's rows from every inventory table in one transaction.
+func (r *PostgresInventoryRepository) EraseUser(ctx context.Context, robloxUserID string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var erased int64
+	for _, table := range userTables {
+		result, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE roblox_user_id = $1", robloxUserID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to erase %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		erased += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return erased, nil
+}
+
+// Ensure PostgresInventoryRepository implements InventoryEraser
+var _ InventoryEraser = (*PostgresInventoryRepository)(nil)
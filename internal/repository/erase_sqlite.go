@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// userTables are the tables holding a user's rows, for EraseUser.
+var userTables = []string{
+	"fishit_inventory_raw",
+	"fishit_inventory_tombstones",
+	"fishit_inventory_history",
+	"fishit_inventory_value",
+}
+
+// EraseUser deletes the user's rows from every inventory table in one transaction.
+func (r *SQLiteInventoryRepository) EraseUser(ctx context.Context, robloxUserID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var erased int64
+	for _, table := range userTables {
+		result, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE roblox_user_id = ?", robloxUserID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to erase %s: %w", table, err)
+		}
+		n, _ := result.RowsAffected()
+		erased += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return erased, nil
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryEraser
+var _ InventoryEraser = (*SQLiteInventoryRepository)(nil)
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureInventoryEventIndexes creates the indexes used by ListInventoryEvents.
+// A positive retention also expires events older than it.
+func (r *MongoDBLogRepository) EnsureInventoryEventIndexes(ctx context.Context, retention time.Duration) error {
+	createdAt := options.Index().SetName("created_at")
+	if retention > 0 {
+		createdAt.SetExpireAfterSeconds(int32(retention / time.Second))
+	}
+	_, err := r.events.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "roblox_user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "uuid", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: createdAt},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create inventory event indexes: %w", err)
+	}
+	return nil
+}
+
+// InsertInventoryEvents stores events, assigning IDs to those without one.
+func (r *MongoDBLogRepository) InsertInventoryEvents(ctx context.Context, events []model.InventoryEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		if events[i].ID.IsZero() {
+			events[i].ID = primitive.NewObjectID()
+		}
+		docs[i] = events[i]
+	}
+	if _, err := r.events.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to insert inventory events: %w", err)
+	}
+	return nil
+}
+
+// ListInventoryEvents returns events matching q ordered by (created_at, _id)
+// descending. The cursor encodes the position of the last returned event.
+func (r *MongoDBLogRepository) ListInventoryEvents(ctx context.Context, q model.InventoryEventQuery) ([]model.InventoryEvent, string, error) {
+	filter := bson.D{}
+	if q.RobloxUserID != "" {
+		filter = append(filter, bson.E{Key: "roblox_user_id", Value: q.RobloxUserID})
+	}
+	if q.UUID != "" {
+		filter = append(filter, bson.E{Key: "uuid", Value: q.UUID})
+	}
+	if len(q.Types) > 0 {
+		filter = append(filter, bson.E{Key: "type", Value: bson.D{{Key: "$in", Value: q.Types}}})
+	}
+	if q.Tiers.Active() {
+		lo, hi := q.Tiers.Bounds()
+		filter = append(filter, bson.E{Key: "tier", Value: bson.D{{Key: "$gte", Value: int(lo)}, {Key: "$lte", Value: int(hi)}}})
+	}
+	createdAt := bson.D{}
+	if !q.From.IsZero() {
+		createdAt = append(createdAt, bson.E{Key: "$gte", Value: q.From})
+	}
+	if !q.To.IsZero() {
+		createdAt = append(createdAt, bson.E{Key: "$lte", Value: q.To})
+	}
+	if len(createdAt) > 0 {
+		filter = append(filter, bson.E{Key: "created_at", Value: createdAt})
+	}
+	if q.Cursor != "" {
+		after, err := afterEventCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter = append(filter, after)
+	}
+
+	// One extra event tells whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(q.Limit + 1))
+	cursor, err := r.events.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list inventory events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	events := []model.InventoryEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, "", fmt.Errorf("failed to decode inventory events: %w", err)
+	}
+
+	next := ""
+	if len(events) > q.Limit {
+		events = events[:q.Limit]
+		last := events[len(events)-1]
+		next = encodeEventCursor(last.CreatedAt, last.ID)
+	}
+	return events, next, nil
+}
+
+// afterEventCursor returns the filter for the documents that come after the
+// cursor in (created_at, _id) descending order.
+func afterEventCursor(cursor string) (bson.E, error) {
+	at, id, err := decodeEventCursor(cursor)
+	if err != nil {
+		return bson.E{}, err
+	}
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "created_at", Value: bson.D{{Key: "$lt", Value: at}}}},
+		bson.D{{Key: "created_at", Value: at}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: id}}}},
+	}}, nil
+}
+
+// encodeEventCursor encodes an event position as "<unix millis>:<object id>".
+func encodeEventCursor(at time.Time, id primitive.ObjectID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(at.UnixMilli(), 10) + ":" + id.Hex()))
+}
+
+func decodeEventCursor(cursor string) (time.Time, primitive.ObjectID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+	millis, hex, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+	return time.UnixMilli(ms).UTC(), id, nil
+}
+
+// Ensure MongoDBLogRepository implements InventoryEventLog
+var _ InventoryEventLog = (*MongoDBLogRepository)(nil)
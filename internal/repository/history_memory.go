@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// AppendSnapshots stores new inventory versions, skipping unchanged content.
+func (r *MemoryInventoryRepository) AppendSnapshots(ctx context.Context, snapshots []model.InventorySnapshot) (int, error) {
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	appended := 0
+	for _, snap := range snapshots {
+		versions := r.history[snap.RobloxUserID]
+		var version int64 = 1
+		if n := len(versions); n > 0 {
+			if versions[n-1].ContentHash == snap.ContentHash {
+				continue
+			}
+			version = versions[n-1].Version + 1
+		}
+		r.history[snap.RobloxUserID] = append(versions, model.InventorySnapshot{
+			RobloxUserID:  snap.RobloxUserID,
+			Version:       version,
+			KeyAccountID:  snap.KeyAccountID,
+			ContentHash:   snap.ContentHash,
+			InventoryJSON: bytes.Clone(snap.InventoryJSON),
+			Size:          len(snap.InventoryJSON),
+			CapturedAt:    snap.CapturedAt.UTC(),
+		})
+		appended++
+	}
+	if appended > 0 {
+		r.changes++
+	}
+	return appended, nil
+}
+
+// ListSnapshots returns snapshot metadata for a user, newest first.
+func (r *MemoryInventoryRepository) ListSnapshots(ctx context.Context, robloxUserID string, limit, offset int) ([]model.InventorySnapshot, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.history[robloxUserID]
+	snapshots := []model.InventorySnapshot{}
+	for i := len(versions) - 1 - offset; i >= 0 && len(snapshots) < limit; i-- {
+		snap := versions[i]
+		snap.InventoryJSON = nil
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, int64(len(versions)), nil
+}
+
+// GetSnapshot returns a specific inventory version.
+func (r *MemoryInventoryRepository) GetSnapshot(ctx context.Context, robloxUserID string, version int64) (*model.InventorySnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, snap := range r.history[robloxUserID] {
+		if snap.Version == version {
+			return copySnapshot(snap), nil
+		}
+	}
+	return nil, nil
+}
+
+// GetSnapshotAt returns the latest version captured at or before t.
+func (r *MemoryInventoryRepository) GetSnapshotAt(ctx context.Context, robloxUserID string, t time.Time) (*model.InventorySnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := r.history[robloxUserID]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].CapturedAt.After(t) {
+			return copySnapshot(versions[i]), nil
+		}
+	}
+	return nil, nil
+}
+
+// PruneSnapshots deletes versions older than retention except each user's latest two.
+func (r *MemoryInventoryRepository) PruneSnapshots(ctx context.Context, retention time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-retention)
+	var deleted int64
+	for id, versions := range r.history {
+		latest := versions[len(versions)-1].Version
+		kept := versions[:0]
+		for _, snap := range versions {
+			if snap.CapturedAt.Before(cutoffTime) && snap.Version < latest-1 {
+				deleted++
+				continue
+			}
+			kept = append(kept, snap)
+		}
+		r.history[id] = kept
+	}
+
+	if deleted > 0 {
+		r.changes++
+		log.Printf("[Memory] Pruned %d inventory snapshots (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+func copySnapshot(snap model.InventorySnapshot) *model.InventorySnapshot {
+	snap.InventoryJSON = bytes.Clone(snap.InventoryJSON)
+	return &snap
+}
+
+// Ensure MemoryInventoryRepository implements InventoryHistoryRepository
+var _ InventoryHistoryRepository = (*MemoryInventoryRepository)(nil)
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SnapshotDocument represents an inventory version in the history collection.
+type SnapshotDocument struct {
+	RobloxUserID  string      `bson:"roblox_user_id"`
+	Version       int64       `bson:"version"`
+	KeyAccountID  int64       `bson:"key_account_id,omitempty"`
+	ContentHash   string      `bson:"content_hash"`
+	InventoryJSON interface{} `bson:"inventory_json,omitempty"`
+	Size          int         `bson:"size"`
+	CapturedAt    time.Time   `bson:"captured_at"`
+	// The JSON as synced, gzipped, when the repository keeps it
+	InventoryOriginal []byte `bson:"inventory_original,omitempty"`
+}
+
+// toModel converts the document to a snapshot, with the kept original content
+// or the BSON content re-encoded as JSON.
+func (d *SnapshotDocument) toModel() (*model.InventorySnapshot, error) {
+	snap := &model.InventorySnapshot{
+		RobloxUserID: d.RobloxUserID,
+		Version:      d.Version,
+		KeyAccountID: d.KeyAccountID,
+		ContentHash:  d.ContentHash,
+		Size:         d.Size,
+		CapturedAt:   d.CapturedAt,
+	}
+	switch {
+	case d.InventoryOriginal != nil:
+		jsonBytes, err := gunzipJSON(d.InventoryOriginal)
+		if err != nil {
+			return nil, err
+		}
+		snap.InventoryJSON = jsonBytes
+	case d.InventoryJSON != nil:
+		jsonBytes, err := bsonToJSON(d.InventoryJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal snapshot to JSON: %w", err)
+		}
+		snap.InventoryJSON = jsonBytes
+	}
+	return snap, nil
+}
+
+// snapshotAppendAttempts bounds how often a snapshot is retried after a
+// concurrent writer took its version.
+const snapshotAppendAttempts = 5
+
+// AppendSnapshots stores new inventory versions, skipping unchanged content.
+// Concurrent writers for the same user are caught by the unique
+// (roblox_user_id, version) index rather than silently overwriting; the loser
+// reads the new latest version and tries again.
+func (r *MongoDBInventoryRepository) AppendSnapshots(ctx context.Context, snapshots []model.InventorySnapshot) (int, error) {
+	appended := 0
+	for _, snap := range snapshots {
+		inventoryData, err := jsonToBSON(snap.InventoryJSON)
+		if err != nil {
+			return appended, fmt.Errorf("failed to parse snapshot JSON for %s: %w", snap.RobloxUserID, err)
+		}
+
+		doc := SnapshotDocument{
+			RobloxUserID:  snap.RobloxUserID,
+			KeyAccountID:  snap.KeyAccountID,
+			ContentHash:   snap.ContentHash,
+			InventoryJSON: inventoryData,
+			Size:          len(snap.InventoryJSON),
+			CapturedAt:    snap.CapturedAt,
+		}
+		if r.keepOriginal {
+			if doc.InventoryOriginal, err = gzipJSON(snap.InventoryJSON); err != nil {
+				return appended, fmt.Errorf("failed to compress snapshot JSON for %s: %w", snap.RobloxUserID, err)
+			}
+		}
+
+		inserted, err := r.appendSnapshot(ctx, &doc)
+		if err != nil {
+			return appended, fmt.Errorf("failed to append snapshot for %s: %w", snap.RobloxUserID, err)
+		}
+		if inserted {
+			appended++
+		}
+	}
+	return appended, nil
+}
+
+// appendSnapshot inserts doc as the user's next version unless the latest
+// version has the same content. Reports whether it was inserted.
+func (r *MongoDBInventoryRepository) appendSnapshot(ctx context.Context, doc *SnapshotDocument) (bool, error) {
+	latestOpts := options.FindOne().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"version": 1, "content_hash": 1})
+
+	for attempt := 1; ; attempt++ {
+		var latest SnapshotDocument
+		err := r.history.FindOne(ctx, bson.M{"roblox_user_id": doc.RobloxUserID}, latestOpts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return false, fmt.Errorf("failed to read latest snapshot: %w", err)
+		}
+		if err == nil && latest.ContentHash == doc.ContentHash {
+			return false, nil
+		}
+
+		doc.Version = latest.Version + 1
+		_, err = r.history.InsertOne(ctx, doc)
+		if err == nil {
+			return true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt == snapshotAppendAttempts {
+			return false, err
+		}
+	}
+}
+
+// ListSnapshots returns snapshot metadata for a user, newest first.
+func (r *MongoDBInventoryRepository) ListSnapshots(ctx context.Context, robloxUserID string, limit, offset int) ([]model.InventorySnapshot, int64, error) {
+	filter := bson.M{"roblox_user_id": robloxUserID}
+
+	total, err := r.history.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetSkip(int64(offset)).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"inventory_json": 0, "inventory_original": 0})
+
+	cursor, err := r.history.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []SnapshotDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode snapshots: %w", err)
+	}
+
+	snapshots := make([]model.InventorySnapshot, 0, len(docs))
+	for i := range docs {
+		snap, err := docs[i].toModel()
+		if err != nil {
+			return nil, 0, err
+		}
+		snapshots = append(snapshots, *snap)
+	}
+	return snapshots, total, nil
+}
+
+// GetSnapshot returns a specific inventory version.
+func (r *MongoDBInventoryRepository) GetSnapshot(ctx context.Context, robloxUserID string, version int64) (*model.InventorySnapshot, error) {
+	return r.findSnapshot(ctx, bson.M{"roblox_user_id": robloxUserID, "version": version})
+}
+
+// GetSnapshotAt returns the latest version captured at or before t.
+func (r *MongoDBInventoryRepository) GetSnapshotAt(ctx context.Context, robloxUserID string, t time.Time) (*model.InventorySnapshot, error) {
+	return r.findSnapshot(ctx, bson.M{"roblox_user_id": robloxUserID, "captured_at": bson.M{"$lte": t}})
+}
+
+// findSnapshot returns the highest version matching filter, or nil.
+func (r *MongoDBInventoryRepository) findSnapshot(ctx context.Context, filter bson.M) (*model.InventorySnapshot, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var doc SnapshotDocument
+	err := r.history.FindOne(ctx, filter, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return doc.toModel()
+}
+
+// PruneSnapshots deletes versions older than retention except each user's latest two.
+func (r *MongoDBInventoryRepository) PruneSnapshots(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-retention)
+
+	// Latest version of every user that has snapshots past the cutoff
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$roblox_user_id"},
+			{Key: "latest", Value: bson.D{{Key: "$max", Value: "$version"}}},
+			{Key: "oldest", Value: bson.D{{Key: "$min", Value: "$captured_at"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "oldest", Value: bson.D{{Key: "$lt", Value: cutoffTime}}}}}},
+	}
+	cursor, err := r.history.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find prunable snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		RobloxUserID string `bson:"_id"`
+		Latest       int64  `bson:"latest"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, fmt.Errorf("failed to decode prunable snapshots: %w", err)
+	}
+
+	var deleted int64
+	for _, u := range users {
+		result, err := r.history.DeleteMany(ctx, bson.M{
+			"roblox_user_id": u.RobloxUserID,
+			"captured_at":    bson.M{"$lt": cutoffTime},
+			"version":        bson.M{"$lt": u.Latest - 1},
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to prune snapshots for %s: %w", u.RobloxUserID, err)
+		}
+		deleted += result.DeletedCount
+	}
+
+	if deleted > 0 {
+		log.Printf("[MongoDB] Pruned %d inventory snapshots (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryHistoryRepository
+var _ InventoryHistoryRepository = (*MongoDBInventoryRepository)(nil)
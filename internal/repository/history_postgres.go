@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// AppendSnapshots stores new inventory versions, skipping unchanged content.
+// The version number and the "content changed" check are evaluated in the
+// same statement, under a per-user advisory lock held until commit: without
+// it, concurrent batches for a user would pick the same version and the
+// unique (roblox_user_id, version) key would abort one of them.
+func (r *PostgresInventoryRepository) AppendSnapshots(ctx context.Context, snapshots []model.InventorySnapshot) (int, error) {
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locked in a fixed order, so two batches cannot wait on each other
+	users := make([]string, 0, len(snapshots))
+	seen := make(map[string]bool, len(snapshots))
+	for _, snap := range snapshots {
+		if !seen[snap.RobloxUserID] {
+			seen[snap.RobloxUserID] = true
+			users = append(users, snap.RobloxUserID)
+		}
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		if _, err := tx.ExecContext(ctx,
+			`SELECT pg_advisory_xact_lock(hashtext('fishit_inventory_history.' || $1::text))`, user); err != nil {
+			return 0, fmt.Errorf("failed to lock history of %s: %w", user, err)
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fishit_inventory_history (roblox_user_id, version, key_account_id, content_hash, inventory_json, captured_at)
+		SELECT $1::text, COALESCE(MAX(version), 0) + 1, $2::bigint, $3::text, $4::jsonb, $5::timestamptz
+		FROM fishit_inventory_history WHERE roblox_user_id = $1
+		HAVING COALESCE((
+			SELECT content_hash FROM fishit_inventory_history
+			WHERE roblox_user_id = $1 ORDER BY version DESC LIMIT 1
+		), '') <> $3`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	appended := 0
+	for _, snap := range snapshots {
+		res, err := stmt.ExecContext(ctx, snap.RobloxUserID, snap.KeyAccountID, snap.ContentHash,
+			[]byte(snap.InventoryJSON), snap.CapturedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to append snapshot for %s: %w", snap.RobloxUserID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			appended++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return appended, nil
+}
+
+// ListSnapshots returns snapshot metadata for a user, newest first.
+func (r *PostgresInventoryRepository) ListSnapshots(ctx context.Context, robloxUserID string, limit, offset int) ([]model.InventorySnapshot, int64, error) {
+	var total int64
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM fishit_inventory_history WHERE roblox_user_id = $1`, robloxUserID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT roblox_user_id, version, key_account_id, content_hash, octet_length(inventory_json::text), captured_at
+		FROM fishit_inventory_history WHERE roblox_user_id = $1
+		ORDER BY version DESC LIMIT $2 OFFSET $3`, robloxUserID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []model.InventorySnapshot{}
+	for rows.Next() {
+		var snap model.InventorySnapshot
+		if err := rows.Scan(&snap.RobloxUserID, &snap.Version, &snap.KeyAccountID,
+			&snap.ContentHash, &snap.Size, &snap.CapturedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, total, rows.Err()
+}
+
+// GetSnapshot returns a specific inventory version.
+func (r *PostgresInventoryRepository) GetSnapshot(ctx context.Context, robloxUserID string, version int64) (*model.InventorySnapshot, error) {
+	return scanPostgresSnapshot(r.db.QueryRowContext(ctx, `
+		SELECT roblox_user_id, version, key_account_id, content_hash, inventory_json, captured_at
+		FROM fishit_inventory_history WHERE roblox_user_id = $1 AND version = $2`, robloxUserID, version))
+}
+
+// GetSnapshotAt returns the latest version captured at or before t.
+func (r *PostgresInventoryRepository) GetSnapshotAt(ctx context.Context, robloxUserID string, t time.Time) (*model.InventorySnapshot, error) {
+	return scanPostgresSnapshot(r.db.QueryRowContext(ctx, `
+		SELECT roblox_user_id, version, key_account_id, content_hash, inventory_json, captured_at
+		FROM fishit_inventory_history WHERE roblox_user_id = $1 AND captured_at <= $2
+		ORDER BY version DESC LIMIT 1`, robloxUserID, t))
+}
+
+// scanPostgresSnapshot reads a full snapshot row. Returns nil if there is no row.
+func scanPostgresSnapshot(row *sql.Row) (*model.InventorySnapshot, error) {
+	var snap model.InventorySnapshot
+	var rawJSON []byte
+	err := row.Scan(&snap.RobloxUserID, &snap.Version, &snap.KeyAccountID, &snap.ContentHash, &rawJSON, &snap.CapturedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	snap.InventoryJSON = rawJSON
+	snap.Size = len(rawJSON)
+	return &snap, nil
+}
+
+// PruneSnapshots deletes versions older than retention except each user's latest two.
+func (r *PostgresInventoryRepository) PruneSnapshots(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-retention)
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM fishit_inventory_history
+		WHERE captured_at < $1
+		AND version < (
+			SELECT MAX(h.version) - 1 FROM fishit_inventory_history h
+			WHERE h.roblox_user_id = fishit_inventory_history.roblox_user_id
+		)`, cutoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Printf("[Postgres] Pruned %d inventory snapshots (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// Ensure PostgresInventoryRepository implements InventoryHistoryRepository
+var _ InventoryHistoryRepository = (*PostgresInventoryRepository)(nil)
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// AppendSnapshots stores new inventory versions, skipping unchanged content.
+// The version number and the "content changed" check are evaluated in the
+// same statement so concurrent writers cannot interleave between them.
+func (r *SQLiteInventoryRepository) AppendSnapshots(ctx context.Context, snapshots []model.InventorySnapshot) (int, error) {
+	if len(snapshots) == 0 {
+		return 0, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fishit_inventory_history (roblox_user_id, version, key_account_id, content_hash, inventory_json, captured_at)
+		SELECT ?1, COALESCE(MAX(version), 0) + 1, ?2, ?3, ?4, ?5
+		FROM fishit_inventory_history WHERE roblox_user_id = ?1
+		HAVING COALESCE((
+			SELECT content_hash FROM fishit_inventory_history
+			WHERE roblox_user_id = ?1 ORDER BY version DESC LIMIT 1
+		), '') <> ?3`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	appended := 0
+	for _, snap := range snapshots {
+		res, err := stmt.ExecContext(ctx, snap.RobloxUserID, snap.KeyAccountID, snap.ContentHash,
+			string(snap.InventoryJSON), snap.CapturedAt.UTC())
+		if err != nil {
+			return 0, fmt.Errorf("failed to append snapshot for %s: %w", snap.RobloxUserID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			appended++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return appended, nil
+}
+
+// ListSnapshots returns snapshot metadata for a user, newest first.
+func (r *SQLiteInventoryRepository) ListSnapshots(ctx context.Context, robloxUserID string, limit, offset int) ([]model.InventorySnapshot, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM fishit_inventory_history WHERE roblox_user_id = ?`, robloxUserID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT roblox_user_id, version, key_account_id, content_hash, LENGTH(inventory_json), captured_at
+		FROM fishit_inventory_history WHERE roblox_user_id = ?
+		ORDER BY version DESC LIMIT ? OFFSET ?`, robloxUserID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []model.InventorySnapshot{}
+	for rows.Next() {
+		var snap model.InventorySnapshot
+		if err := rows.Scan(&snap.RobloxUserID, &snap.Version, &snap.KeyAccountID,
+			&snap.ContentHash, &snap.Size, &snap.CapturedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, total, rows.Err()
+}
+
+// GetSnapshot returns a specific inventory version.
+func (r *SQLiteInventoryRepository) GetSnapshot(ctx context.Context, robloxUserID string, version int64) (*model.InventorySnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.scanSnapshot(r.db.QueryRowContext(ctx, `
+		SELECT roblox_user_id, version, key_account_id, content_hash, inventory_json, captured_at
+		FROM fishit_inventory_history WHERE roblox_user_id = ? AND version = ?`, robloxUserID, version))
+}
+
+// GetSnapshotAt returns the latest version captured at or before t.
+func (r *SQLiteInventoryRepository) GetSnapshotAt(ctx context.Context, robloxUserID string, t time.Time) (*model.InventorySnapshot, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.scanSnapshot(r.db.QueryRowContext(ctx, `
+		SELECT roblox_user_id, version, key_account_id, content_hash, inventory_json, captured_at
+		FROM fishit_inventory_history WHERE roblox_user_id = ? AND captured_at <= ?
+		ORDER BY version DESC LIMIT 1`, robloxUserID, t.UTC()))
+}
+
+// scanSnapshot reads a full snapshot row. Returns nil if there is no row.
+func (r *SQLiteInventoryRepository) scanSnapshot(row *sql.Row) (*model.InventorySnapshot, error) {
+	var snap model.InventorySnapshot
+	var rawJSON string
+	err := row.Scan(&snap.RobloxUserID, &snap.Version, &snap.KeyAccountID, &snap.ContentHash, &rawJSON, &snap.CapturedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	snap.InventoryJSON = []byte(rawJSON)
+	snap.Size = len(rawJSON)
+	return &snap, nil
+}
+
+// PruneSnapshots deletes versions older than retention except each user's latest two.
+func (r *SQLiteInventoryRepository) PruneSnapshots(ctx context.Context, retention time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-retention).UTC()
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM fishit_inventory_history
+		WHERE captured_at < ?
+		AND version < (
+			SELECT MAX(h.version) - 1 FROM fishit_inventory_history h
+			WHERE h.roblox_user_id = fishit_inventory_history.roblox_user_id
+		)`, cutoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Printf("[SQLite] Pruned %d inventory snapshots (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryHistoryRepository
+var _ InventoryHistoryRepository = (*SQLiteInventoryRepository)(nil)
@@ -15,19 +15,146 @@ type InventoryRepository interface {
 	// GetRawInventory retrieves raw JSON inventory by Roblox user ID.
 	GetRawInventory(ctx context.Context, robloxUserID string) ([]byte, *time.Time, error)
 
+	// GetInventory retrieves an inventory with its stored content hash, or nil if not found.
+	GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error)
+
 	// BatchUpsertRawInventory inserts or updates multiple inventories efficiently.
-	BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) error
+	// An item that cannot be stored, such as one with invalid JSON, is reported
+	// failed in the result without holding back the rest. An error means the
+	// batch as a whole could not be written and is worth retrying.
+	BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) (*model.BatchResult, error)
 
 	// GetStats returns statistics about the inventory database.
 	GetStats(ctx context.Context) (map[string]interface{}, error)
 
-	// DeleteInactiveUsers deletes inventory records that haven't been synced within threshold.
+	// DeleteInactiveUsers deletes inventory records that haven't been synced
+	// (or seen unchanged) within threshold.
 	DeleteInactiveUsers(ctx context.Context, threshold time.Duration) (int64, error)
 
 	// Close closes the repository connection.
 	Close() error
 }
 
+// InventoryLastSeenRepository lets syncs with unchanged content skip the full
+// write: the stored hash is compared first and only the last-seen time is bumped.
+type InventoryLastSeenRepository interface {
+	// GetContentHash returns the stored content hash, or "" if the user has no inventory.
+	GetContentHash(ctx context.Context, robloxUserID string) (string, error)
+
+	// TouchInventory records that the user's inventory was synced unchanged at
+	// seenAt; last-seen times never move back. Reports false if the user has
+	// no stored inventory.
+	TouchInventory(ctx context.Context, robloxUserID string, seenAt time.Time) (bool, error)
+}
+
+// InventoryConditionalRepository makes If-Match writes atomic: the stored
+// content hash is checked and the inventory replaced in one step, so a write
+// landing between a client's read and its conditional write is not overwritten.
+type InventoryConditionalRepository interface {
+	// ReplaceInventoryIfHash replaces the user's inventory, synced now, only
+	// if its content hash is ifHash. Reports false, writing nothing, if it is
+	// not or the user has no inventory.
+	ReplaceInventoryIfHash(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, ifHash string) (bool, error)
+}
+
+// InventoryHistoryRepository defines access to the append-only inventory snapshot store.
+// Backends that keep history implement it alongside InventoryRepository.
+type InventoryHistoryRepository interface {
+	// AppendSnapshots stores a new version for each snapshot whose content hash
+	// differs from the user's latest version. Returns how many were appended.
+	AppendSnapshots(ctx context.Context, snapshots []model.InventorySnapshot) (int, error)
+
+	// ListSnapshots returns snapshot metadata (without content), newest first.
+	ListSnapshots(ctx context.Context, robloxUserID string, limit, offset int) ([]model.InventorySnapshot, int64, error)
+
+	// GetSnapshot returns a specific version, or nil if it does not exist.
+	GetSnapshot(ctx context.Context, robloxUserID string, version int64) (*model.InventorySnapshot, error)
+
+	// GetSnapshotAt returns the latest version captured at or before t, or nil.
+	GetSnapshotAt(ctx context.Context, robloxUserID string, t time.Time) (*model.InventorySnapshot, error)
+
+	// PruneSnapshots deletes versions older than retention, always keeping each
+	// user's latest two versions so the most recent change can still be diffed.
+	PruneSnapshots(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// InventoryValueRepository stores inventory value over time, for charting.
+type InventoryValueRepository interface {
+	// RecordValues appends a value point for each user whose total differs
+	// from their latest recorded point.
+	RecordValues(ctx context.Context, points []model.ValuePoint) error
+
+	// ListValues returns a user's value points recorded in [from, to], oldest first.
+	ListValues(ctx context.Context, robloxUserID string, from, to time.Time, limit int) ([]model.ValuePoint, error)
+
+	// PruneValues deletes value points older than retention, always keeping each user's latest point.
+	PruneValues(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+// InventorySearchRepository searches items across all stored inventories.
+// Backends push the filters down into their own query language.
+type InventorySearchRepository interface {
+	// SearchItems returns the users holding items that match q, ordered by
+	// item count (descending), and the total number of matching users.
+	SearchItems(ctx context.Context, q model.ItemSearchQuery) ([]model.ItemOwnership, int64, error)
+}
+
+// InventoryProjectionRepository reads parts of a stored inventory without
+// loading the whole document. Backends without it are served by decoding the
+// full document in the service.
+type InventoryProjectionRepository interface {
+	// GetInventoryFields returns a user's inventory with InventoryJSON cut down
+	// to the given top-level fields, or nil if not found.
+	GetInventoryFields(ctx context.Context, robloxUserID string, fields []string) (*model.RawInventory, error)
+
+	// GetCategoryItems returns the page of one category's items selected by q,
+	// or nil if the user has no inventory.
+	GetCategoryItems(ctx context.Context, robloxUserID string, q model.CategoryQuery) (*model.CategoryItems, error)
+}
+
+// InventoryScanner iterates over every stored inventory, for rebuilding
+// derived data such as leaderboards.
+type InventoryScanner interface {
+	// ScanInventories calls fn for each stored inventory in roblox_user_id order.
+	// Iteration stops at the first error returned by fn.
+	ScanInventories(ctx context.Context, fn func(item model.InventoryItem) error) error
+}
+
+// InventoryRetentionRepository lets the cleanup scheduler decide per user
+// whether an inactive inventory is purged, instead of deleting everything
+// past a single threshold with DeleteInactiveUsers.
+type InventoryRetentionRepository interface {
+	// ListInactiveInventories returns up to limit inventories not synced (or
+	// seen unchanged) since before, with roblox_user_id greater than after,
+	// in roblox_user_id order.
+	ListInactiveInventories(ctx context.Context, before time.Time, after string, limit int) ([]model.RawInventory, error)
+
+	// DeleteInventoryIfInactive deletes a user's inventory unless it was synced
+	// or seen since before. Reports whether it was deleted.
+	DeleteInventoryIfInactive(ctx context.Context, robloxUserID string, before time.Time) (bool, error)
+}
+
+// InventoryTombstoneRepository is implemented by backends that soft delete:
+// DeleteInactiveUsers and DeleteInventoryIfInactive move inventories to a
+// tombstone store, from which they can be restored until pruned.
+type InventoryTombstoneRepository interface {
+	// RestoreInventory moves the user's latest tombstoned inventory back,
+	// unless a live inventory synced at or after it exists. The restored
+	// inventory counts as seen now. Returns nil if the user has no tombstone.
+	RestoreInventory(ctx context.Context, robloxUserID string) (*model.RestoreResult, error)
+
+	// PruneTombstones permanently deletes tombstones older than grace.
+	PruneTombstones(ctx context.Context, grace time.Duration) (int64, error)
+}
+
+// InventoryEraser is implemented by backends that can erase everything
+// stored about a user, for data deletion requests.
+type InventoryEraser interface {
+	// EraseUser deletes the user's inventory, tombstones, history snapshots
+	// and value points. Returns how many records were deleted.
+	EraseUser(ctx context.Context, robloxUserID string) (int64, error)
+}
+
 // KeyAccountRepository defines key account data access methods.
 type KeyAccountRepository interface {
 	// GetKeyAccountByRobloxUser finds key_account by roblox_user_id.
@@ -36,3 +163,17 @@ type KeyAccountRepository interface {
 	// ValidateKeyAndHWID validates a key+hwid+roblox_id combination for token generation.
 	ValidateKeyAndHWID(ctx context.Context, key, hwid, robloxUserID string) (*model.KeyAccountValidation, error)
 }
+
+// KeyAccountLister is an optional KeyAccountRepository extension that lists
+// the key accounts of a Roblox user, for data export requests.
+type KeyAccountLister interface {
+	// ListKeyAccounts returns all of the user's key accounts, active or not.
+	ListKeyAccounts(ctx context.Context, robloxUserID string) ([]model.KeyAccount, error)
+}
+
+// KeyTierRepository is an optional KeyAccountRepository extension that
+// resolves the tier of the key behind key accounts, for retention rules.
+type KeyTierRepository interface {
+	// GetKeyTiers returns the key tier of each given key account that has one.
+	GetKeyTiers(ctx context.Context, keyAccountIDs []int64) (map[int64]string, error)
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// MemoryInventoryRepository implements InventoryRepository in process memory,
+// for tests and local demos. With a snapshot path it is saved to disk
+// periodically and on Close, and loaded again when created.
+type MemoryInventoryRepository struct {
+	mu          sync.RWMutex
+	inventories map[string]*model.RawInventory
+	tombstones  []memoryTombstone
+	history     map[string][]model.InventorySnapshot // oldest version first
+	values      map[string][]model.ValuePoint        // in recording order
+	nextID      int64
+	changes     uint64 // bumped on every write, to skip unchanged snapshots
+
+	snapshotPath string
+	saved        uint64 // changes at the last snapshot
+	stop         chan struct{}
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// memoryTombstone is a soft-deleted inventory.
+type memoryTombstone struct {
+	ID        int64
+	Inventory model.RawInventory
+	DeletedAt time.Time
+}
+
+// NewMemoryInventoryRepository creates an in-memory inventory repository.
+// A non-empty snapshotPath is loaded if it exists and, with a positive
+// interval, rewritten that often while there are changes.
+func NewMemoryInventoryRepository(snapshotPath string, interval time.Duration) (*MemoryInventoryRepository, error) {
+	r := &MemoryInventoryRepository{
+		inventories:  make(map[string]*model.RawInventory),
+		history:      make(map[string][]model.InventorySnapshot),
+		values:       make(map[string][]model.ValuePoint),
+		snapshotPath: snapshotPath,
+	}
+
+	if snapshotPath != "" {
+		if err := r.loadSnapshot(); err != nil {
+			return nil, err
+		}
+		if interval > 0 {
+			r.stop = make(chan struct{})
+			r.done = make(chan struct{})
+			go r.snapshotLoop(interval)
+		}
+	}
+
+	log.Printf("[MemoryInventoryRepository] Initialized with %d inventories", len(r.inventories))
+	return r, nil
+}
+
+// UpsertRawInventory inserts or replaces a user's inventory, synced now.
+func (r *MemoryInventoryRepository) UpsertRawInventory(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	r.upsert(keyAccountID, robloxUserID, rawJSON, contentHash("", rawJSON), now, now)
+	return nil
+}
+
+// BatchUpsertRawInventory inserts or replaces multiple inventories at once.
+func (r *MemoryInventoryRepository) BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) (*model.BatchResult, error) {
+	items, result := screenBatch(items)
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, item := range items {
+		r.upsert(item.KeyAccountID, item.RobloxUserID, item.RawJSON, contentHash(item.ContentHash, item.RawJSON), item.SyncedAt, item.SyncedAt)
+	}
+	succeeded(result, items)
+	return result, nil
+}
+
+// upsert stores an inventory, keeping the ID of the one it replaces and a
+// later last-seen time. Called with mu held.
+func (r *MemoryInventoryRepository) upsert(keyAccountID int64, robloxUserID string, rawJSON []byte, hash string, syncedAt, lastSeenAt time.Time) {
+	inv, ok := r.inventories[robloxUserID]
+	if !ok {
+		r.nextID++
+		inv = &model.RawInventory{ID: r.nextID, RobloxUserID: robloxUserID}
+		r.inventories[robloxUserID] = inv
+	}
+	inv.KeyAccountID = keyAccountID
+	inv.InventoryJSON = bytes.Clone(rawJSON)
+	inv.ContentHash = hash
+	inv.SyncedAt = syncedAt.UTC()
+	r.see(inv, lastSeenAt)
+	r.changes++
+}
+
+// see moves an inventory's last-seen time forward to seenAt. Called with mu held.
+func (r *MemoryInventoryRepository) see(inv *model.RawInventory, seenAt time.Time) {
+	if inv.LastSeenAt == nil || seenAt.After(*inv.LastSeenAt) {
+		seen := seenAt.UTC()
+		inv.LastSeenAt = &seen
+	}
+}
+
+// GetRawInventory retrieves raw JSON inventory by Roblox user ID.
+func (r *MemoryInventoryRepository) GetRawInventory(ctx context.Context, robloxUserID string) ([]byte, *time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inv, ok := r.inventories[robloxUserID]
+	if !ok {
+		return nil, nil, nil
+	}
+	syncedAt := inv.SyncedAt
+	return bytes.Clone(inv.InventoryJSON), &syncedAt, nil
+}
+
+// GetInventory retrieves an inventory with its content hash, or nil if not found.
+func (r *MemoryInventoryRepository) GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inv, ok := r.inventories[robloxUserID]
+	if !ok {
+		return nil, nil
+	}
+	return copyInventory(inv), nil
+}
+
+// GetContentHash returns the stored content hash, or "" if the user has no inventory.
+func (r *MemoryInventoryRepository) GetContentHash(ctx context.Context, robloxUserID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if inv, ok := r.inventories[robloxUserID]; ok {
+		return inv.ContentHash, nil
+	}
+	return "", nil
+}
+
+// TouchInventory records that the user's inventory was synced unchanged at seenAt,
+// unless it was seen later already. Reports false if the user has no stored inventory.
+func (r *MemoryInventoryRepository) TouchInventory(ctx context.Context, robloxUserID string, seenAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.inventories[robloxUserID]
+	if !ok {
+		return false, nil
+	}
+	r.see(inv, seenAt)
+	r.changes++
+	return true, nil
+}
+
+// GetStats returns statistics about the stored inventories. db_size_bytes
+// counts inventory JSON only.
+func (r *MemoryInventoryRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]interface{})
+	stats["total_inventories"] = int64(len(r.inventories))
+
+	var lastSync time.Time
+	var size int64
+	for _, inv := range r.inventories {
+		if inv.SyncedAt.After(lastSync) {
+			lastSync = inv.SyncedAt
+		}
+		size += int64(len(inv.InventoryJSON))
+	}
+	if !lastSync.IsZero() {
+		stats["last_sync"] = lastSync
+	}
+	stats["db_size_bytes"] = size
+	if r.snapshotPath != "" {
+		stats["snapshot_path"] = r.snapshotPath
+	}
+	return stats, nil
+}
+
+// DeleteInactiveUsers moves inventories that haven't been synced within the
+// threshold to the tombstones.
+func (r *MemoryInventoryRepository) DeleteInactiveUsers(ctx context.Context, threshold time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-threshold)
+	var deleted int64
+	for id, inv := range r.inventories {
+		if lastActive(inv).Before(cutoffTime) {
+			r.softDelete(id)
+			deleted++
+		}
+	}
+
+	if deleted > 0 {
+		log.Printf("[Memory] Cleaned up %d inactive inventory records (threshold: %v)", deleted, threshold)
+	}
+	return deleted, nil
+}
+
+// softDelete moves a user's inventory to the tombstones. Called with mu held.
+func (r *MemoryInventoryRepository) softDelete(robloxUserID string) {
+	inv := r.inventories[robloxUserID]
+	delete(r.inventories, robloxUserID)
+	r.nextID++
+	r.tombstones = append(r.tombstones, memoryTombstone{ID: r.nextID, Inventory: *inv, DeletedAt: time.Now().UTC()})
+	r.changes++
+}
+
+// Close stops periodic snapshots and writes a final one.
+func (r *MemoryInventoryRepository) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		if r.stop != nil {
+			close(r.stop)
+			<-r.done
+		}
+		if r.snapshotPath != "" {
+			err = r.Snapshot()
+		}
+	})
+	return err
+}
+
+// lastActive returns when an inventory was last synced or seen.
+func lastActive(inv *model.RawInventory) time.Time {
+	if inv.LastSeenAt != nil {
+		return *inv.LastSeenAt
+	}
+	return inv.SyncedAt
+}
+
+// copyInventory returns a copy of inv that shares no memory with it.
+func copyInventory(inv *model.RawInventory) *model.RawInventory {
+	c := *inv
+	c.InventoryJSON = bytes.Clone(inv.InventoryJSON)
+	if inv.LastSeenAt != nil {
+		seen := *inv.LastSeenAt
+		c.LastSeenAt = &seen
+	}
+	return &c
+}
+
+// sortedUserIDs returns the IDs of the stored inventories in order. Called
+// with mu held.
+func (r *MemoryInventoryRepository) sortedUserIDs() []string {
+	ids := make([]string, 0, len(r.inventories))
+	for id := range r.inventories {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Ensure MemoryInventoryRepository implements InventoryRepository
+var _ InventoryRepository = (*MemoryInventoryRepository)(nil)
+
+// Ensure MemoryInventoryRepository implements InventoryLastSeenRepository
+var _ InventoryLastSeenRepository = (*MemoryInventoryRepository)(nil)
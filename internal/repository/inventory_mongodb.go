@@ -1,15 +1,21 @@
 package repository
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"vinzhub-rest-api-v2/internal/model"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -19,10 +25,44 @@ type MongoDBInventoryRepository struct {
 	client     *mongo.Client
 	db         *mongo.Database
 	collection *mongo.Collection
+	history    *mongo.Collection
+	values     *mongo.Collection
+	tombstones *mongo.Collection
+
+	keepOriginal bool // store synced JSON bytes alongside the BSON document
 }
 
-// NewMongoDBInventoryRepository creates a new MongoDB inventory repository.
+// NewMongoDBInventoryRepository creates a new MongoDB inventory repository
+// and applies pending index migrations.
 func NewMongoDBInventoryRepository(uri, database, collection string) (*MongoDBInventoryRepository, error) {
+	client, err := connectMongo(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database(database)
+	migrator, err := newMongoMigrator(db, collection)
+	if err == nil {
+		err = migrateUp(migrator)
+	}
+	if err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	log.Printf("[MongoDB] Connected to %s/%s", database, collection)
+	return &MongoDBInventoryRepository{
+		client:     client,
+		db:         db,
+		collection: db.Collection(collection),
+		history:    db.Collection(collection + "_history"),    // one document per (roblox_user_id, version)
+		values:     db.Collection(collection + "_value"),      // inventory value over time per user
+		tombstones: db.Collection(collection + "_tombstones"), // soft-deleted inventories, restorable until pruned
+	}, nil
+}
+
+// connectMongo connects to MongoDB and verifies the connection.
+func connectMongo(uri string) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -41,28 +81,10 @@ func NewMongoDBInventoryRepository(uri, database, collection string) (*MongoDBIn
 
 	// Ping to verify connection
 	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(context.Background())
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
-
-	db := client.Database(database)
-	coll := db.Collection(collection)
-
-	// Create index on roblox_user_id
-	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{Key: "roblox_user_id", Value: 1}},
-		Options: options.Index().SetUnique(true),
-	}
-	_, err = coll.Indexes().CreateOne(ctx, indexModel)
-	if err != nil {
-		log.Printf("[MongoDB] Warning: failed to create index: %v", err)
-	}
-
-	log.Printf("[MongoDB] Connected to %s/%s", database, collection)
-	return &MongoDBInventoryRepository{
-		client:     client,
-		db:         db,
-		collection: coll,
-	}, nil
+	return client, nil
 }
 
 // InventoryDocument represents a document in MongoDB.
@@ -70,46 +92,57 @@ type InventoryDocument struct {
 	RobloxUserID  string      `bson:"roblox_user_id"`
 	KeyAccountID  int64       `bson:"key_account_id,omitempty"`
 	InventoryJSON interface{} `bson:"inventory_json"` // Stores parsed JSON as BSON
+	ContentHash   string      `bson:"content_hash,omitempty"`
 	SyncedAt      time.Time   `bson:"synced_at"`
+	LastSeenAt    *time.Time  `bson:"last_seen_at,omitempty"`
+	// The JSON as synced, gzipped, when the repository keeps it
+	InventoryOriginal []byte `bson:"inventory_original,omitempty"`
 }
 
 // UpsertRawInventory inserts or updates raw JSON inventory.
 func (r *MongoDBInventoryRepository) UpsertRawInventory(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte) error {
-	// Parse JSON to interface{} for proper BSON conversion
-	var inventoryData interface{}
-	if err := json.Unmarshal(rawJSON, &inventoryData); err != nil {
+	// Parse JSON for proper BSON conversion
+	inventoryData, err := jsonToBSON(rawJSON)
+	if err != nil {
 		return fmt.Errorf("failed to parse inventory JSON: %w", err)
 	}
 
+	now := time.Now()
 	filter := bson.M{"roblox_user_id": robloxUserID}
 	update := bson.M{
 		"$set": bson.M{
 			"key_account_id":  keyAccountID,
 			"inventory_json":  inventoryData,
-			"synced_at":       time.Now(),
+			"content_hash":    contentHash("", rawJSON),
+			"synced_at":       now,
+			"last_seen_at":    now,
 		},
 	}
+	if err := r.setOriginalJSON(update, rawJSON); err != nil {
+		return err
+	}
 
 	opts := options.Update().SetUpsert(true)
-	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	_, err = r.collection.UpdateOne(ctx, filter, update, opts)
 	if err != nil {
 		return fmt.Errorf("failed to upsert inventory: %w", err)
 	}
 	return nil
 }
 
-// BatchUpsertRawInventory upserts multiple inventory records.
-func (r *MongoDBInventoryRepository) BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) error {
-	if len(items) == 0 {
-		return nil
-	}
+// BatchUpsertRawInventory upserts multiple inventory records. Writes are
+// unordered, so a document the server rejects fails alone.
+func (r *MongoDBInventoryRepository) BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) (*model.BatchResult, error) {
+	items, result := screenBatch(items)
 
-	models := make([]mongo.WriteModel, len(items))
-	for i, item := range items {
-		// Parse JSON to interface{} for proper BSON conversion
-		var inventoryData interface{}
-		if err := json.Unmarshal(item.RawJSON, &inventoryData); err != nil {
-			log.Printf("[MongoDB] Warning: failed to parse JSON for %s: %v", item.RobloxUserID, err)
+	// models[i] writes written[i]
+	models := make([]mongo.WriteModel, 0, len(items))
+	written := make([]model.InventoryItem, 0, len(items))
+	for _, item := range items {
+		// Parse JSON for proper BSON conversion
+		inventoryData, err := jsonToBSON(item.RawJSON)
+		if err != nil {
+			result.Failed = append(result.Failed, model.BatchFailure{RobloxUserID: item.RobloxUserID, Reason: err.Error()})
 			continue
 		}
 
@@ -118,20 +151,51 @@ func (r *MongoDBInventoryRepository) BatchUpsertRawInventory(ctx context.Context
 			"$set": bson.M{
 				"key_account_id":  item.KeyAccountID,
 				"inventory_json":  inventoryData,
+				"content_hash":    contentHash(item.ContentHash, item.RawJSON),
 				"synced_at":       item.SyncedAt,
 			},
+			"$max": bson.M{
+				"last_seen_at": item.SyncedAt,
+			},
+		}
+		if err := r.setOriginalJSON(update, item.RawJSON); err != nil {
+			result.Failed = append(result.Failed, model.BatchFailure{RobloxUserID: item.RobloxUserID, Reason: err.Error()})
+			continue
 		}
-		models[i] = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+		written = append(written, item)
+	}
+	if len(models) == 0 {
+		return result, nil
 	}
 
 	opts := options.BulkWrite().SetOrdered(false)
 	_, err := r.collection.BulkWrite(ctx, models, opts)
-	if err != nil {
-		return fmt.Errorf("failed to batch upsert: %w", err)
+	var bwe mongo.BulkWriteException
+	if err != nil && (!errors.As(err, &bwe) || bwe.WriteConcernError != nil) {
+		return nil, fmt.Errorf("failed to batch upsert: %w", err)
 	}
 
-	log.Printf("[MongoDB] Batch upserted %d items", len(items))
-	return nil
+	// An item that lost an upsert race to another writer is left out of
+	// both lists, so it is retried
+	errored := make(map[int]bool, len(bwe.WriteErrors))
+	for _, we := range bwe.WriteErrors {
+		errored[we.Index] = true
+		if mongo.IsDuplicateKeyError(we) {
+			continue
+		}
+		item := written[we.Index]
+		log.Printf("[MongoDB] Rejected batch item %s: %v", item.RobloxUserID, we)
+		result.Failed = append(result.Failed, model.BatchFailure{RobloxUserID: item.RobloxUserID, Reason: we.Error()})
+	}
+	for i, item := range written {
+		if !errored[i] {
+			result.Succeeded = append(result.Succeeded, item.RobloxUserID)
+		}
+	}
+
+	log.Printf("[MongoDB] Batch upserted %d items", len(result.Succeeded))
+	return result, nil
 }
 
 // GetRawInventory retrieves raw JSON inventory by Roblox user ID.
@@ -147,8 +211,8 @@ func (r *MongoDBInventoryRepository) GetRawInventory(ctx context.Context, roblox
 		return nil, nil, fmt.Errorf("failed to get inventory: %w", err)
 	}
 
-	// Convert BSON back to JSON
-	jsonBytes, err := json.Marshal(doc.InventoryJSON)
+	// The kept original, or BSON converted back to JSON
+	jsonBytes, err := doc.rawJSON()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal inventory to JSON: %w", err)
 	}
@@ -156,6 +220,63 @@ func (r *MongoDBInventoryRepository) GetRawInventory(ctx context.Context, roblox
 	return jsonBytes, &doc.SyncedAt, nil
 }
 
+// GetInventory retrieves an inventory with its content hash, or nil if not found.
+// The hash is the one computed from the JSON as synced, since the BSON round
+// trip does not preserve formatting unless the original JSON is kept.
+func (r *MongoDBInventoryRepository) GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error) {
+	filter := bson.M{"roblox_user_id": robloxUserID}
+
+	var doc InventoryDocument
+	err := r.collection.FindOne(ctx, filter).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	jsonBytes, err := doc.rawJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inventory to JSON: %w", err)
+	}
+
+	return &model.RawInventory{
+		KeyAccountID:  doc.KeyAccountID,
+		RobloxUserID:  doc.RobloxUserID,
+		InventoryJSON: jsonBytes,
+		ContentHash:   contentHash(doc.ContentHash, jsonBytes),
+		SyncedAt:      doc.SyncedAt,
+		LastSeenAt:    doc.LastSeenAt,
+	}, nil
+}
+
+// GetContentHash returns the stored content hash, or "" if the user has no inventory.
+func (r *MongoDBInventoryRepository) GetContentHash(ctx context.Context, robloxUserID string) (string, error) {
+	opts := options.FindOne().SetProjection(bson.M{"content_hash": 1})
+
+	var doc InventoryDocument
+	err := r.collection.FindOne(ctx, bson.M{"roblox_user_id": robloxUserID}, opts).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get content hash: %w", err)
+	}
+	return doc.ContentHash, nil
+}
+
+// TouchInventory records that the user's inventory was synced unchanged at seenAt,
+// unless it was seen later already. Reports false if the user has no stored inventory.
+func (r *MongoDBInventoryRepository) TouchInventory(ctx context.Context, robloxUserID string, seenAt time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"roblox_user_id": robloxUserID},
+		bson.M{"$max": bson.M{"last_seen_at": seenAt}})
+	if err != nil {
+		return false, fmt.Errorf("failed to touch inventory: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
 // GetStats returns statistics about the inventory collection.
 func (r *MongoDBInventoryRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -176,13 +297,17 @@ func (r *MongoDBInventoryRepository) GetStats(ctx context.Context) (map[string]i
 		stats["last_sync"] = doc.SyncedAt
 	}
 
-	// Get collection stats
+	// Get collection stats; size is 0 if unavailable
+	stats["db_size_bytes"] = int64(0)
 	result := r.db.RunCommand(ctx, bson.D{{Key: "collStats", Value: r.collection.Name()}})
 	var collStats bson.M
 	if err := result.Decode(&collStats); err == nil {
-		if size, ok := collStats["size"].(int64); ok {
+		switch size := collStats["size"].(type) {
+		case int64:
 			stats["db_size_bytes"] = size
-		} else if size, ok := collStats["size"].(int32); ok {
+		case int32:
+			stats["db_size_bytes"] = int64(size)
+		case float64:
 			stats["db_size_bytes"] = int64(size)
 		}
 	}
@@ -190,27 +315,169 @@ func (r *MongoDBInventoryRepository) GetStats(ctx context.Context) (map[string]i
 	return stats, nil
 }
 
-// DeleteInactiveUsers deletes inventory records that haven't been synced within the threshold.
+// DeleteInactiveUsers moves inventory records that haven't been synced within
+// the threshold to the tombstone collection.
 // For example, threshold of 30*24*time.Hour deletes users inactive for 30 days.
 func (r *MongoDBInventoryRepository) DeleteInactiveUsers(ctx context.Context, threshold time.Duration) (int64, error) {
 	cutoffTime := time.Now().Add(-threshold)
 	
-	filter := bson.M{
-		"synced_at": bson.M{
-			"$lt": cutoffTime,
-		},
-	}
-	
-	result, err := r.collection.DeleteMany(ctx, filter)
+	deleted, err := r.softDelete(ctx, inactiveFilter(cutoffTime))
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete inactive users: %w", err)
 	}
 	
-	if result.DeletedCount > 0 {
-		log.Printf("[MongoDB] Cleaned up %d inactive inventory records (threshold: %v)", result.DeletedCount, threshold)
+	if deleted > 0 {
+		log.Printf("[MongoDB] Cleaned up %d inactive inventory records (threshold: %v)", deleted, threshold)
 	}
 	
-	return result.DeletedCount, nil
+	return deleted, nil
+}
+
+// jsonToBSON decodes JSON for storage as BSON, keeping what a plain
+// json.Unmarshal loses: objects become bson.D in their original key order,
+// and integers int64 (Decimal128 beyond its range) instead of float64.
+func jsonToBSON(rawJSON []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(rawJSON))
+	dec.UseNumber()
+	v, err := decodeBSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("unexpected data after top-level value")
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// decodeBSONValue decodes the next JSON value from dec.
+func decodeBSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			doc := bson.D{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeBSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				doc = append(doc, bson.E{Key: key.(string), Value: value})
+			}
+			_, err := dec.Token() // closing brace
+			return doc, err
+		}
+		arr := bson.A{}
+		for dec.More() {
+			value, err := decodeBSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err := dec.Token() // closing bracket
+		return arr, err
+	case json.Number:
+		return bsonNumber(t), nil
+	default: // string, bool or nil
+		return t, nil
+	}
+}
+
+// bsonNumber converts a JSON number to the BSON type that holds it exactly,
+// falling back to float64 for fractions.
+func bsonNumber(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if !strings.ContainsAny(n.String(), ".eE") {
+		if d, err := primitive.ParseDecimal128(n.String()); err == nil {
+			return d
+		}
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// bsonToJSON converts a decoded BSON value to JSON. Embedded documents decode
+// as primitive.D, which encoding/json would render as Key/Value pairs, so
+// they are written out here in their stored key order.
+func bsonToJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeBSONJSON(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBSONJSON appends the JSON encoding of v to buf.
+func writeBSONJSON(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case primitive.D:
+		buf.WriteByte('{')
+		for i, e := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, e.Key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeBSONJSON(buf, e.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case primitive.M:
+		// Maps have no order; sort the keys as encoding/json does
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d := make(primitive.D, len(keys))
+		for i, k := range keys {
+			d[i] = primitive.E{Key: k, Value: t[k]}
+		}
+		return writeBSONJSON(buf, d)
+	case primitive.A:
+		buf.WriteByte('[')
+		for i, val := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeBSONJSON(buf, val); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case primitive.Decimal128:
+		buf.WriteString(t.String())
+	default:
+		return writeJSON(buf, t)
+	}
+	return nil
+}
+
+// writeJSON appends the JSON encoding of a scalar to buf, leaving <, > and &
+// as they were synced.
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // Encode's newline
+	return nil
 }
 
 // Close closes the MongoDB connection.
@@ -219,3 +486,6 @@ func (r *MongoDBInventoryRepository) Close() error {
 	defer cancel()
 	return r.client.Disconnect(ctx)
 }
+
+// Ensure MongoDBInventoryRepository implements InventoryLastSeenRepository
+var _ InventoryLastSeenRepository = (*MongoDBInventoryRepository)(nil)
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"vinzhub-rest-api-v2/internal/migrate"
 	"vinzhub-rest-api-v2/internal/model"
 
 	_ "modernc.org/sqlite" // Pure Go SQLite driver - no CGO required
@@ -20,9 +21,30 @@ type SQLiteInventoryRepository struct {
 	mu sync.RWMutex
 }
 
-// NewSQLiteInventoryRepository creates a new SQLite inventory repository.
+// NewSQLiteInventoryRepository creates a new SQLite inventory repository
+// and applies pending schema migrations.
 // dbPath is the path to the SQLite database file (e.g., "./data/inventory.db")
 func NewSQLiteInventoryRepository(dbPath string) (*SQLiteInventoryRepository, error) {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := newSQLMigrator(db, "sqlite", migrate.SQLite, dbPath)
+	if err == nil {
+		err = migrateUp(migrator)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.Printf("[SQLiteInventoryRepository] Initialized with database: %s", dbPath)
+	return &SQLiteInventoryRepository{db: db}, nil
+}
+
+// openSQLite opens the database at dbPath.
+func openSQLite(dbPath string) (*sql.DB, error) {
 	// Open with WAL mode and other optimizations
 	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=10000&_busy_timeout=5000", dbPath)
 
@@ -36,29 +58,36 @@ func NewSQLiteInventoryRepository(dbPath string) (*SQLiteInventoryRepository, er
 	db.SetMaxIdleConns(1)
 	db.SetConnMaxLifetime(0) // Keep connection alive
 
-	// Create table if not exists
-	if err := createTables(db); err != nil {
-		return nil, fmt.Errorf("failed to create tables: %w", err)
+	if err := upgradeLegacySchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to upgrade legacy schema: %w", err)
 	}
+	return db, nil
+}
 
-	log.Printf("[SQLiteInventoryRepository] Initialized with database: %s", dbPath)
-	return &SQLiteInventoryRepository{db: db}, nil
+// upgradeLegacySchema adds the columns that inventory tables created before
+// they existed lack, so the first migration finds the table it creates.
+func upgradeLegacySchema(db *sql.DB) error {
+	var tables int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'fishit_inventory_raw'`).Scan(&tables)
+	if err != nil || tables == 0 {
+		return err
+	}
+
+	if err := addSQLiteColumn(db, "fishit_inventory_raw", "content_hash", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return addSQLiteColumn(db, "fishit_inventory_raw", "last_seen_at", "DATETIME")
 }
 
-// createTables creates the inventory table.
-func createTables(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS fishit_inventory_raw (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		key_account_id INTEGER DEFAULT 0,
-		roblox_user_id TEXT NOT NULL UNIQUE,
-		inventory_json TEXT NOT NULL,
-		synced_at DATETIME NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_roblox_user ON fishit_inventory_raw(roblox_user_id);
-	CREATE INDEX IF NOT EXISTS idx_synced_at ON fishit_inventory_raw(synced_at);
-	`
-	_, err := db.Exec(query)
+// addSQLiteColumn adds a column to an existing table unless it is already there.
+func addSQLiteColumn(db *sql.DB, table, column, definition string) error {
+	var exists int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
@@ -67,15 +96,19 @@ func (r *SQLiteInventoryRepository) UpsertRawInventory(ctx context.Context, keyA
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	// Timestamps are stored as UTC text, so they compare in time order; Go
+	// sets them rather than datetime('now'), which keeps only seconds
 	query := `
-		INSERT INTO fishit_inventory_raw (key_account_id, roblox_user_id, inventory_json, synced_at)
-		VALUES (?, ?, ?, datetime('now'))
+		INSERT INTO fishit_inventory_raw (key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?5)
 		ON CONFLICT(roblox_user_id) DO UPDATE SET
 			key_account_id = COALESCE(excluded.key_account_id, key_account_id),
 			inventory_json = excluded.inventory_json,
-			synced_at = datetime('now')`
+			content_hash = excluded.content_hash,
+			synced_at = excluded.synced_at,
+			last_seen_at = excluded.last_seen_at`
 
-	_, err := r.db.ExecContext(ctx, query, keyAccountID, robloxUserID, string(rawJSON))
+	_, err := r.db.ExecContext(ctx, query, keyAccountID, robloxUserID, string(rawJSON), contentHash("", rawJSON), time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("failed to upsert raw inventory: %w", err)
 	}
@@ -83,9 +116,12 @@ func (r *SQLiteInventoryRepository) UpsertRawInventory(ctx context.Context, keyA
 }
 
 // BatchUpsertRawInventory inserts or updates multiple inventories efficiently.
-func (r *SQLiteInventoryRepository) BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) error {
+// Each row is written under a savepoint, so a row SQLite refuses is rolled
+// back and reported failed while the rest are committed.
+func (r *SQLiteInventoryRepository) BatchUpsertRawInventory(ctx context.Context, items []model.InventoryItem) (*model.BatchResult, error) {
+	items, result := screenBatch(items)
 	if len(items) == 0 {
-		return nil
+		return result, nil
 	}
 
 	r.mu.Lock()
@@ -93,33 +129,50 @@ func (r *SQLiteInventoryRepository) BatchUpsertRawInventory(ctx context.Context,
 
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO fishit_inventory_raw (key_account_id, roblox_user_id, inventory_json, synced_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO fishit_inventory_raw (key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(roblox_user_id) DO UPDATE SET
 			key_account_id = COALESCE(excluded.key_account_id, key_account_id),
 			inventory_json = excluded.inventory_json,
-			synced_at = excluded.synced_at`)
+			content_hash = excluded.content_hash,
+			synced_at = excluded.synced_at,
+			last_seen_at = MAX(excluded.last_seen_at, COALESCE(last_seen_at, ''))`)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
+	written := make([]model.InventoryItem, 0, len(items))
 	for _, item := range items {
-		_, err := stmt.ExecContext(ctx, item.KeyAccountID, item.RobloxUserID, string(item.RawJSON), item.SyncedAt)
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+			return nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		_, err := stmt.ExecContext(ctx, item.KeyAccountID, item.RobloxUserID, string(item.RawJSON),
+			contentHash(item.ContentHash, item.RawJSON), item.SyncedAt.UTC(), item.SyncedAt.UTC())
 		if err != nil {
-			return fmt.Errorf("failed to batch upsert item %s: %w", item.RobloxUserID, err)
+			log.Printf("[SQLiteInventoryRepository] Rejected batch item %s: %v", item.RobloxUserID, err)
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				return nil, fmt.Errorf("failed to roll back to savepoint: %w", err)
+			}
+			result.Failed = append(result.Failed, model.BatchFailure{RobloxUserID: item.RobloxUserID, Reason: err.Error()})
+			continue
 		}
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_item"); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		written = append(written, item)
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	return nil
+	succeeded(result, written)
+	return result, nil
 }
 
 // GetRawInventory retrieves raw JSON inventory by Roblox user ID.
@@ -140,9 +193,73 @@ func (r *SQLiteInventoryRepository) GetRawInventory(ctx context.Context, robloxU
 		return nil, nil, fmt.Errorf("failed to get raw inventory: %w", err)
 	}
 
+	syncedAt = syncedAt.UTC()
 	return []byte(rawJSON), &syncedAt, nil
 }
 
+// GetInventory retrieves an inventory with its content hash, or nil if not found.
+func (r *SQLiteInventoryRepository) GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	query := `
+		SELECT id, key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at
+		FROM fishit_inventory_raw WHERE roblox_user_id = ?`
+
+	var inv model.RawInventory
+	var rawJSON string
+	var lastSeen sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, robloxUserID).Scan(
+		&inv.ID, &inv.KeyAccountID, &inv.RobloxUserID, &rawJSON, &inv.ContentHash, &inv.SyncedAt, &lastSeen)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	inv.InventoryJSON = []byte(rawJSON)
+	inv.ContentHash = contentHash(inv.ContentHash, inv.InventoryJSON)
+	inv.SyncedAt = inv.SyncedAt.UTC()
+	if lastSeen.Valid {
+		seen := lastSeen.Time.UTC()
+		inv.LastSeenAt = &seen
+	}
+	return &inv, nil
+}
+
+// GetContentHash returns the stored content hash, or "" if the user has no inventory.
+func (r *SQLiteInventoryRepository) GetContentHash(ctx context.Context, robloxUserID string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var hash string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT content_hash FROM fishit_inventory_raw WHERE roblox_user_id = ?`, robloxUserID).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get content hash: %w", err)
+	}
+	return hash, nil
+}
+
+// TouchInventory records that the user's inventory was synced unchanged at seenAt,
+// unless it was seen later already. Reports false if the user has no stored inventory.
+func (r *SQLiteInventoryRepository) TouchInventory(ctx context.Context, robloxUserID string, seenAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE fishit_inventory_raw SET last_seen_at = MAX(?, COALESCE(last_seen_at, '')) WHERE roblox_user_id = ?`, seenAt.UTC(), robloxUserID)
+	if err != nil {
+		return false, fmt.Errorf("failed to touch inventory: %w", err)
+	}
+	touched, err := result.RowsAffected()
+	return touched > 0, err
+}
+
 // GetStats returns statistics about the inventory database.
 func (r *SQLiteInventoryRepository) GetStats(ctx context.Context) (map[string]interface{}, error) {
 	r.mu.RLock()
@@ -157,10 +274,10 @@ func (r *SQLiteInventoryRepository) GetStats(ctx context.Context) (map[string]in
 	}
 	stats["total_inventories"] = count
 
-	// Last sync time
-	var lastSync sql.NullTime
-	if err := r.db.QueryRowContext(ctx, "SELECT MAX(synced_at) FROM fishit_inventory_raw").Scan(&lastSync); err == nil && lastSync.Valid {
-		stats["last_sync"] = lastSync.Time
+	// Last sync time (MAX() would lose the column type the driver parses times by)
+	var lastSync time.Time
+	if err := r.db.QueryRowContext(ctx, "SELECT synced_at FROM fishit_inventory_raw ORDER BY synced_at DESC LIMIT 1").Scan(&lastSync); err == nil {
+		stats["last_sync"] = lastSync.UTC()
 	}
 
 	// Database file size (approximate from page count)
@@ -172,24 +289,19 @@ func (r *SQLiteInventoryRepository) GetStats(ctx context.Context) (map[string]in
 	return stats, nil
 }
 
-// DeleteInactiveUsers deletes inventory records that haven't been synced within the threshold.
+// DeleteInactiveUsers moves inventory records that haven't been synced within
+// the threshold to the tombstones table.
 func (r *SQLiteInventoryRepository) DeleteInactiveUsers(ctx context.Context, threshold time.Duration) (int64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	cutoffTime := time.Now().Add(-threshold)
+	cutoffTime := time.Now().Add(-threshold).UTC()
 	
-	query := `DELETE FROM fishit_inventory_raw WHERE synced_at < ?`
-	result, err := r.db.ExecContext(ctx, query, cutoffTime)
+	deleted, err := r.softDelete(ctx, `COALESCE(last_seen_at, synced_at) < ?`, cutoffTime)
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete inactive users: %w", err)
 	}
 	
-	deleted, err := result.RowsAffected()
-	if err != nil {
-		return 0, err
-	}
-	
 	if deleted > 0 {
 		log.Printf("[SQLite] Cleaned up %d inactive inventory records (threshold: %v)", deleted, threshold)
 	}
@@ -204,3 +316,6 @@ func (r *SQLiteInventoryRepository) Close() error {
 
 // Ensure SQLiteInventoryRepository implements InventoryRepository
 var _ InventoryRepository = (*SQLiteInventoryRepository)(nil)
+
+// Ensure SQLiteInventoryRepository implements InventoryLastSeenRepository
+var _ InventoryLastSeenRepository = (*SQLiteInventoryRepository)(nil)
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 
 	"vinzhub-rest-api-v2/internal/model"
 )
@@ -132,3 +133,75 @@ func (r *MySQLKeyAccountRepository) ValidateKeyAndHWID(ctx context.Context, key,
 
 // Ensure MySQLKeyAccountRepository implements KeyAccountRepository
 var _ KeyAccountRepository = (*MySQLKeyAccountRepository)(nil)
+
+// GetKeyTiers returns the tier of the key behind each key account, read from
+// the optional `keys`.`tier` column. Accounts whose key has no tier are left out.
+func (r *MySQLKeyAccountRepository) GetKeyTiers(ctx context.Context, keyAccountIDs []int64) (map[int64]string, error) {
+	tiers := make(map[int64]string, len(keyAccountIDs))
+	if len(keyAccountIDs) == 0 {
+		return tiers, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keyAccountIDs)), ",")
+	args := make([]interface{}, len(keyAccountIDs))
+	for i, id := range keyAccountIDs {
+		args[i] = id
+	}
+	query := "SELECT ka.id, COALESCE(k.tier, '') FROM key_accounts ka JOIN `keys` k ON k.id = ka.key_id WHERE ka.id IN (" + placeholders + ")"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key tiers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var tier string
+		if err := rows.Scan(&id, &tier); err != nil {
+			return nil, fmt.Errorf("failed to scan key tier: %w", err)
+		}
+		if tier != "" {
+			tiers[id] = tier
+		}
+	}
+	return tiers, rows.Err()
+}
+
+// Ensure MySQLKeyAccountRepository implements KeyTierRepository
+var _ KeyTierRepository = (*MySQLKeyAccountRepository)(nil)
+
+// ListKeyAccounts returns all of the user's key accounts, active or not.
+func (r *MySQLKeyAccountRepository) ListKeyAccounts(ctx context.Context, robloxUserID string) ([]model.KeyAccount, error) {
+	query := `
+		SELECT id, key_id, roblox_user_id, COALESCE(roblox_username, ''), COALESCE(hwid, ''),
+			is_active, first_used_at, last_used_at
+		FROM key_accounts WHERE roblox_user_id = ? ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query, robloxUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []model.KeyAccount{}
+	for rows.Next() {
+		var a model.KeyAccount
+		var firstUsed, lastUsed sql.NullTime
+		if err := rows.Scan(&a.ID, &a.KeyID, &a.RobloxUserID, &a.RobloxUsername, &a.HWID,
+			&a.IsActive, &firstUsed, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan key account: %w", err)
+		}
+		if firstUsed.Valid {
+			a.FirstUsedAt = &firstUsed.Time
+		}
+		if lastUsed.Valid {
+			a.LastUsedAt = &lastUsed.Time
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// Ensure MySQLKeyAccountRepository implements KeyAccountLister
+var _ KeyAccountLister = (*MySQLKeyAccountRepository)(nil)
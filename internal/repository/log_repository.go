@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"vinzhub-rest-api-v2/internal/model"
@@ -18,10 +19,59 @@ type LogRepository interface {
 	Close() error
 }
 
+// InventoryEventLog is an optional LogRepository extension that stores
+// inventory change events.
+type InventoryEventLog interface {
+	// InsertInventoryEvents stores events.
+	InsertInventoryEvents(ctx context.Context, events []model.InventoryEvent) error
+
+	// ListInventoryEvents returns the events matching q, newest first, and the
+	// cursor of the next page ("" on the last page).
+	// An unparseable q.Cursor yields ErrInvalidCursor.
+	ListInventoryEvents(ctx context.Context, q model.InventoryEventQuery) ([]model.InventoryEvent, string, error)
+}
+
+// TradeLog is an optional LogRepository extension that stores item
+// transfers between players, detected from inventory events.
+type TradeLog interface {
+	// FindTransferCounterpart returns the most recent event since the given
+	// time that completes a transfer with event: for an acquisition, a removal
+	// of the same uuid by another player, and the reverse for a removal.
+	// Events already part of a transfer are skipped. Returns nil if none.
+	FindTransferCounterpart(ctx context.Context, event *model.InventoryEvent, since time.Time) (*model.InventoryEvent, error)
+
+	// InsertItemTransfer stores a transfer. Reports false, without error, if
+	// either of its events is already part of a stored transfer.
+	InsertItemTransfer(ctx context.Context, transfer *model.ItemTransfer) (bool, error)
+
+	// ListItemTransfers returns the transfers matching q, newest first, and
+	// the cursor of the next page ("" on the last page).
+	ListItemTransfers(ctx context.Context, q model.ItemTransferQuery) ([]model.ItemTransfer, string, error)
+}
+
+// UserLogStore is an optional LogRepository extension for data requests.
+// Obfuscation logs are matched by their user_id, which holds the key account
+// of the request; logs of anonymous requests cannot be attributed to anyone.
+type UserLogStore interface {
+	// ListObfuscationLogsByUser returns the obfuscation logs of the given key
+	// accounts, newest first.
+	ListObfuscationLogsByUser(ctx context.Context, keyAccountIDs []int64) ([]model.ObfuscationLog, error)
+
+	// EraseUserLogs deletes the obfuscation logs of the given key accounts, and
+	// the inventory events and item transfers of robloxUserID. It reports what
+	// was deleted even when it fails part way.
+	EraseUserLogs(ctx context.Context, robloxUserID string, keyAccountIDs []int64) (*model.UserLogErasure, error)
+}
+
+// ErrInvalidCursor is returned for a pagination cursor the repository did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // MongoDBLogRepository implements LogRepository for MongoDB
 type MongoDBLogRepository struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	events     *mongo.Collection
+	transfers  *mongo.Collection
 }
 
 // NewMongoDBLogRepository creates a new MongoDB log repository
@@ -40,10 +90,14 @@ func NewMongoDBLogRepository(uri, dbName, collectionName string) (*MongoDBLogRep
 	}
 
 	collection := client.Database(dbName).Collection(collectionName)
+	events := client.Database(dbName).Collection("inventory_events")
+	transfers := client.Database(dbName).Collection("item_transfers")
 
 	return &MongoDBLogRepository{
 		client:     client,
 		collection: collection,
+		events:     events,
+		transfers:  transfers,
 	}, nil
 }
 
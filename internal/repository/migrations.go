@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/migrate"
+)
+
+// migrationFiles holds the schema migrations of each backend, under
+// migrations/<backend>/.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrateTimeout bounds the migrations run when a repository is opened,
+// including the wait for another instance's lock.
+const migrateTimeout = 2 * time.Minute
+
+// loadMigrations returns the migrations of a backend.
+func loadMigrations(backend string) ([]migrate.Migration, error) {
+	dir, err := fs.Sub(migrationFiles, "migrations/"+backend)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Load(dir)
+}
+
+// newSQLMigrator creates the migrator of a SQLite or PostgreSQL database.
+func newSQLMigrator(db *sql.DB, backend string, dialect migrate.Dialect, label string) (*migrate.Migrator, error) {
+	migrations, err := loadMigrations(backend)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(migrate.NewSQLDriver(db, dialect), migrations, label), nil
+}
+
+// migrateUp applies the pending migrations when a repository is opened.
+func migrateUp(migrator *migrate.Migrator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	if _, err := migrator.Up(ctx); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return nil
+}
+
+// NewSQLiteMigrator opens the SQLite database at dbPath for the migrate
+// command. The returned function closes it.
+func NewSQLiteMigrator(dbPath string) (*migrate.Migrator, func() error, error) {
+	db, err := openSQLite(dbPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := newSQLMigrator(db, "sqlite", migrate.SQLite, dbPath)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return migrator, db.Close, nil
+}
+
+// NewPostgresMigrator opens a PostgreSQL database for the migrate command.
+// The returned function closes it.
+func NewPostgresMigrator(dsn, schema string) (*migrate.Migrator, func() error, error) {
+	db, err := openPostgres(dsn, schema)
+	if err != nil {
+		return nil, nil, err
+	}
+	migrator, err := newSQLMigrator(db, "postgres", migrate.Postgres, postgresLabel(schema))
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return migrator, db.Close, nil
+}
+
+// postgresLabel names a PostgreSQL schema in migration logs.
+func postgresLabel(schema string) string {
+	if schema == "" {
+		return "postgres"
+	}
+	return "postgres schema " + schema
+}
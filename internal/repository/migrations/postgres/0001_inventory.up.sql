@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_raw (
+	id BIGSERIAL PRIMARY KEY,
+	key_account_id BIGINT DEFAULT 0,
+	roblox_user_id TEXT NOT NULL UNIQUE,
+	inventory_json JSONB NOT NULL,
+	content_hash TEXT NOT NULL DEFAULT '',
+	synced_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_seen_at TIMESTAMPTZ
+);
+-- Tables created before these columns existed
+ALTER TABLE fishit_inventory_raw ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE fishit_inventory_raw ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_inventory_roblox_user ON fishit_inventory_raw(roblox_user_id);
+CREATE INDEX IF NOT EXISTS idx_inventory_synced_at ON fishit_inventory_raw(synced_at);
+CREATE INDEX IF NOT EXISTS idx_inventory_key_account ON fishit_inventory_raw(key_account_id);
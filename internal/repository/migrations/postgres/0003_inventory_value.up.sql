@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_value (
+	id BIGSERIAL PRIMARY KEY,
+	roblox_user_id TEXT NOT NULL,
+	total DOUBLE PRECISION NOT NULL,
+	item_count INTEGER NOT NULL DEFAULT 0,
+	recorded_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_value_user_recorded ON fishit_inventory_value(roblox_user_id, recorded_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_tombstones (
+	id BIGSERIAL PRIMARY KEY,
+	key_account_id BIGINT DEFAULT 0,
+	roblox_user_id TEXT NOT NULL,
+	inventory_json JSONB NOT NULL,
+	content_hash TEXT NOT NULL DEFAULT '',
+	synced_at TIMESTAMPTZ NOT NULL,
+	last_seen_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tombstones_user ON fishit_inventory_tombstones(roblox_user_id, deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tombstones_deleted_at ON fishit_inventory_tombstones(deleted_at);
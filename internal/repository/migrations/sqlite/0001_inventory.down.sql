@@ -0,0 +1 @@
+DROP TABLE IF EXISTS fishit_inventory_raw;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_raw (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	key_account_id INTEGER DEFAULT 0,
+	roblox_user_id TEXT NOT NULL UNIQUE,
+	inventory_json TEXT NOT NULL,
+	content_hash TEXT NOT NULL DEFAULT '',
+	synced_at DATETIME NOT NULL,
+	last_seen_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_roblox_user ON fishit_inventory_raw(roblox_user_id);
+CREATE INDEX IF NOT EXISTS idx_synced_at ON fishit_inventory_raw(synced_at);
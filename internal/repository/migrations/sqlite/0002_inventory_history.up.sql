@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	roblox_user_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	key_account_id INTEGER DEFAULT 0,
+	content_hash TEXT NOT NULL,
+	inventory_json TEXT NOT NULL,
+	captured_at DATETIME NOT NULL,
+	UNIQUE(roblox_user_id, version)
+);
+CREATE INDEX IF NOT EXISTS idx_history_captured_at ON fishit_inventory_history(captured_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_value (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	roblox_user_id TEXT NOT NULL,
+	total REAL NOT NULL,
+	item_count INTEGER NOT NULL DEFAULT 0,
+	recorded_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_value_user_recorded ON fishit_inventory_value(roblox_user_id, recorded_at);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS fishit_inventory_tombstones (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	key_account_id INTEGER DEFAULT 0,
+	roblox_user_id TEXT NOT NULL,
+	inventory_json TEXT NOT NULL,
+	content_hash TEXT NOT NULL DEFAULT '',
+	synced_at DATETIME NOT NULL,
+	last_seen_at DATETIME,
+	deleted_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tombstones_user ON fishit_inventory_tombstones(roblox_user_id, deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tombstones_deleted_at ON fishit_inventory_tombstones(deleted_at);
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoMigrationLockTTL is how long a migration lock is honoured; a lock
+// older than that was left by a process that died and is taken over.
+const mongoMigrationLockTTL = 5 * time.Minute
+
+// mongoMigrationLockID is the _id of the lock document in the migrations collection.
+const mongoMigrationLockID = "lock"
+
+// mongoIndexMigration is the content of a MongoDB migration file. Collection
+// names may use {collection} for the inventory collection.
+type mongoIndexMigration struct {
+	CreateIndexes []struct {
+		Collection string `bson:"collection"`
+		Keys       bson.D `bson:"keys"`
+		Unique     bool   `bson:"unique"`
+	} `bson:"create_indexes"`
+	DropIndexes []struct {
+		Collection string `bson:"collection"`
+		Name       string `bson:"name"`
+	} `bson:"drop_indexes"`
+}
+
+// mongoMigrationRecord is an applied migration in <collection>_migrations.
+type mongoMigrationRecord struct {
+	Version   int64     `bson:"_id"`
+	Name      string    `bson:"name"`
+	Checksum  string    `bson:"checksum"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// mongoMigrationDriver applies index migrations to an inventory collection and
+// its companions, recording them in <collection>_migrations.
+type mongoMigrationDriver struct {
+	db         *mongo.Database
+	collection string
+	records    *mongo.Collection
+}
+
+func newMongoMigrator(db *mongo.Database, collection string) (*migrate.Migrator, error) {
+	migrations, err := loadMigrations("mongodb")
+	if err != nil {
+		return nil, err
+	}
+	driver := &mongoMigrationDriver{
+		db:         db,
+		collection: collection,
+		records:    db.Collection(collection + "_migrations"),
+	}
+	return migrate.New(driver, migrations, "mongodb "+db.Name()+"/"+collection), nil
+}
+
+// NewMongoDBMigrator connects to MongoDB for the migrate command. The
+// returned function disconnects.
+func NewMongoDBMigrator(uri, database, collection string) (*migrate.Migrator, func() error, error) {
+	client, err := connectMongo(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	disconnect := func() error { return client.Disconnect(context.Background()) }
+
+	migrator, err := newMongoMigrator(client.Database(database), collection)
+	if err != nil {
+		disconnect()
+		return nil, nil, err
+	}
+	return migrator, disconnect, nil
+}
+
+// Lock inserts the lock document, waiting while another process holds it.
+func (d *mongoMigrationDriver) Lock(ctx context.Context) (func(), error) {
+	for {
+		_, err := d.records.InsertOne(ctx, bson.M{"_id": mongoMigrationLockID, "locked_at": time.Now()})
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to lock migrations: %w", err)
+		}
+
+		stale := bson.M{"_id": mongoMigrationLockID, "locked_at": bson.M{"$lt": time.Now().Add(-mongoMigrationLockTTL)}}
+		if _, err := d.records.DeleteOne(ctx, stale); err != nil {
+			return nil, fmt.Errorf("failed to lock migrations: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock migrations: %w", ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+
+	return func() {
+		// A fresh context, so a cancelled run still unlocks
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		d.records.DeleteOne(ctx, bson.M{"_id": mongoMigrationLockID})
+	}, nil
+}
+
+// Applied returns the recorded migrations in version order.
+func (d *mongoMigrationDriver) Applied(ctx context.Context) ([]migrate.Record, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := d.records.Find(ctx, bson.M{"_id": bson.M{"$ne": mongoMigrationLockID}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []mongoMigrationRecord
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode migrations: %w", err)
+	}
+	records := make([]migrate.Record, len(docs))
+	for i, doc := range docs {
+		records[i] = migrate.Record(doc)
+	}
+	return records, nil
+}
+
+// Apply creates and drops the migration's indexes, then records the change.
+// Both are idempotent, so a run interrupted in between can be repeated.
+func (d *mongoMigrationDriver) Apply(ctx context.Context, m migrate.Migration, up bool) error {
+	content := m.Up
+	if !up {
+		content = m.Down
+	}
+	var spec mongoIndexMigration
+	if err := bson.UnmarshalExtJSON([]byte(content), false, &spec); err != nil {
+		return fmt.Errorf("invalid migration file: %w", err)
+	}
+
+	for _, idx := range spec.CreateIndexes {
+		coll := d.db.Collection(d.collectionName(idx.Collection))
+		model := mongo.IndexModel{Keys: idx.Keys}
+		if idx.Unique {
+			model.Options = options.Index().SetUnique(true)
+		}
+		if _, err := coll.Indexes().CreateOne(ctx, model); err != nil {
+			return fmt.Errorf("failed to create index on %s: %w", coll.Name(), err)
+		}
+	}
+	for _, idx := range spec.DropIndexes {
+		coll := d.db.Collection(d.collectionName(idx.Collection))
+		if _, err := coll.Indexes().DropOne(ctx, idx.Name); err != nil && !isMongoNotFound(err) {
+			return fmt.Errorf("failed to drop index %s on %s: %w", idx.Name, coll.Name(), err)
+		}
+	}
+
+	if !up {
+		if _, err := d.records.DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+			return fmt.Errorf("failed to record migration: %w", err)
+		}
+		return nil
+	}
+	record := mongoMigrationRecord{Version: m.Version, Name: m.Name, Checksum: m.Checksum, AppliedAt: time.Now().UTC()}
+	_, err := d.records.ReplaceOne(ctx, bson.M{"_id": m.Version}, record, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return nil
+}
+
+func (d *mongoMigrationDriver) collectionName(name string) string {
+	return strings.ReplaceAll(name, "{collection}", d.collection)
+}
+
+// isMongoNotFound reports whether err is MongoDB's IndexNotFound or
+// NamespaceNotFound, which dropping an index that is already gone returns.
+func isMongoNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Code == 26)
+}
+
+// Ensure mongoMigrationDriver implements migrate.Driver
+var _ migrate.Driver = (*mongoMigrationDriver)(nil)
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetKeepOriginalJSON makes writes store the JSON exactly as synced, gzipped,
+// next to the queryable BSON document, and reads return it instead of
+// re-encoding the BSON. When off, writes drop any kept bytes, so a read never
+// returns JSON older than its document.
+func (r *MongoDBInventoryRepository) SetKeepOriginalJSON(enabled bool) {
+	r.keepOriginal = enabled
+}
+
+// setOriginalJSON adds the synced bytes to update, an update of
+// inventory_json with a $set, or removes kept bytes if they are not kept.
+func (r *MongoDBInventoryRepository) setOriginalJSON(update bson.M, rawJSON []byte) error {
+	if !r.keepOriginal {
+		update["$unset"] = bson.M{"inventory_original": ""}
+		return nil
+	}
+	compressed, err := gzipJSON(rawJSON)
+	if err != nil {
+		return fmt.Errorf("failed to compress inventory JSON: %w", err)
+	}
+	update["$set"].(bson.M)["inventory_original"] = compressed
+	return nil
+}
+
+// rawJSON returns the inventory as JSON: the bytes as synced if they were
+// kept, otherwise the BSON document re-encoded.
+func (d *InventoryDocument) rawJSON() ([]byte, error) {
+	if d.InventoryOriginal != nil {
+		return gunzipJSON(d.InventoryOriginal)
+	}
+	return bsonToJSON(d.InventoryJSON)
+}
+
+func gzipJSON(rawJSON []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(rawJSON); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipJSON(compressed []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original JSON: %w", err)
+	}
+	defer gz.Close()
+	rawJSON, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read original JSON: %w", err)
+	}
+	return rawJSON, nil
+}
+
+// BackfillOriginalJSON stores JSON for the inventories and tombstones written
+// without their original bytes, so reads of them stop depending on how BSON
+// re-encodes. The bytes first synced are gone: documents keep their JSON as
+// it reads today. Returns how many documents were filled.
+func (r *MongoDBInventoryRepository) BackfillOriginalJSON(ctx context.Context) (int64, error) {
+	var filled int64
+	for _, coll := range []*mongo.Collection{r.collection, r.tombstones} {
+		n, err := backfillOriginalJSON(ctx, coll)
+		filled += n
+		if err != nil {
+			return filled, fmt.Errorf("failed to backfill %s: %w", coll.Name(), err)
+		}
+		if n > 0 {
+			log.Printf("[MongoDB] Backfilled original JSON of %d documents in %s", n, coll.Name())
+		}
+	}
+	return filled, nil
+}
+
+func backfillOriginalJSON(ctx context.Context, coll *mongo.Collection) (int64, error) {
+	missing := bson.M{"inventory_original": bson.M{"$exists": false}}
+	opts := options.Find().
+		SetProjection(bson.M{"roblox_user_id": 1, "inventory_json": 1, "synced_at": 1}).
+		SetBatchSize(500)
+	cursor, err := coll.Find(ctx, missing, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var filled int64
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID                primitive.ObjectID `bson:"_id"`
+			InventoryDocument `bson:",inline"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return filled, fmt.Errorf("failed to decode inventory: %w", err)
+		}
+		jsonBytes, err := bsonToJSON(doc.InventoryJSON)
+		if err != nil {
+			return filled, fmt.Errorf("failed to marshal inventory for %s: %w", doc.RobloxUserID, err)
+		}
+		compressed, err := gzipJSON(jsonBytes)
+		if err != nil {
+			return filled, fmt.Errorf("failed to compress inventory for %s: %w", doc.RobloxUserID, err)
+		}
+
+		// Matching synced_at skips documents rewritten since they were read
+		match := bson.M{"_id": doc.ID, "synced_at": doc.SyncedAt, "inventory_original": bson.M{"$exists": false}}
+		result, err := coll.UpdateOne(ctx, match, bson.M{"$set": bson.M{"inventory_original": compressed}})
+		if err != nil {
+			return filled, fmt.Errorf("failed to store original JSON for %s: %w", doc.RobloxUserID, err)
+		}
+		filled += result.ModifiedCount
+	}
+	return filled, cursor.Err()
+}
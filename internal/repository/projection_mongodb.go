@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetInventoryFields returns the user's inventory with only the given
+// top-level fields, selected with a find projection. ContentHash is the
+// stored hash as is, so it is empty for documents written before hashes
+// were stored.
+func (r *MongoDBInventoryRepository) GetInventoryFields(ctx context.Context, robloxUserID string, fields []string) (*model.RawInventory, error) {
+	projection := bson.D{
+		{Key: "roblox_user_id", Value: 1},
+		{Key: "key_account_id", Value: 1},
+		{Key: "content_hash", Value: 1},
+		{Key: "synced_at", Value: 1},
+		{Key: "last_seen_at", Value: 1},
+	}
+	for _, field := range fields {
+		projection = append(projection, bson.E{Key: "inventory_json." + field, Value: 1})
+	}
+
+	var doc InventoryDocument
+	err := r.collection.FindOne(ctx, bson.M{"roblox_user_id": robloxUserID},
+		options.FindOne().SetProjection(projection)).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory fields: %w", err)
+	}
+
+	jsonBytes := []byte("{}")
+	if doc.InventoryJSON != nil {
+		if jsonBytes, err = bsonToJSON(doc.InventoryJSON); err != nil {
+			return nil, fmt.Errorf("failed to marshal inventory to JSON: %w", err)
+		}
+	}
+
+	return &model.RawInventory{
+		KeyAccountID:  doc.KeyAccountID,
+		RobloxUserID:  doc.RobloxUserID,
+		InventoryJSON: jsonBytes,
+		ContentHash:   doc.ContentHash,
+		SyncedAt:      doc.SyncedAt,
+		LastSeenAt:    doc.LastSeenAt,
+	}, nil
+}
+
+// GetCategoryItems filters, sorts and pages one category's items in an
+// aggregation that unwinds the category array.
+func (r *MongoDBInventoryRepository) GetCategoryItems(ctx context.Context, robloxUserID string, q model.CategoryQuery) (*model.CategoryItems, error) {
+	var meta struct {
+		ContentHash string    `bson:"content_hash"`
+		SyncedAt    time.Time `bson:"synced_at"`
+	}
+	err := r.collection.FindOne(ctx, bson.M{"roblox_user_id": robloxUserID},
+		options.FindOne().SetProjection(bson.M{"content_hash": 1, "synced_at": 1})).Decode(&meta)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	filter := bson.D{}
+	if q.Tiers.Active() {
+		numbers, names := q.Tiers.Values()
+		values := bson.A{}
+		for _, n := range numbers {
+			values = append(values, n)
+		}
+		for _, name := range names {
+			values = append(values, name)
+		}
+		filter = append(filter, bson.E{Key: "items.tier", Value: bson.D{{Key: "$in", Value: values}}})
+	}
+	if q.Favorited != nil {
+		filter = append(filter, mongoBoolFilter("items.favorited", *q.Favorited))
+	}
+	if q.Shiny != nil {
+		filter = append(filter, mongoBoolFilter("items.is_shiny", *q.Shiny))
+	}
+
+	path := "$inventory_json." + q.Category
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "roblox_user_id", Value: robloxUserID}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "items", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$isArray", Value: path}}, path, bson.A{},
+			}}}},
+		}}},
+		{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$items"}, {Key: "includeArrayIndex", Value: "idx"}}}},
+		{{Key: "$match", Value: filter}},
+	}
+
+	sortStage := bson.D{{Key: "idx", Value: 1}}
+	if q.Sort != "" {
+		direction := 1
+		if q.Desc {
+			direction = -1
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.D{{Key: "sort_key", Value: mongoItemSortKey(q.Sort)}}}})
+		sortStage = bson.D{{Key: "sort_key", Value: direction}, {Key: "idx", Value: 1}}
+	}
+	page := bson.A{
+		bson.D{{Key: "$sort", Value: sortStage}},
+		bson.D{{Key: "$skip", Value: q.Offset}},
+	}
+	if q.Limit > 0 {
+		page = append(page, bson.D{{Key: "$limit", Value: q.Limit}})
+	}
+	page = append(page, bson.D{{Key: "$project", Value: bson.D{{Key: "items", Value: 1}}}})
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "n"}}}},
+		{Key: "items", Value: page},
+	}}})
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category items: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+		Items []struct {
+			Item interface{} `bson:"items"`
+		} `bson:"items"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to decode category items: %w", err)
+	}
+
+	result := &model.CategoryItems{
+		Items:       []json.RawMessage{},
+		ContentHash: meta.ContentHash,
+		SyncedAt:    meta.SyncedAt,
+	}
+	if len(facets) > 0 {
+		if len(facets[0].Total) > 0 {
+			result.Total = facets[0].Total[0].N
+		}
+		for _, item := range facets[0].Items {
+			jsonBytes, err := bsonToJSON(item.Item)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal item to JSON: %w", err)
+			}
+			result.Items = append(result.Items, jsonBytes)
+		}
+	}
+	return result, nil
+}
+
+// mongoBoolFilter matches items whose boolean field is (or, for false, is not) true.
+func mongoBoolFilter(field string, value bool) bson.E {
+	if value {
+		return bson.E{Key: field, Value: true}
+	}
+	return bson.E{Key: field, Value: bson.D{{Key: "$ne", Value: true}}}
+}
+
+// mongoItemSortKey returns the aggregation expression for a model.ItemSort
+// key, with the same defaults for missing values as InventoryEntry.
+func mongoItemSortKey(key string) interface{} {
+	switch key {
+	case model.ItemSortTier:
+		// Every stored representation of a known tier maps to its number
+		branches := bson.A{}
+		for t := model.Tier(1); t <= model.MaxTier; t++ {
+			values := bson.A{int(t), strconv.Itoa(int(t))}
+			for name, tier := range model.TierNames {
+				if tier == t {
+					values = append(values, name)
+				}
+			}
+			branches = append(branches, bson.D{
+				{Key: "case", Value: bson.D{{Key: "$in", Value: bson.A{"$items.tier", values}}}},
+				{Key: "then", Value: int(t)},
+			})
+		}
+		return bson.D{{Key: "$switch", Value: bson.D{{Key: "branches", Value: branches}, {Key: "default", Value: 0}}}}
+	case model.ItemSortName:
+		return bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$type", Value: "$items.name"}}, "string"}}}, "$items.name", "",
+		}}}
+	case model.ItemSortPrice:
+		return bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$isNumber", Value: "$items.price"}}, "$items.price", 0,
+		}}}
+	default:
+		return bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$and", Value: bson.A{
+				bson.D{{Key: "$isNumber", Value: "$items.quantity"}},
+				bson.D{{Key: "$gt", Value: bson.A{"$items.quantity", 0}}},
+			}}}, "$items.quantity", 1,
+		}}}
+	}
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryProjectionRepository
+var _ InventoryProjectionRepository = (*MongoDBInventoryRepository)(nil)
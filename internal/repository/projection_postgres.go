@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"github.com/lib/pq"
+)
+
+// GetInventoryFields returns the user's inventory with only the given
+// top-level fields, picked out with jsonb_each. ContentHash is the stored
+// hash as is, so it is empty for rows written before hashes were stored.
+func (r *PostgresInventoryRepository) GetInventoryFields(ctx context.Context, robloxUserID string, fields []string) (*model.RawInventory, error) {
+	query := `
+		SELECT id, key_account_id, roblox_user_id,
+			COALESCE((SELECT jsonb_object_agg(key, value) FROM jsonb_each(inventory_json) WHERE key = ANY($2)), '{}'::jsonb),
+			content_hash, synced_at, last_seen_at
+		FROM fishit_inventory_raw WHERE roblox_user_id = $1`
+
+	var inv model.RawInventory
+	var lastSeen sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, robloxUserID, pq.Array(fields)).Scan(
+		&inv.ID, &inv.KeyAccountID, &inv.RobloxUserID, &inv.InventoryJSON, &inv.ContentHash, &inv.SyncedAt, &lastSeen)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get inventory fields: %w", err)
+	}
+	if lastSeen.Valid {
+		inv.LastSeenAt = &lastSeen.Time
+	}
+	return &inv, nil
+}
+
+// GetCategoryItems filters, sorts and pages one category's items in SQL by
+// expanding the array with jsonb_array_elements WITH ORDINALITY.
+func (r *PostgresInventoryRepository) GetCategoryItems(ctx context.Context, robloxUserID string, q model.CategoryQuery) (*model.CategoryItems, error) {
+	args := []interface{}{robloxUserID, q.Category}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var conds []string
+	if q.Tiers.Active() {
+		numbers, names := q.Tiers.Values()
+		values := make([]interface{}, 0, len(numbers)+len(names))
+		for _, n := range numbers {
+			values = append(values, n)
+		}
+		for _, name := range names {
+			values = append(values, name)
+		}
+		tiers, _ := json.Marshal(values)
+		conds = append(conds, param(string(tiers))+"::jsonb @> jsonb_build_array(item->'tier')")
+	}
+	if q.Favorited != nil {
+		conds = append(conds, pgBoolCondition("favorited", *q.Favorited))
+	}
+	if q.Shiny != nil {
+		conds = append(conds, pgBoolCondition("is_shiny", *q.Shiny))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	order := "idx"
+	if q.Sort != "" {
+		direction := "ASC"
+		if q.Desc {
+			direction = "DESC"
+		}
+		order = pgItemSortKey(q.Sort) + " " + direction + ", idx"
+	}
+	limit := "ALL"
+	if q.Limit > 0 {
+		limit = param(q.Limit)
+	}
+	offset := param(q.Offset)
+
+	query := `
+		WITH items AS (
+			SELECT item, idx FROM (
+				SELECT i.item, i.idx
+				FROM fishit_inventory_raw r,
+					jsonb_array_elements(CASE WHEN jsonb_typeof(r.inventory_json->$2::text) = 'array'
+						THEN r.inventory_json->$2::text ELSE '[]'::jsonb END) WITH ORDINALITY AS i(item, idx)
+				WHERE r.roblox_user_id = $1
+			) expanded ` + where + `
+		)
+		SELECT r.content_hash, r.synced_at,
+			(SELECT COUNT(*) FROM items),
+			COALESCE((SELECT jsonb_agg(item ORDER BY ` + order + `) FROM (
+				SELECT item, idx FROM items ORDER BY ` + order + ` LIMIT ` + limit + ` OFFSET ` + offset + `
+			) page), '[]'::jsonb)
+		FROM fishit_inventory_raw r WHERE r.roblox_user_id = $1`
+
+	var page model.CategoryItems
+	var items []byte
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&page.ContentHash, &page.SyncedAt, &page.Total, &items)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get category items: %w", err)
+	}
+	if err := json.Unmarshal(items, &page.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode category items: %w", err)
+	}
+	return &page, nil
+}
+
+// pgBoolCondition matches items whose boolean field is (or, for false, is not) true.
+func pgBoolCondition(field string, value bool) string {
+	if value {
+		return "item->'" + field + "' = 'true'::jsonb"
+	}
+	return "COALESCE(item->'" + field + "', 'false'::jsonb) <> 'true'::jsonb"
+}
+
+// pgItemSortKey returns the SQL sort expression for a model.ItemSort key,
+// with the same defaults for missing values as InventoryEntry.
+func pgItemSortKey(key string) string {
+	switch key {
+	case model.ItemSortTier:
+		return pgTierSortKey
+	case model.ItemSortName:
+		return `(CASE WHEN jsonb_typeof(item->'name') = 'string' THEN item->>'name' ELSE '' END) COLLATE "C"`
+	case model.ItemSortPrice:
+		return `(CASE WHEN jsonb_typeof(item->'price') = 'number' THEN (item->>'price')::numeric ELSE 0 END)`
+	default:
+		return `(CASE WHEN jsonb_typeof(item->'quantity') = 'number' AND (item->>'quantity')::numeric > 0
+			THEN (item->>'quantity')::numeric ELSE 1 END)`
+	}
+}
+
+// pgTierSortKey maps every stored representation of a known tier (number,
+// numeric string or name) to its number; anything else sorts as 0.
+var pgTierSortKey = func() string {
+	var whens []string
+	for t := model.Tier(1); t <= model.MaxTier; t++ {
+		whens = append(whens, fmt.Sprintf("WHEN '%d'::jsonb THEN %d WHEN '\"%d\"'::jsonb THEN %d", t, t, t, t))
+	}
+	names := make([]string, 0, len(model.TierNames))
+	for name := range model.TierNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		whens = append(whens, fmt.Sprintf("WHEN '\"%s\"'::jsonb THEN %d", name, model.TierNames[name]))
+	}
+	return "(CASE item->'tier' " + strings.Join(whens, " ") + " ELSE 0 END)"
+}()
+
+// Ensure PostgresInventoryRepository implements InventoryProjectionRepository
+var _ InventoryProjectionRepository = (*PostgresInventoryRepository)(nil)
@@ -0,0 +1,869 @@
+// Package repotest is a conformance suite for InventoryRepository
+// implementations. Every backend runs it, so the service layer sees the same
+// behaviour whichever database is configured.
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/canonjson"
+)
+
+// timePrecision is the coarsest timestamp precision a backend may store
+// (MongoDB keeps milliseconds).
+const timePrecision = time.Millisecond
+
+// Backend describes an inventory repository under test.
+type Backend struct {
+	// Open returns a new, empty repository. The suite closes it.
+	Open func(t *testing.T) repository.InventoryRepository
+
+	// ExactJSON reports that inventories are returned byte for byte as
+	// written. Other backends must return equivalent JSON: the same values,
+	// numbers compared exactly, keys in any order.
+	ExactJSON bool
+
+	// RejectUsers, if set, makes the database refuse from then on to write
+	// the inventories of the given users, as it would rows it cannot store.
+	// Cases that need it are skipped for backends without one.
+	RejectUsers func(t *testing.T, repo repository.InventoryRepository, robloxUserIDs ...string)
+}
+
+// TestInventoryRepository runs the conformance suite against b.
+func TestInventoryRepository(t *testing.T, b Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b Backend, repo repository.InventoryRepository)
+	}{
+		{"Missing", testMissing},
+		{"Upsert", testUpsert},
+		{"BatchUpsert", testBatchUpsert},
+		{"BatchPartialFailure", testBatchPartialFailure},
+		{"BatchRejectedRows", testBatchRejectedRows},
+		{"LastSeen", testLastSeen},
+		{"ReplaceIfHash", testReplaceIfHash},
+		{"JSONFidelity", testJSONFidelity},
+		{"Stats", testStats},
+		{"DeleteInactiveUsers", testDeleteInactiveUsers},
+		{"Concurrent", testConcurrent},
+		{"ConcurrentSnapshots", testConcurrentSnapshots},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := b.Open(t)
+			t.Cleanup(func() {
+				if err := repo.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			tt.fn(t, b, repo)
+		})
+	}
+}
+
+// Zones away from UTC, so backends that store or compare local times as text
+// sort them wrongly.
+var (
+	east = time.FixedZone("UTC+7", 7*60*60)
+	west = time.FixedZone("UTC-7", -7*60*60)
+)
+
+func testMissing(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+
+	raw, syncedAt, err := repo.GetRawInventory(ctx, "missing")
+	if err != nil || raw != nil || syncedAt != nil {
+		t.Errorf("GetRawInventory = %q, %v, %v; want nil, nil, nil", raw, syncedAt, err)
+	}
+	inv, err := repo.GetInventory(ctx, "missing")
+	if err != nil || inv != nil {
+		t.Errorf("GetInventory = %+v, %v; want nil, nil", inv, err)
+	}
+
+	if lastSeen, ok := repo.(repository.InventoryLastSeenRepository); ok {
+		hash, err := lastSeen.GetContentHash(ctx, "missing")
+		if err != nil || hash != "" {
+			t.Errorf("GetContentHash = %q, %v; want \"\", nil", hash, err)
+		}
+		touched, err := lastSeen.TouchInventory(ctx, "missing", time.Now())
+		if err != nil || touched {
+			t.Errorf("TouchInventory = %v, %v; want false, nil", touched, err)
+		}
+	}
+
+	deleted, err := repo.DeleteInactiveUsers(ctx, time.Hour)
+	if err != nil || deleted != 0 {
+		t.Errorf("DeleteInactiveUsers on empty repository = %d, %v; want 0, nil", deleted, err)
+	}
+}
+
+func testUpsert(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	first := []byte(`{"coins":100,"fish":[{"item_id":1,"tier":3}]}`)
+	second := []byte(`{"coins":250,"fish":[]}`)
+
+	before := time.Now()
+	if err := repo.UpsertRawInventory(ctx, 7, "100", first); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+	after := time.Now()
+
+	inv := mustGet(t, repo, "100")
+	if inv.KeyAccountID != 7 || inv.RobloxUserID != "100" {
+		t.Errorf("got key account %d, user %q; want 7, \"100\"", inv.KeyAccountID, inv.RobloxUserID)
+	}
+	checkJSON(t, b, "GetInventory", inv.InventoryJSON, first)
+	checkHash(t, repo, inv, first)
+	checkWithin(t, "synced_at", inv.SyncedAt, before, after)
+	if inv.LastSeenAt == nil {
+		t.Errorf("last_seen_at is nil after upsert")
+	} else {
+		checkWithin(t, "last_seen_at", *inv.LastSeenAt, before, after)
+	}
+
+	raw, syncedAt, err := repo.GetRawInventory(ctx, "100")
+	if err != nil || syncedAt == nil {
+		t.Fatalf("GetRawInventory = %q, %v, %v", raw, syncedAt, err)
+	}
+	checkJSON(t, b, "GetRawInventory", raw, first)
+	if !syncedAt.Equal(inv.SyncedAt) {
+		t.Errorf("GetRawInventory synced_at = %v; GetInventory has %v", syncedAt, inv.SyncedAt)
+	}
+
+	// Upserting again replaces everything but the ID
+	before = time.Now()
+	if err := repo.UpsertRawInventory(ctx, 8, "100", second); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+	after = time.Now()
+
+	updated := mustGet(t, repo, "100")
+	if updated.ID != inv.ID {
+		t.Errorf("ID changed from %d to %d on upsert", inv.ID, updated.ID)
+	}
+	if updated.KeyAccountID != 8 {
+		t.Errorf("key account = %d; want 8", updated.KeyAccountID)
+	}
+	checkJSON(t, b, "GetInventory", updated.InventoryJSON, second)
+	checkHash(t, repo, updated, second)
+	checkWithin(t, "synced_at", updated.SyncedAt, before, after)
+}
+
+func testBatchUpsert(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	if result, err := repo.BatchUpsertRawInventory(ctx, nil); err != nil || result == nil || len(result.Succeeded)+len(result.Failed) > 0 {
+		t.Errorf("BatchUpsertRawInventory(nil) = %+v, %v; want an empty result", result, err)
+	}
+
+	now := time.Now()
+	items := []model.InventoryItem{
+		{KeyAccountID: 1, RobloxUserID: "201", RawJSON: []byte(`{"coins":1}`), SyncedAt: now.Add(-time.Hour).In(east)},
+		{KeyAccountID: 2, RobloxUserID: "202", RawJSON: []byte(`{"coins":2}`), SyncedAt: now.Add(-2 * time.Hour).In(west)},
+		{KeyAccountID: 3, RobloxUserID: "203", RawJSON: []byte(`{"coins":3}`), SyncedAt: now, ContentHash: "precomputed"},
+	}
+	mustBatchUpsert(t, repo, items)
+
+	for _, item := range items {
+		inv := mustGet(t, repo, item.RobloxUserID)
+		if inv.KeyAccountID != item.KeyAccountID {
+			t.Errorf("%s: key account = %d; want %d", item.RobloxUserID, inv.KeyAccountID, item.KeyAccountID)
+		}
+		checkJSON(t, b, item.RobloxUserID, inv.InventoryJSON, item.RawJSON)
+		checkTime(t, item.RobloxUserID+" synced_at", inv.SyncedAt, item.SyncedAt)
+		if inv.LastSeenAt == nil {
+			t.Errorf("%s: last_seen_at is nil after batch upsert", item.RobloxUserID)
+		} else {
+			checkTime(t, item.RobloxUserID+" last_seen_at", *inv.LastSeenAt, item.SyncedAt)
+		}
+		if item.ContentHash != "" {
+			if inv.ContentHash != item.ContentHash {
+				t.Errorf("%s: content hash = %q; want the precomputed %q", item.RobloxUserID, inv.ContentHash, item.ContentHash)
+			}
+		} else {
+			checkHash(t, repo, inv, item.RawJSON)
+		}
+	}
+
+	// A batch replaces existing inventories, including their sync time
+	replaced := items[0]
+	replaced.KeyAccountID = 9
+	replaced.RawJSON = []byte(`{"coins":10}`)
+	replaced.SyncedAt = now.Add(-30 * time.Minute)
+	mustBatchUpsert(t, repo, []model.InventoryItem{replaced})
+	inv := mustGet(t, repo, replaced.RobloxUserID)
+	if inv.KeyAccountID != 9 {
+		t.Errorf("key account = %d after batch replace; want 9", inv.KeyAccountID)
+	}
+	checkJSON(t, b, "replaced", inv.InventoryJSON, replaced.RawJSON)
+	checkHash(t, repo, inv, replaced.RawJSON)
+	checkTime(t, "replaced synced_at", inv.SyncedAt, replaced.SyncedAt)
+}
+
+func testBatchPartialFailure(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	stored := []byte(`{"coins":1}`)
+	if err := repo.UpsertRawInventory(ctx, 1, "902", stored); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+
+	now := time.Now()
+	items := []model.InventoryItem{
+		{KeyAccountID: 1, RobloxUserID: "901", RawJSON: []byte(`{"coins":1}`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "902", RawJSON: []byte(`{"coins":`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "", RawJSON: []byte(`{"coins":3}`), SyncedAt: now},
+		// Valid JSON that Postgres cannot store in JSONB; others may
+		{KeyAccountID: 1, RobloxUserID: "904", RawJSON: []byte(`{"name":"a\u0000b"}`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "905", RawJSON: []byte(`{"coins":5}`), SyncedAt: now},
+	}
+	result, err := repo.BatchUpsertRawInventory(ctx, items)
+	if err != nil {
+		t.Fatalf("BatchUpsertRawInventory: %v", err)
+	}
+
+	outcome := make(map[string]string)
+	for _, id := range result.Succeeded {
+		if outcome[id] != "" {
+			t.Errorf("%q reported twice", id)
+		}
+		outcome[id] = "succeeded"
+	}
+	for _, f := range result.Failed {
+		if outcome[f.RobloxUserID] != "" {
+			t.Errorf("%q reported twice", f.RobloxUserID)
+		}
+		if f.Reason == "" {
+			t.Errorf("%q failed without a reason", f.RobloxUserID)
+		}
+		outcome[f.RobloxUserID] = "failed"
+	}
+	for _, item := range items {
+		if outcome[item.RobloxUserID] == "" {
+			t.Errorf("%q not reported", item.RobloxUserID)
+		}
+	}
+	for id, want := range map[string]string{"901": "succeeded", "902": "failed", "": "failed", "905": "succeeded"} {
+		if outcome[id] != want {
+			t.Errorf("%q %s; want %s", id, outcome[id], want)
+		}
+	}
+
+	// Items reported written are stored, and a failed one leaves what was there
+	for _, item := range items {
+		if outcome[item.RobloxUserID] == "succeeded" {
+			inv := mustGet(t, repo, item.RobloxUserID)
+			checkJSON(t, b, item.RobloxUserID, inv.InventoryJSON, item.RawJSON)
+		}
+	}
+	checkJSON(t, b, "kept after a failed write", mustGet(t, repo, "902").InventoryJSON, stored)
+	if outcome["904"] == "failed" {
+		if inv, err := repo.GetInventory(ctx, "904"); err != nil || inv != nil {
+			t.Errorf("GetInventory(904) = %+v, %v after a failed write; want nil, nil", inv, err)
+		}
+	}
+}
+
+// testBatchRejectedRows checks that rows the database itself refuses fail
+// alone: the rest of the batch is still written.
+func testBatchRejectedRows(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	if b.RejectUsers == nil {
+		t.Skip("backend cannot be made to reject rows")
+	}
+	ctx := context.Background()
+	stored := []byte(`{"coins":1}`)
+	if err := repo.UpsertRawInventory(ctx, 1, "912", stored); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+	b.RejectUsers(t, repo, "912", "914")
+
+	now := time.Now()
+	items := []model.InventoryItem{
+		{KeyAccountID: 1, RobloxUserID: "911", RawJSON: []byte(`{"coins":11}`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "912", RawJSON: []byte(`{"coins":12}`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "913", RawJSON: []byte(`{"coins":13}`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "914", RawJSON: []byte(`{"coins":14}`), SyncedAt: now},
+		{KeyAccountID: 1, RobloxUserID: "915", RawJSON: []byte(`{"coins":15}`), SyncedAt: now},
+	}
+	result, err := repo.BatchUpsertRawInventory(ctx, items)
+	if err != nil {
+		t.Fatalf("BatchUpsertRawInventory: %v", err)
+	}
+
+	succeededIDs := slices.Sorted(slices.Values(result.Succeeded))
+	if want := []string{"911", "913", "915"}; !slices.Equal(succeededIDs, want) {
+		t.Errorf("succeeded = %v; want %v", succeededIDs, want)
+	}
+	var failedIDs []string
+	for _, f := range result.Failed {
+		if f.Reason == "" {
+			t.Errorf("%q failed without a reason", f.RobloxUserID)
+		}
+		failedIDs = append(failedIDs, f.RobloxUserID)
+	}
+	slices.Sort(failedIDs)
+	if want := []string{"912", "914"}; !slices.Equal(failedIDs, want) {
+		t.Errorf("failed = %v; want %v", failedIDs, want)
+	}
+
+	for _, item := range items {
+		if slices.Contains(succeededIDs, item.RobloxUserID) {
+			checkJSON(t, b, item.RobloxUserID, mustGet(t, repo, item.RobloxUserID).InventoryJSON, item.RawJSON)
+		}
+	}
+	checkJSON(t, b, "kept after a rejected write", mustGet(t, repo, "912").InventoryJSON, stored)
+	if inv, err := repo.GetInventory(ctx, "914"); err != nil || inv != nil {
+		t.Errorf("GetInventory(914) = %+v, %v after a rejected write; want nil, nil", inv, err)
+	}
+}
+
+func testLastSeen(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	lastSeen, ok := repo.(repository.InventoryLastSeenRepository)
+
+	now := time.Now()
+	if err := repo.UpsertRawInventory(ctx, 1, "300", []byte(`{"coins":1}`)); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+
+	// A buffered write flushed after a newer sync keeps the newer last-seen time
+	older := model.InventoryItem{KeyAccountID: 1, RobloxUserID: "300", RawJSON: []byte(`{"coins":2}`), SyncedAt: now.Add(-time.Hour)}
+	mustBatchUpsert(t, repo, []model.InventoryItem{older})
+	inv := mustGet(t, repo, "300")
+	checkTime(t, "synced_at", inv.SyncedAt, older.SyncedAt)
+	if inv.LastSeenAt == nil || inv.LastSeenAt.Before(now.Truncate(timePrecision)) {
+		t.Errorf("last_seen_at = %v after an older batch write; want at least %v", inv.LastSeenAt, now)
+	}
+
+	if !ok {
+		return
+	}
+
+	hash, err := lastSeen.GetContentHash(ctx, "300")
+	if err != nil || hash != inv.ContentHash {
+		t.Errorf("GetContentHash = %q, %v; want %q", hash, err, inv.ContentHash)
+	}
+
+	seen := now.Add(time.Minute).In(east)
+	touched, err := lastSeen.TouchInventory(ctx, "300", seen)
+	if err != nil || !touched {
+		t.Fatalf("TouchInventory = %v, %v; want true, nil", touched, err)
+	}
+	inv = mustGet(t, repo, "300")
+	checkTime(t, "synced_at after touch", inv.SyncedAt, older.SyncedAt)
+	if inv.LastSeenAt == nil {
+		t.Fatalf("last_seen_at is nil after touch")
+	}
+	checkTime(t, "last_seen_at after touch", *inv.LastSeenAt, seen)
+
+	// Touching with an earlier time does not move last-seen back
+	if _, err := lastSeen.TouchInventory(ctx, "300", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("TouchInventory: %v", err)
+	}
+	inv = mustGet(t, repo, "300")
+	if inv.LastSeenAt == nil {
+		t.Fatalf("last_seen_at is nil after touch")
+	}
+	checkTime(t, "last_seen_at after an earlier touch", *inv.LastSeenAt, seen)
+}
+
+// jsonCases are inventories whose JSON backends like to re-encode.
+var jsonCases = []struct {
+	name string
+	raw  string
+}{
+	{"KeyOrder", `{"zebra":1,"apple":2,"mango":{"b":1,"a":2}}`},
+	{"Whitespace", "{\n  \"fish\": [ {\"item_id\": 1, \"tier\": 7} ],\n  \"coins\": 5\n}"},
+	{"LargeIntegers", `{"coins":9007199254740993,"uuid_like":12345678901234567890,"min":-9223372036854775808}`},
+	{"Numbers", `{"float":2.5,"exp":-3e2,"tiny":1e-7,"zero":0,"whole":1.0}`},
+	{"Strings", `{"name":"café \"quoted\" <b>&amp;</b>","emoji":"🐟","escaped":"line\nbreak\ttab\\"}`},
+	{"Empty", `{"object":{},"array":[],"null":null,"bools":[true,false]}`},
+	{"Nested", `{"rods":[{"id":1,"enchants":[{"id":2,"levels":[1,2,3]}]}],"stats":{"a":{"b":{"c":[]}}}}`},
+}
+
+func testReplaceIfHash(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	conditional, ok := repo.(repository.InventoryConditionalRepository)
+	if !ok {
+		t.Skip("backend has no conditional writes")
+	}
+	ctx := context.Background()
+	base := []byte(`{"coins":1}`)
+
+	written, err := conditional.ReplaceInventoryIfHash(ctx, 1, "1000", base, "anything")
+	if err != nil || written {
+		t.Errorf("ReplaceInventoryIfHash of a missing user = %v, %v; want false, nil", written, err)
+	}
+	if inv, err := repo.GetInventory(ctx, "1000"); err != nil || inv != nil {
+		t.Errorf("GetInventory = %+v, %v after a refused replace; want nil, nil", inv, err)
+	}
+
+	if err := repo.UpsertRawInventory(ctx, 1, "1000", base); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+	baseHash := mustGet(t, repo, "1000").ContentHash
+
+	written, err = conditional.ReplaceInventoryIfHash(ctx, 1, "1000", []byte(`{"coins":2}`), "stale")
+	if err != nil || written {
+		t.Errorf("ReplaceInventoryIfHash with a stale hash = %v, %v; want false, nil", written, err)
+	}
+	checkJSON(t, b, "after a stale replace", mustGet(t, repo, "1000").InventoryJSON, base)
+
+	// Of writers racing from the same base, exactly one wins
+	const writers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []int
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			raw := []byte(fmt.Sprintf(`{"coins":%d}`, 100+w))
+			written, err := conditional.ReplaceInventoryIfHash(ctx, int64(w), "1000", raw, baseHash)
+			if err != nil {
+				t.Errorf("ReplaceInventoryIfHash: %v", err)
+				return
+			}
+			if written {
+				mu.Lock()
+				winners = append(winners, w)
+				mu.Unlock()
+			}
+		}(w)
+	}
+	wg.Wait()
+	if len(winners) != 1 {
+		t.Fatalf("%d writers replaced the same base; want 1", len(winners))
+	}
+
+	want := []byte(fmt.Sprintf(`{"coins":%d}`, 100+winners[0]))
+	inv := mustGet(t, repo, "1000")
+	if inv.KeyAccountID != int64(winners[0]) {
+		t.Errorf("key account = %d; want the winner's %d", inv.KeyAccountID, winners[0])
+	}
+	checkJSON(t, b, "after replace", inv.InventoryJSON, want)
+	checkHash(t, repo, inv, want)
+}
+
+func testJSONFidelity(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	for i, tc := range jsonCases {
+		upserted := fmt.Sprintf("%d", 400+i)
+		if err := repo.UpsertRawInventory(ctx, 1, upserted, []byte(tc.raw)); err != nil {
+			t.Errorf("%s: UpsertRawInventory: %v", tc.name, err)
+			continue
+		}
+		batched := fmt.Sprintf("%d", 500+i)
+		item := model.InventoryItem{KeyAccountID: 1, RobloxUserID: batched, RawJSON: []byte(tc.raw), SyncedAt: time.Now()}
+		result, err := repo.BatchUpsertRawInventory(ctx, []model.InventoryItem{item})
+		if err == nil {
+			err = allWritten(result, []model.InventoryItem{item})
+		}
+		if err != nil {
+			t.Errorf("%s: BatchUpsertRawInventory: %v", tc.name, err)
+			continue
+		}
+
+		for _, id := range []string{upserted, batched} {
+			raw, _, err := repo.GetRawInventory(ctx, id)
+			if err != nil {
+				t.Errorf("%s: GetRawInventory: %v", tc.name, err)
+				continue
+			}
+			checkJSON(t, b, tc.name+" GetRawInventory", raw, []byte(tc.raw))
+
+			inv := mustGet(t, repo, id)
+			checkJSON(t, b, tc.name+" GetInventory", inv.InventoryJSON, []byte(tc.raw))
+			checkHash(t, repo, inv, []byte(tc.raw))
+		}
+	}
+}
+
+func testStats(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+
+	stats, err := repo.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	checkStat(t, stats, "total_inventories", int64(0))
+	if _, ok := stats["db_size_bytes"].(int64); !ok {
+		t.Errorf("db_size_bytes = %#v; want an int64", stats["db_size_bytes"])
+	}
+	if lastSync, ok := stats["last_sync"]; ok {
+		t.Errorf("last_sync = %v on an empty repository; want none", lastSync)
+	}
+
+	latest := time.Now().Add(-time.Minute).In(east)
+	items := []model.InventoryItem{
+		{KeyAccountID: 1, RobloxUserID: "601", RawJSON: []byte(`{"coins":1}`), SyncedAt: latest.Add(-time.Hour)},
+		{KeyAccountID: 1, RobloxUserID: "602", RawJSON: []byte(`{"coins":2}`), SyncedAt: latest},
+		{KeyAccountID: 1, RobloxUserID: "603", RawJSON: []byte(`{"coins":3}`), SyncedAt: latest.Add(-2 * time.Hour).In(west)},
+	}
+	mustBatchUpsert(t, repo, items)
+
+	stats, err = repo.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	checkStat(t, stats, "total_inventories", int64(len(items)))
+	if _, ok := stats["db_size_bytes"].(int64); !ok {
+		t.Errorf("db_size_bytes = %#v; want an int64", stats["db_size_bytes"])
+	}
+	lastSync, ok := stats["last_sync"].(time.Time)
+	if !ok {
+		t.Fatalf("last_sync = %#v; want a time.Time", stats["last_sync"])
+	}
+	checkTime(t, "last_sync", lastSync, latest)
+}
+
+func testDeleteInactiveUsers(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := repo.UpsertRawInventory(ctx, 1, "701", []byte(`{"coins":1}`)); err != nil {
+		t.Fatalf("UpsertRawInventory: %v", err)
+	}
+	items := []model.InventoryItem{
+		{KeyAccountID: 1, RobloxUserID: "702", RawJSON: []byte(`{"coins":2}`), SyncedAt: now.Add(-2 * time.Hour).In(east)},
+		{KeyAccountID: 1, RobloxUserID: "703", RawJSON: []byte(`{"coins":3}`), SyncedAt: now.Add(-30 * time.Minute).In(west)},
+		{KeyAccountID: 1, RobloxUserID: "704", RawJSON: []byte(`{"coins":4}`), SyncedAt: now.Add(-3 * time.Hour)},
+	}
+	mustBatchUpsert(t, repo, items)
+
+	wantGone := map[string]bool{"701": false, "702": true, "703": false, "704": true}
+	if lastSeen, ok := repo.(repository.InventoryLastSeenRepository); ok {
+		// Seen unchanged recently, so still active
+		if _, err := lastSeen.TouchInventory(ctx, "704", now.Add(-time.Minute)); err != nil {
+			t.Fatalf("TouchInventory: %v", err)
+		}
+		wantGone["704"] = false
+	}
+
+	var want int64
+	for _, gone := range wantGone {
+		if gone {
+			want++
+		}
+	}
+	deleted, err := repo.DeleteInactiveUsers(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("DeleteInactiveUsers: %v", err)
+	}
+	if deleted != want {
+		t.Errorf("DeleteInactiveUsers = %d; want %d", deleted, want)
+	}
+
+	for id, gone := range wantGone {
+		inv, err := repo.GetInventory(ctx, id)
+		if err != nil {
+			t.Fatalf("GetInventory(%s): %v", id, err)
+		}
+		if gone && inv != nil {
+			t.Errorf("%s is still stored after it went inactive", id)
+		}
+		if !gone && inv == nil {
+			t.Errorf("%s was deleted while active", id)
+		}
+	}
+
+	stats, err := repo.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	checkStat(t, stats, "total_inventories", int64(len(wantGone))-want)
+
+	deleted, err = repo.DeleteInactiveUsers(ctx, time.Hour)
+	if err != nil || deleted != 0 {
+		t.Errorf("second DeleteInactiveUsers = %d, %v; want 0, nil", deleted, err)
+	}
+}
+
+func testConcurrent(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	ctx := context.Background()
+	const workers, writes = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*writes*2)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			id := fmt.Sprintf("%d", 800+w)
+			for i := 0; i < writes; i++ {
+				raw := []byte(fmt.Sprintf(`{"coins":%d}`, i))
+				if i%2 == 0 {
+					errs <- repo.UpsertRawInventory(ctx, int64(w), id, raw)
+				} else {
+					item := model.InventoryItem{KeyAccountID: int64(w), RobloxUserID: id, RawJSON: raw, SyncedAt: time.Now()}
+					result, err := repo.BatchUpsertRawInventory(ctx, []model.InventoryItem{item})
+					if err == nil {
+						err = allWritten(result, []model.InventoryItem{item})
+					}
+					errs <- err
+				}
+				_, _, err := repo.GetRawInventory(ctx, id)
+				errs <- err
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent access: %v", err)
+		}
+	}
+
+	for w := 0; w < workers; w++ {
+		inv := mustGet(t, repo, fmt.Sprintf("%d", 800+w))
+		checkJSON(t, b, "last write", inv.InventoryJSON, []byte(fmt.Sprintf(`{"coins":%d}`, writes-1)))
+	}
+	stats, err := repo.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	checkStat(t, stats, "total_inventories", int64(workers))
+}
+
+func testConcurrentSnapshots(t *testing.T, b Backend, repo repository.InventoryRepository) {
+	history, ok := repo.(repository.InventoryHistoryRepository)
+	if !ok {
+		t.Skip("backend has no inventory history")
+	}
+	ctx := context.Background()
+	const writers = 8
+	users := []string{"900", "901"}
+
+	// Every batch is a new version for both users, in alternating order
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var batch []model.InventorySnapshot
+			for i := range users {
+				user := users[(i+w)%len(users)]
+				raw := []byte(fmt.Sprintf(`{"coins":%d}`, w))
+				hash, err := canonjson.Hash(raw)
+				if err != nil {
+					t.Errorf("Hash: %v", err)
+					return
+				}
+				batch = append(batch, model.InventorySnapshot{
+					RobloxUserID: user, KeyAccountID: int64(w), ContentHash: hash,
+					InventoryJSON: raw, CapturedAt: time.Now(),
+				})
+			}
+			if _, err := history.AppendSnapshots(ctx, batch); err != nil {
+				t.Errorf("AppendSnapshots: %v", err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, user := range users {
+		snapshots, total, err := history.ListSnapshots(ctx, user, writers*2, 0)
+		if err != nil {
+			t.Fatalf("ListSnapshots: %v", err)
+		}
+		if total != writers || len(snapshots) != writers {
+			t.Fatalf("user %s has %d snapshots (%d listed); want %d", user, total, len(snapshots), writers)
+		}
+		for i, snap := range snapshots {
+			if want := int64(writers - i); snap.Version != want {
+				t.Errorf("user %s snapshot %d has version %d; want %d", user, i, snap.Version, want)
+			}
+		}
+	}
+}
+
+// mustGet returns a stored inventory, failing the test if it is missing.
+// mustBatchUpsert writes items in one batch, all of which must be written.
+func mustBatchUpsert(t *testing.T, repo repository.InventoryRepository, items []model.InventoryItem) {
+	t.Helper()
+	result, err := repo.BatchUpsertRawInventory(context.Background(), items)
+	if err == nil {
+		err = allWritten(result, items)
+	}
+	if err != nil {
+		t.Fatalf("BatchUpsertRawInventory: %v", err)
+	}
+}
+
+// allWritten returns an error unless result reports every item written.
+func allWritten(result *model.BatchResult, items []model.InventoryItem) error {
+	if result == nil {
+		return fmt.Errorf("nil result")
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("failed items: %+v", result.Failed)
+	}
+	var want []string
+	for _, item := range items {
+		want = append(want, item.RobloxUserID)
+	}
+	got := slices.Clone(result.Succeeded)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		return fmt.Errorf("succeeded = %v; want %v", got, want)
+	}
+	return nil
+}
+
+func mustGet(t *testing.T, repo repository.InventoryRepository, robloxUserID string) *model.RawInventory {
+	t.Helper()
+	inv, err := repo.GetInventory(context.Background(), robloxUserID)
+	if err != nil {
+		t.Fatalf("GetInventory(%s): %v", robloxUserID, err)
+	}
+	if inv == nil {
+		t.Fatalf("GetInventory(%s) = nil; want an inventory", robloxUserID)
+	}
+	return inv
+}
+
+// checkJSON compares returned JSON with what was written, byte for byte if
+// the backend promises to keep it.
+func checkJSON(t *testing.T, b Backend, what string, got, want []byte) {
+	t.Helper()
+	if b.ExactJSON {
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: JSON = %s; want exactly %s", what, got, want)
+		}
+		return
+	}
+	if err := equivalentJSON(got, want); err != nil {
+		t.Errorf("%s: JSON = %s; want equivalent to %s: %v", what, got, want, err)
+	}
+}
+
+// checkHash verifies the stored content hash is the canonical hash of raw,
+// and that GetContentHash agrees with GetInventory.
+func checkHash(t *testing.T, repo repository.InventoryRepository, inv *model.RawInventory, raw []byte) {
+	t.Helper()
+	want, err := canonjson.Hash(raw)
+	if err != nil {
+		t.Fatalf("canonjson.Hash: %v", err)
+	}
+	if inv.ContentHash != want {
+		t.Errorf("%s: content hash = %q; want %q", inv.RobloxUserID, inv.ContentHash, want)
+	}
+	if lastSeen, ok := repo.(repository.InventoryLastSeenRepository); ok {
+		hash, err := lastSeen.GetContentHash(context.Background(), inv.RobloxUserID)
+		if err != nil || hash != want {
+			t.Errorf("%s: GetContentHash = %q, %v; want %q", inv.RobloxUserID, hash, err, want)
+		}
+	}
+}
+
+// checkTime verifies a stored timestamp is in UTC and equal to want at the
+// precision backends keep.
+func checkTime(t *testing.T, what string, got, want time.Time) {
+	t.Helper()
+	if got.Location() != time.UTC {
+		t.Errorf("%s = %v; want it in UTC", what, got)
+	}
+	if d := got.Sub(want); d <= -timePrecision || d >= timePrecision {
+		t.Errorf("%s = %v; want %v", what, got, want.UTC())
+	}
+}
+
+// checkWithin verifies a timestamp set by the backend is in UTC and between
+// before and after.
+func checkWithin(t *testing.T, what string, got, before, after time.Time) {
+	t.Helper()
+	if got.Location() != time.UTC {
+		t.Errorf("%s = %v; want it in UTC", what, got)
+	}
+	if got.Before(before.Truncate(timePrecision)) || got.After(after) {
+		t.Errorf("%s = %v; want between %v and %v", what, got, before.UTC(), after.UTC())
+	}
+}
+
+func checkStat(t *testing.T, stats map[string]interface{}, key string, want int64) {
+	t.Helper()
+	if got, ok := stats[key].(int64); !ok || got != want {
+		t.Errorf("%s = %#v; want int64(%d)", key, stats[key], want)
+	}
+}
+
+// equivalentJSON reports how got differs from want, comparing values with
+// numbers taken exactly and object keys in any order.
+func equivalentJSON(got, want []byte) error {
+	var g, w interface{}
+	if err := decodeJSON(got, &g); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := decodeJSON(want, &w); err != nil {
+		return fmt.Errorf("invalid expected JSON: %w", err)
+	}
+	return equivalentValue("$", g, w)
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func equivalentValue(path string, got, want interface{}) error {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, want an object", path, got)
+		}
+		if len(g) != len(w) {
+			return fmt.Errorf("%s: got %d keys, want %d", path, len(g), len(w))
+		}
+		for key, wv := range w {
+			gv, ok := g[key]
+			if !ok {
+				return fmt.Errorf("%s: missing key %q", path, key)
+			}
+			if err := equivalentValue(path+"."+key, gv, wv); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, want an array", path, got)
+		}
+		if len(g) != len(w) {
+			return fmt.Errorf("%s: got %d elements, want %d", path, len(g), len(w))
+		}
+		for i := range w {
+			if err := equivalentValue(fmt.Sprintf("%s[%d]", path, i), g[i], w[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		g, ok := got.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: got %T, want a number", path, got)
+		}
+		gr, gok := new(big.Rat).SetString(g.String())
+		wr, wok := new(big.Rat).SetString(w.String())
+		if !gok || !wok || gr.Cmp(wr) != 0 {
+			return fmt.Errorf("%s: got %s, want %s", path, g, w)
+		}
+		return nil
+	default:
+		if got != want {
+			return fmt.Errorf("%s: got %#v, want %#v", path, got, want)
+		}
+		return nil
+	}
+}
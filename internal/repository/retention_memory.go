@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// ListInactiveInventories returns a page of inventories inactive since before.
+func (r *MemoryInventoryRepository) ListInactiveInventories(ctx context.Context, before time.Time, after string, limit int) ([]model.RawInventory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var page []model.RawInventory
+	for _, id := range r.sortedUserIDs() {
+		if len(page) == limit {
+			break
+		}
+		inv := r.inventories[id]
+		if id > after && lastActive(inv).Before(before) {
+			page = append(page, *copyInventory(inv))
+		}
+	}
+	return page, nil
+}
+
+// DeleteInventoryIfInactive moves a user's inventory to the tombstones
+// unless it became active since before.
+func (r *MemoryInventoryRepository) DeleteInventoryIfInactive(ctx context.Context, robloxUserID string, before time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.inventories[robloxUserID]
+	if !ok || !lastActive(inv).Before(before) {
+		return false, nil
+	}
+	r.softDelete(robloxUserID)
+	return true, nil
+}
+
+// Ensure MemoryInventoryRepository implements InventoryRetentionRepository
+var _ InventoryRetentionRepository = (*MemoryInventoryRepository)(nil)
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// inactiveFilter matches documents neither synced nor seen since before,
+// the same condition DeleteInactiveUsers uses.
+func inactiveFilter(before time.Time) bson.M {
+	return bson.M{
+		"synced_at":    bson.M{"$lt": before},
+		"last_seen_at": bson.M{"$not": bson.M{"$gte": before}},
+	}
+}
+
+// ListInactiveInventories returns a page of inventories inactive since before.
+func (r *MongoDBInventoryRepository) ListInactiveInventories(ctx context.Context, before time.Time, after string, limit int) ([]model.RawInventory, error) {
+	filter := inactiveFilter(before)
+	filter["roblox_user_id"] = bson.M{"$gt": after}
+	opts := options.Find().SetSort(bson.D{{Key: "roblox_user_id", Value: 1}}).SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inactive inventories: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var page []model.RawInventory
+	for cursor.Next(ctx) {
+		var doc InventoryDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to decode inactive inventory: %w", err)
+		}
+		jsonBytes, err := doc.rawJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal inventory for %s: %w", doc.RobloxUserID, err)
+		}
+		page = append(page, model.RawInventory{
+			KeyAccountID:  doc.KeyAccountID,
+			RobloxUserID:  doc.RobloxUserID,
+			InventoryJSON: jsonBytes,
+			ContentHash:   contentHash(doc.ContentHash, jsonBytes),
+			SyncedAt:      doc.SyncedAt,
+			LastSeenAt:    doc.LastSeenAt,
+		})
+	}
+	return page, cursor.Err()
+}
+
+// DeleteInventoryIfInactive moves a user's inventory to the tombstone
+// collection unless it became active since before.
+func (r *MongoDBInventoryRepository) DeleteInventoryIfInactive(ctx context.Context, robloxUserID string, before time.Time) (bool, error) {
+	filter := inactiveFilter(before)
+	filter["roblox_user_id"] = robloxUserID
+
+	deleted, err := r.softDelete(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete inactive inventory: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryRetentionRepository
+var _ InventoryRetentionRepository = (*MongoDBInventoryRepository)(nil)
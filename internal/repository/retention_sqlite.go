@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// ListInactiveInventories returns a page of inventories inactive since before.
+func (r *SQLiteInventoryRepository) ListInactiveInventories(ctx context.Context, before time.Time, after string, limit int) ([]model.RawInventory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at
+		FROM fishit_inventory_raw
+		WHERE COALESCE(last_seen_at, synced_at) < ? AND roblox_user_id > ?
+		ORDER BY roblox_user_id LIMIT ?`, before.UTC(), after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inactive inventories: %w", err)
+	}
+	defer rows.Close()
+
+	var page []model.RawInventory
+	for rows.Next() {
+		var inv model.RawInventory
+		var rawJSON string
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&inv.ID, &inv.KeyAccountID, &inv.RobloxUserID, &rawJSON, &inv.ContentHash, &inv.SyncedAt, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan inactive inventory: %w", err)
+		}
+		inv.InventoryJSON = []byte(rawJSON)
+		inv.ContentHash = contentHash(inv.ContentHash, inv.InventoryJSON)
+		if lastSeen.Valid {
+			inv.LastSeenAt = &lastSeen.Time
+		}
+		page = append(page, inv)
+	}
+	return page, rows.Err()
+}
+
+// DeleteInventoryIfInactive moves a user's inventory to the tombstones table
+// unless it became active since before.
+func (r *SQLiteInventoryRepository) DeleteInventoryIfInactive(ctx context.Context, robloxUserID string, before time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted, err := r.softDelete(ctx, `roblox_user_id = ? AND COALESCE(last_seen_at, synced_at) < ?`, robloxUserID, before.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to delete inactive inventory: %w", err)
+	}
+	return deleted > 0, nil
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryRetentionRepository
+var _ InventoryRetentionRepository = (*SQLiteInventoryRepository)(nil)
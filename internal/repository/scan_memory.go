@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// ScanInventories pages through all inventories by roblox_user_id so the
+// repository lock is only held while a page is copied, not while fn runs.
+func (r *MemoryInventoryRepository) ScanInventories(ctx context.Context, fn func(item model.InventoryItem) error) error {
+	after := ""
+	for {
+		page := r.scanPage(after)
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(page) < scanPageSize {
+			return nil
+		}
+		after = page[len(page)-1].RobloxUserID
+	}
+}
+
+func (r *MemoryInventoryRepository) scanPage(after string) []model.InventoryItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var page []model.InventoryItem
+	for _, id := range r.sortedUserIDs() {
+		if id <= after {
+			continue
+		}
+		if len(page) == scanPageSize {
+			break
+		}
+		inv := r.inventories[id]
+		page = append(page, model.InventoryItem{
+			KeyAccountID: inv.KeyAccountID,
+			RobloxUserID: inv.RobloxUserID,
+			RawJSON:      copyInventory(inv).InventoryJSON,
+			SyncedAt:     inv.SyncedAt,
+		})
+	}
+	return page
+}
+
+// Ensure MemoryInventoryRepository implements InventoryScanner
+var _ InventoryScanner = (*MemoryInventoryRepository)(nil)
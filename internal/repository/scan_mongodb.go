@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScanInventories streams all inventories with a single cursor.
+func (r *MongoDBInventoryRepository) ScanInventories(ctx context.Context, fn func(item model.InventoryItem) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "roblox_user_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to scan inventories: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc InventoryDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode inventory: %w", err)
+		}
+		rawJSON, err := doc.rawJSON()
+		if err != nil {
+			return fmt.Errorf("failed to marshal inventory for %s: %w", doc.RobloxUserID, err)
+		}
+		if err := fn(model.InventoryItem{
+			KeyAccountID: doc.KeyAccountID,
+			RobloxUserID: doc.RobloxUserID,
+			RawJSON:      rawJSON,
+			SyncedAt:     doc.SyncedAt,
+		}); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryScanner
+var _ InventoryScanner = (*MongoDBInventoryRepository)(nil)
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// ScanInventories pages through all inventories by roblox_user_id.
+func (r *PostgresInventoryRepository) ScanInventories(ctx context.Context, fn func(item model.InventoryItem) error) error {
+	after := ""
+	for {
+		page, err := r.scanPage(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(page) < scanPageSize {
+			return nil
+		}
+		after = page[len(page)-1].RobloxUserID
+	}
+}
+
+func (r *PostgresInventoryRepository) scanPage(ctx context.Context, after string) ([]model.InventoryItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key_account_id, roblox_user_id, inventory_json, synced_at
+		FROM fishit_inventory_raw WHERE roblox_user_id > $1
+		ORDER BY roblox_user_id LIMIT $2`, after, scanPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan inventories: %w", err)
+	}
+	defer rows.Close()
+
+	var page []model.InventoryItem
+	for rows.Next() {
+		var item model.InventoryItem
+		if err := rows.Scan(&item.KeyAccountID, &item.RobloxUserID, &item.RawJSON, &item.SyncedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory: %w", err)
+		}
+		page = append(page, item)
+	}
+	return page, rows.Err()
+}
+
+// Ensure PostgresInventoryRepository implements InventoryScanner
+var _ InventoryScanner = (*PostgresInventoryRepository)(nil)
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// scanPageSize is how many inventories SQL scanners read per query.
+const scanPageSize = 500
+
+// ScanInventories pages through all inventories by roblox_user_id so the
+// repository lock is only held while a page is read, not while fn runs.
+func (r *SQLiteInventoryRepository) ScanInventories(ctx context.Context, fn func(item model.InventoryItem) error) error {
+	after := ""
+	for {
+		page, err := r.scanPage(ctx, after)
+		if err != nil {
+			return err
+		}
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(page) < scanPageSize {
+			return nil
+		}
+		after = page[len(page)-1].RobloxUserID
+	}
+}
+
+func (r *SQLiteInventoryRepository) scanPage(ctx context.Context, after string) ([]model.InventoryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT key_account_id, roblox_user_id, inventory_json, synced_at
+		FROM fishit_inventory_raw WHERE roblox_user_id > ?
+		ORDER BY roblox_user_id LIMIT ?`, after, scanPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan inventories: %w", err)
+	}
+	defer rows.Close()
+
+	var page []model.InventoryItem
+	for rows.Next() {
+		var item model.InventoryItem
+		var rawJSON string
+		if err := rows.Scan(&item.KeyAccountID, &item.RobloxUserID, &rawJSON, &item.SyncedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan inventory: %w", err)
+		}
+		item.RawJSON = []byte(rawJSON)
+		page = append(page, item)
+	}
+	return page, rows.Err()
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryScanner
+var _ InventoryScanner = (*SQLiteInventoryRepository)(nil)
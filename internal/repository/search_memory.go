@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// SearchItems finds users holding matching items by decoding every
+// inventory, matching items the way the SQL backends compare JSON values.
+func (r *MemoryInventoryRepository) SearchItems(ctx context.Context, q model.ItemSearchQuery) ([]model.ItemOwnership, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var tierNumbers map[float64]bool
+	var tierNames map[string]bool
+	if q.HasTierFilter() {
+		numbers, names := q.TierValues()
+		tierNumbers = make(map[float64]bool, len(numbers))
+		for _, n := range numbers {
+			tierNumbers[float64(n)] = true
+		}
+		tierNames = make(map[string]bool, len(names))
+		for _, name := range names {
+			tierNames[name] = true
+		}
+	}
+
+	matches := []model.ItemOwnership{}
+	for _, inv := range r.inventories {
+		var categories map[string]json.RawMessage
+		if err := json.Unmarshal(inv.InventoryJSON, &categories); err != nil {
+			continue // not an object: no categories to search
+		}
+
+		var count float64
+		matched := false
+		for _, category := range q.Categories() {
+			var items []map[string]interface{}
+			if err := json.Unmarshal(categories[category], &items); err != nil {
+				continue
+			}
+			for _, item := range items {
+				if !itemMatches(item, category, &q, tierNumbers, tierNames) {
+					continue
+				}
+				matched = true
+				if quantity, ok := item["quantity"].(float64); ok && quantity > 0 {
+					count += quantity
+				} else {
+					count++
+				}
+			}
+		}
+		if matched {
+			matches = append(matches, model.ItemOwnership{RobloxUserID: inv.RobloxUserID, ItemCount: count, SyncedAt: inv.SyncedAt})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].ItemCount != matches[j].ItemCount {
+			return matches[i].ItemCount > matches[j].ItemCount
+		}
+		return matches[i].RobloxUserID < matches[j].RobloxUserID
+	})
+	total := int64(len(matches))
+	if q.Offset >= len(matches) {
+		return []model.ItemOwnership{}, total, nil
+	}
+	matches = matches[q.Offset:]
+	if len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+	}
+	return matches, total, nil
+}
+
+// itemMatches applies the query's filters to one decoded item.
+func itemMatches(item map[string]interface{}, category string, q *model.ItemSearchQuery, tierNumbers map[float64]bool, tierNames map[string]bool) bool {
+	if q.ItemID != 0 {
+		id, _ := item["item_id"].(float64)
+		if id == 0 {
+			id, _ = item[model.CategoryIDFields[category]].(float64)
+		}
+		if id != float64(q.ItemID) {
+			return false
+		}
+	}
+	if tierNumbers != nil {
+		switch tier := item["tier"].(type) {
+		case float64:
+			if !tierNumbers[tier] {
+				return false
+			}
+		case string:
+			if !tierNames[tier] {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	if q.Shiny != nil && jsonTruthy(item["is_shiny"]) != *q.Shiny {
+		return false
+	}
+	if q.VariantID != "" && jsonText(item["variant_id"]) != q.VariantID {
+		return false
+	}
+	return true
+}
+
+// jsonTruthy reports whether a JSON value is true or 1, as SQL sees it.
+func jsonTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case float64:
+		return v == 1
+	}
+	return false
+}
+
+// jsonText returns a scalar JSON value as text, as a SQL cast would.
+func jsonText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	return "" // null, objects and arrays match no variant
+}
+
+// Ensure MemoryInventoryRepository implements InventorySearchRepository
+var _ InventorySearchRepository = (*MemoryInventoryRepository)(nil)
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SearchItems finds users holding matching items. Candidate documents are
+// selected with $elemMatch array queries, then matching items are unwound
+// and counted per user.
+func (r *MongoDBInventoryRepository) SearchItems(ctx context.Context, q model.ItemSearchQuery) ([]model.ItemOwnership, int64, error) {
+	// itemFilter matches a single item; prefix is the path to the item
+	itemFilter := func(prefix, idField string) bson.D {
+		filter := bson.D{}
+		if q.ItemID != 0 {
+			filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+				bson.D{{Key: prefix + "item_id", Value: q.ItemID}},
+				bson.D{{Key: idField, Value: q.ItemID}},
+			}})
+		}
+		if q.HasTierFilter() {
+			numbers, names := q.TierValues()
+			values := bson.A{}
+			for _, n := range numbers {
+				values = append(values, n)
+			}
+			for _, name := range names {
+				values = append(values, name)
+			}
+			filter = append(filter, bson.E{Key: prefix + "tier", Value: bson.D{{Key: "$in", Value: values}}})
+		}
+		if q.Shiny != nil {
+			if *q.Shiny {
+				filter = append(filter, bson.E{Key: prefix + "is_shiny", Value: true})
+			} else {
+				filter = append(filter, bson.E{Key: prefix + "is_shiny", Value: bson.D{{Key: "$ne", Value: true}}})
+			}
+		}
+		if q.VariantID != "" {
+			variants := bson.A{q.VariantID}
+			if n, err := strconv.ParseFloat(q.VariantID, 64); err == nil {
+				variants = append(variants, n)
+			}
+			filter = append(filter, bson.E{Key: prefix + "variant_id", Value: bson.D{{Key: "$in", Value: variants}}})
+		}
+		return filter
+	}
+
+	prefilter := bson.A{}
+	categoryItems := bson.A{}
+	for _, category := range q.Categories() {
+		idField := model.CategoryIDFields[category]
+		path := "$inventory_json." + category
+
+		prefilter = append(prefilter, bson.D{{Key: "inventory_json." + category,
+			Value: bson.D{{Key: "$elemMatch", Value: itemFilter("", idField)}}}})
+
+		// Tag each item with its category-specific ID so one filter covers all categories
+		categoryItems = append(categoryItems, bson.D{{Key: "$map", Value: bson.D{
+			{Key: "input", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$isArray", Value: path}}, path, bson.A{},
+			}}}},
+			{Key: "as", Value: "i"},
+			{Key: "in", Value: bson.D{
+				{Key: "item", Value: "$$i"},
+				{Key: "category_id", Value: "$$i." + idField},
+			}},
+		}}})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "$or", Value: prefilter}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "roblox_user_id", Value: 1},
+			{Key: "synced_at", Value: 1},
+			{Key: "items", Value: bson.D{{Key: "$concatArrays", Value: categoryItems}}},
+		}}},
+		{{Key: "$unwind", Value: "$items"}},
+		{{Key: "$match", Value: itemFilter("items.item.", "items.category_id")}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$roblox_user_id"},
+			{Key: "synced_at", Value: bson.D{{Key: "$first", Value: "$synced_at"}}},
+			{Key: "item_count", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$gt", Value: bson.A{"$items.item.quantity", 0}}}, "$items.item.quantity", 1,
+			}}}}}},
+		}}},
+		{{Key: "$facet", Value: bson.D{
+			{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "n"}}}},
+			{Key: "results", Value: bson.A{
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "item_count", Value: -1}, {Key: "_id", Value: 1}}}},
+				bson.D{{Key: "$skip", Value: q.Offset}},
+				bson.D{{Key: "$limit", Value: q.Limit}},
+			}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+		Results []struct {
+			RobloxUserID string    `bson:"_id"`
+			ItemCount    float64   `bson:"item_count"`
+			SyncedAt     time.Time `bson:"synced_at"`
+		} `bson:"results"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode search results: %w", err)
+	}
+
+	results := []model.ItemOwnership{}
+	var total int64
+	if len(facets) > 0 {
+		if len(facets[0].Total) > 0 {
+			total = facets[0].Total[0].N
+		}
+		for _, res := range facets[0].Results {
+			results = append(results, model.ItemOwnership{
+				RobloxUserID: res.RobloxUserID,
+				ItemCount:    res.ItemCount,
+				SyncedAt:     res.SyncedAt,
+			})
+		}
+	}
+	return results, total, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventorySearchRepository
+var _ InventorySearchRepository = (*MongoDBInventoryRepository)(nil)
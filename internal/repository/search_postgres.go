@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// SearchItems finds users holding matching items by expanding every
+// inventory's category arrays with jsonb_array_elements.
+func (r *PostgresInventoryRepository) SearchItems(ctx context.Context, q model.ItemSearchQuery) ([]model.ItemOwnership, int64, error) {
+	arms := make([]string, 0, len(q.Categories()))
+	for _, category := range q.Categories() {
+		arms = append(arms, fmt.Sprintf(`
+			SELECT r.roblox_user_id,
+				COALESCE(NULLIF(i->'item_id', '0'::jsonb), i->'%s') AS item_id,
+				i AS item
+			FROM fishit_inventory_raw r,
+				jsonb_array_elements(CASE WHEN jsonb_typeof(r.inventory_json->'%s') = 'array'
+					THEN r.inventory_json->'%s' ELSE '[]'::jsonb END) i`,
+			model.CategoryIDFields[category], category, category))
+	}
+
+	var conds []string
+	var args []interface{}
+	param := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.ItemID != 0 {
+		conds = append(conds, "item_id = to_jsonb("+param(q.ItemID)+"::bigint)")
+	}
+	if q.HasTierFilter() {
+		numbers, names := q.TierValues()
+		values := make([]interface{}, 0, len(numbers)+len(names))
+		for _, n := range numbers {
+			values = append(values, n)
+		}
+		for _, name := range names {
+			values = append(values, name)
+		}
+		tiers, _ := json.Marshal(values)
+		conds = append(conds, param(string(tiers))+"::jsonb @> jsonb_build_array(item->'tier')")
+	}
+	if q.Shiny != nil {
+		if *q.Shiny {
+			conds = append(conds, "item->'is_shiny' = 'true'::jsonb")
+		} else {
+			conds = append(conds, "COALESCE(item->'is_shiny', 'false'::jsonb) <> 'true'::jsonb")
+		}
+	}
+	if q.VariantID != "" {
+		conds = append(conds, "item->>'variant_id' = "+param(q.VariantID))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	matched := `
+		WITH items AS (` + strings.Join(arms, "\n\t\t\tUNION ALL") + `
+		),
+		matched AS (
+			SELECT roblox_user_id,
+				SUM(CASE WHEN jsonb_typeof(item->'quantity') = 'number' AND (item->>'quantity')::numeric > 0
+					THEN (item->>'quantity')::numeric ELSE 1 END)::float8 AS item_count
+			FROM items ` + where + `
+			GROUP BY roblox_user_id
+		)`
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, matched+` SELECT COUNT(*) FROM matched`, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	limit, offset := param(q.Limit), param(q.Offset)
+	rows, err := r.db.QueryContext(ctx, matched+`
+		SELECT m.roblox_user_id, m.item_count, r.synced_at
+		FROM matched m JOIN fishit_inventory_raw r ON r.roblox_user_id = m.roblox_user_id
+		ORDER BY m.item_count DESC, m.roblox_user_id
+		LIMIT `+limit+` OFFSET `+offset, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	results := []model.ItemOwnership{}
+	for rows.Next() {
+		var o model.ItemOwnership
+		if err := rows.Scan(&o.RobloxUserID, &o.ItemCount, &o.SyncedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, o)
+	}
+	return results, total, rows.Err()
+}
+
+// Ensure PostgresInventoryRepository implements InventorySearchRepository
+var _ InventorySearchRepository = (*PostgresInventoryRepository)(nil)
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// SearchItems finds users holding matching items by expanding every
+// inventory's category arrays with json_each.
+func (r *SQLiteInventoryRepository) SearchItems(ctx context.Context, q model.ItemSearchQuery) ([]model.ItemOwnership, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	arms := make([]string, 0, len(q.Categories()))
+	for _, category := range q.Categories() {
+		arms = append(arms, fmt.Sprintf(`
+			SELECT r.roblox_user_id,
+				COALESCE(NULLIF(json_extract(i.value, '$.item_id'), 0), json_extract(i.value, '$.%s')) AS item_id,
+				i.value AS item
+			FROM fishit_inventory_raw r, json_each(r.inventory_json, '$.%s') i`,
+			model.CategoryIDFields[category], category))
+	}
+
+	var conds []string
+	var args []interface{}
+	if q.ItemID != 0 {
+		conds = append(conds, "item_id = ?")
+		args = append(args, q.ItemID)
+	}
+	if q.HasTierFilter() {
+		numbers, names := q.TierValues()
+		placeholders := make([]string, 0, len(numbers)+len(names))
+		for _, n := range numbers {
+			placeholders = append(placeholders, "?")
+			args = append(args, n)
+		}
+		for _, name := range names {
+			placeholders = append(placeholders, "?")
+			args = append(args, name)
+		}
+		conds = append(conds, "json_extract(item, '$.tier') IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if q.Shiny != nil {
+		if *q.Shiny {
+			conds = append(conds, "json_extract(item, '$.is_shiny') = 1")
+		} else {
+			conds = append(conds, "COALESCE(json_extract(item, '$.is_shiny'), 0) = 0")
+		}
+	}
+	if q.VariantID != "" {
+		conds = append(conds, "CAST(json_extract(item, '$.variant_id') AS TEXT) = ?")
+		args = append(args, q.VariantID)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	matched := `
+		WITH items AS (` + strings.Join(arms, "\n\t\t\tUNION ALL") + `
+		),
+		matched AS (
+			SELECT roblox_user_id,
+				SUM(CASE WHEN json_extract(item, '$.quantity') > 0 THEN json_extract(item, '$.quantity') ELSE 1 END) AS item_count
+			FROM items ` + where + `
+			GROUP BY roblox_user_id
+		)`
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, matched+` SELECT COUNT(*) FROM matched`, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, matched+`
+		SELECT m.roblox_user_id, m.item_count, r.synced_at
+		FROM matched m JOIN fishit_inventory_raw r ON r.roblox_user_id = m.roblox_user_id
+		ORDER BY m.item_count DESC, m.roblox_user_id
+		LIMIT ? OFFSET ?`, append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	results := []model.ItemOwnership{}
+	for rows.Next() {
+		var o model.ItemOwnership
+		if err := rows.Scan(&o.RobloxUserID, &o.ItemCount, &o.SyncedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, o)
+	}
+	return results, total, rows.Err()
+}
+
+// Ensure SQLiteInventoryRepository implements InventorySearchRepository
+var _ InventorySearchRepository = (*SQLiteInventoryRepository)(nil)
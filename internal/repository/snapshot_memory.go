@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// memorySnapshotVersion is the format version of memory snapshots.
+const memorySnapshotVersion = 1
+
+// memorySnapshot is the on-disk form of a MemoryInventoryRepository: a
+// gzipped gob, which keeps inventory bytes exactly as stored.
+type memorySnapshot struct {
+	Version     int
+	SavedAt     time.Time
+	NextID      int64
+	Inventories []model.RawInventory
+	Tombstones  []memoryTombstone
+	History     map[string][]model.InventorySnapshot
+	Values      map[string][]model.ValuePoint
+}
+
+// Snapshot writes the repository to its snapshot path, atomically. It is a
+// no-op without a path or when nothing changed since the last snapshot.
+func (r *MemoryInventoryRepository) Snapshot() error {
+	if r.snapshotPath == "" {
+		return nil
+	}
+
+	// Encoding under the read lock keeps the snapshot consistent; writers
+	// wait for it, readers do not
+	r.mu.RLock()
+	changes := r.changes
+	if changes == r.saved {
+		r.mu.RUnlock()
+		return nil
+	}
+	err := r.writeSnapshot()
+	r.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.saved = changes
+	r.mu.Unlock()
+	return nil
+}
+
+// writeSnapshot encodes the repository to a temporary file and renames it
+// over the snapshot. Called with mu held.
+func (r *MemoryInventoryRepository) writeSnapshot() error {
+	snap := memorySnapshot{
+		Version:     memorySnapshotVersion,
+		SavedAt:     time.Now().UTC(),
+		NextID:      r.nextID,
+		Inventories: make([]model.RawInventory, 0, len(r.inventories)),
+		Tombstones:  r.tombstones,
+		History:     r.history,
+		Values:      r.values,
+	}
+	for _, id := range r.sortedUserIDs() {
+		snap.Inventories = append(snap.Inventories, *r.inventories[id])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.snapshotPath), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	tmp := r.snapshotPath + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp) // no-op once renamed
+
+	gz := gzip.NewWriter(file)
+	if err := gob.NewEncoder(gz).Encode(&snap); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, r.snapshotPath); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// loadSnapshot reads the snapshot file, if there is one, into the empty
+// repository.
+func (r *MemoryInventoryRepository) loadSnapshot() error {
+	file, err := os.Open(r.snapshotPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot %s: %w", r.snapshotPath, err)
+	}
+	var snap memorySnapshot
+	if err := gob.NewDecoder(gz).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot %s: %w", r.snapshotPath, err)
+	}
+	if snap.Version != memorySnapshotVersion {
+		return fmt.Errorf("snapshot %s has unsupported version %d", r.snapshotPath, snap.Version)
+	}
+
+	for i := range snap.Inventories {
+		inv := snap.Inventories[i]
+		r.inventories[inv.RobloxUserID] = &inv
+	}
+	r.tombstones = snap.Tombstones
+	if snap.History != nil {
+		r.history = snap.History
+	}
+	if snap.Values != nil {
+		r.values = snap.Values
+	}
+	r.nextID = snap.NextID
+
+	log.Printf("[Memory] Loaded snapshot %s (saved %s)", r.snapshotPath, snap.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// snapshotLoop snapshots the repository every interval until Close.
+func (r *MemoryInventoryRepository) snapshotLoop(interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				log.Printf("[Memory] Warning: snapshot failed: %v", err)
+			}
+		}
+	}
+}
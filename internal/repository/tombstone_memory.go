@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// RestoreInventory moves the user's latest tombstone back unless a live
+// inventory at least as recent exists.
+func (r *MemoryInventoryRepository) RestoreInventory(ctx context.Context, robloxUserID string) (*model.RestoreResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	latest := -1
+	for i, tomb := range r.tombstones {
+		if tomb.Inventory.RobloxUserID != robloxUserID {
+			continue
+		}
+		if latest < 0 || !tomb.DeletedAt.Before(r.tombstones[latest].DeletedAt) {
+			latest = i
+		}
+	}
+	if latest < 0 {
+		return nil, nil
+	}
+
+	tomb := r.tombstones[latest]
+	result := &model.RestoreResult{
+		RobloxUserID: robloxUserID,
+		KeyAccountID: tomb.Inventory.KeyAccountID,
+		ContentHash:  tomb.Inventory.ContentHash,
+		SyncedAt:     tomb.Inventory.SyncedAt,
+		DeletedAt:    tomb.DeletedAt,
+	}
+	if live, ok := r.inventories[robloxUserID]; ok && !live.SyncedAt.Before(tomb.Inventory.SyncedAt) {
+		liveSyncedAt := live.SyncedAt
+		result.LiveSyncedAt = &liveSyncedAt
+		return result, nil
+	}
+
+	inv := tomb.Inventory
+	r.upsert(inv.KeyAccountID, robloxUserID, inv.InventoryJSON, inv.ContentHash, inv.SyncedAt, time.Now())
+	r.tombstones = append(r.tombstones[:latest], r.tombstones[latest+1:]...)
+	result.Restored = true
+	return result, nil
+}
+
+// PruneTombstones permanently deletes tombstones older than grace.
+func (r *MemoryInventoryRepository) PruneTombstones(ctx context.Context, grace time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-grace)
+	kept := r.tombstones[:0]
+	for _, tomb := range r.tombstones {
+		if !tomb.DeletedAt.Before(cutoffTime) {
+			kept = append(kept, tomb)
+		}
+	}
+	deleted := int64(len(r.tombstones) - len(kept))
+	r.tombstones = kept
+
+	if deleted > 0 {
+		r.changes++
+		log.Printf("[Memory] Pruned %d inventory tombstones (grace: %v)", deleted, grace)
+	}
+	return deleted, nil
+}
+
+// Ensure MemoryInventoryRepository implements InventoryTombstoneRepository
+var _ InventoryTombstoneRepository = (*MemoryInventoryRepository)(nil)
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tombstoneDocument is a soft-deleted inventory document.
+type tombstoneDocument struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"`
+	InventoryDocument `bson:",inline"`
+	DeletedAt         time.Time `bson:"deleted_at"`
+}
+
+// softDelete moves the inventories matching filter to the tombstone
+// collection. Each tombstone is written before its inventory is deleted, and
+// removed again if the inventory stopped matching in between.
+func (r *MongoDBInventoryRepository) softDelete(ctx context.Context, filter bson.M) (int64, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var deleted int64
+	for cursor.Next(ctx) {
+		var doc tombstoneDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return deleted, fmt.Errorf("failed to decode inventory: %w", err)
+		}
+		liveID := doc.ID
+		doc.ID = primitive.NewObjectID()
+		doc.DeletedAt = time.Now()
+		if _, err := r.tombstones.InsertOne(ctx, doc); err != nil {
+			return deleted, fmt.Errorf("failed to write tombstone for %s: %w", doc.RobloxUserID, err)
+		}
+
+		match := bson.M{"_id": liveID}
+		for k, v := range filter {
+			match[k] = v
+		}
+		result, err := r.collection.DeleteOne(ctx, match)
+		if err == nil && result.DeletedCount > 0 {
+			deleted++
+			continue
+		}
+		if _, undoErr := r.tombstones.DeleteOne(ctx, bson.M{"_id": doc.ID}); undoErr != nil {
+			log.Printf("[MongoDB] Warning: failed to remove tombstone of %s: %v", doc.RobloxUserID, undoErr)
+		}
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, cursor.Err()
+}
+
+// RestoreInventory moves the user's latest tombstone back into the inventory
+// collection unless a live inventory at least as recent exists.
+func (r *MongoDBInventoryRepository) RestoreInventory(ctx context.Context, robloxUserID string) (*model.RestoreResult, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "_id", Value: -1}})
+
+	var tomb tombstoneDocument
+	err := r.tombstones.FindOne(ctx, bson.M{"roblox_user_id": robloxUserID}, opts).Decode(&tomb)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tombstone: %w", err)
+	}
+
+	result := &model.RestoreResult{
+		RobloxUserID: robloxUserID,
+		KeyAccountID: tomb.KeyAccountID,
+		ContentHash:  tomb.ContentHash,
+		SyncedAt:     tomb.SyncedAt,
+		DeletedAt:    tomb.DeletedAt,
+	}
+	if result.ContentHash == "" {
+		jsonBytes, err := tomb.rawJSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal inventory for %s: %w", robloxUserID, err)
+		}
+		result.ContentHash = contentHash("", jsonBytes)
+	}
+
+	// A live inventory synced at or after the tombstoned one does not match,
+	// and the upsert then fails on the unique roblox_user_id index
+	update := bson.M{"$set": bson.M{
+		"key_account_id": tomb.KeyAccountID,
+		"inventory_json": tomb.InventoryJSON,
+		"content_hash":   result.ContentHash,
+		"synced_at":      tomb.SyncedAt,
+		"last_seen_at":   time.Now(),
+	}}
+	if r.keepOriginal && tomb.InventoryOriginal != nil {
+		update["$set"].(bson.M)["inventory_original"] = tomb.InventoryOriginal
+	} else {
+		update["$unset"] = bson.M{"inventory_original": ""}
+	}
+	_, err = r.collection.UpdateOne(ctx,
+		bson.M{"roblox_user_id": robloxUserID, "synced_at": bson.M{"$lt": tomb.SyncedAt}},
+		update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		var live InventoryDocument
+		if err := r.collection.FindOne(ctx, bson.M{"roblox_user_id": robloxUserID}).Decode(&live); err != nil {
+			return nil, fmt.Errorf("failed to get live inventory: %w", err)
+		}
+		result.LiveSyncedAt = &live.SyncedAt
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore inventory: %w", err)
+	}
+
+	if _, err := r.tombstones.DeleteOne(ctx, bson.M{"_id": tomb.ID}); err != nil {
+		return nil, fmt.Errorf("failed to delete tombstone: %w", err)
+	}
+	result.Restored = true
+	return result, nil
+}
+
+// PruneTombstones permanently deletes tombstones older than grace.
+func (r *MongoDBInventoryRepository) PruneTombstones(ctx context.Context, grace time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-grace)
+	result, err := r.tombstones.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoffTime}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune tombstones: %w", err)
+	}
+
+	if result.DeletedCount > 0 {
+		log.Printf("[MongoDB] Pruned %d inventory tombstones (grace: %v)", result.DeletedCount, grace)
+	}
+	return result.DeletedCount, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryTombstoneRepository
+var _ InventoryTombstoneRepository = (*MongoDBInventoryRepository)(nil)
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// softDelete moves the inventories matching where to the tombstones table in
+// a single statement.
+func (r *PostgresInventoryRepository) softDelete(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM fishit_inventory_raw WHERE `+where+`
+			RETURNING key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at
+		)
+		INSERT INTO fishit_inventory_tombstones (key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at, deleted_at)
+		SELECT key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at, NOW()
+		FROM deleted`, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RestoreInventory moves the user's latest tombstone back into the inventory
+// table unless a live inventory at least as recent exists.
+func (r *PostgresInventoryRepository) RestoreInventory(ctx context.Context, robloxUserID string) (*model.RestoreResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var rawJSON []byte
+	result := &model.RestoreResult{RobloxUserID: robloxUserID}
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, key_account_id, inventory_json, content_hash, synced_at, deleted_at
+		FROM fishit_inventory_tombstones WHERE roblox_user_id = $1
+		ORDER BY deleted_at DESC, id DESC LIMIT 1
+		FOR UPDATE`, robloxUserID).
+		Scan(&id, &result.KeyAccountID, &rawJSON, &result.ContentHash, &result.SyncedAt, &result.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tombstone: %w", err)
+	}
+	result.ContentHash = contentHash(result.ContentHash, rawJSON)
+
+	// The WHERE keeps a live inventory synced at or after the tombstoned one
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO fishit_inventory_raw (key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (roblox_user_id) DO UPDATE SET
+			key_account_id = EXCLUDED.key_account_id,
+			inventory_json = EXCLUDED.inventory_json,
+			content_hash = EXCLUDED.content_hash,
+			synced_at = EXCLUDED.synced_at,
+			last_seen_at = EXCLUDED.last_seen_at
+		WHERE fishit_inventory_raw.synced_at < EXCLUDED.synced_at`,
+		result.KeyAccountID, robloxUserID, rawJSON, result.ContentHash, result.SyncedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore inventory: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var liveSyncedAt time.Time
+		if err := tx.QueryRowContext(ctx, `SELECT synced_at FROM fishit_inventory_raw WHERE roblox_user_id = $1`, robloxUserID).Scan(&liveSyncedAt); err != nil {
+			return nil, fmt.Errorf("failed to get live inventory: %w", err)
+		}
+		result.LiveSyncedAt = &liveSyncedAt
+		return result, nil
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM fishit_inventory_tombstones WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete tombstone: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	result.Restored = true
+	return result, nil
+}
+
+// PruneTombstones permanently deletes tombstones older than grace.
+func (r *PostgresInventoryRepository) PruneTombstones(ctx context.Context, grace time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-grace)
+	result, err := r.db.ExecContext(ctx, `DELETE FROM fishit_inventory_tombstones WHERE deleted_at < $1`, cutoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune tombstones: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Printf("[Postgres] Pruned %d inventory tombstones (grace: %v)", deleted, grace)
+	}
+	return deleted, nil
+}
+
+// Ensure PostgresInventoryRepository implements InventoryTombstoneRepository
+var _ InventoryTombstoneRepository = (*PostgresInventoryRepository)(nil)
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// softDelete moves the inventories matching where to the tombstones table.
+// Called with mu held, so nothing can change between the copy and the delete.
+func (r *SQLiteInventoryRepository) softDelete(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertArgs := append([]interface{}{time.Now().UTC()}, args...)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fishit_inventory_tombstones (key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at, deleted_at)
+		SELECT key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at, ?
+		FROM fishit_inventory_raw WHERE `+where, insertArgs...); err != nil {
+		return 0, fmt.Errorf("failed to write tombstones: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM fishit_inventory_raw WHERE `+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return deleted, nil
+}
+
+// RestoreInventory moves the user's latest tombstone back into the inventory
+// table unless a live inventory at least as recent exists.
+func (r *SQLiteInventoryRepository) RestoreInventory(ctx context.Context, robloxUserID string) (*model.RestoreResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var rawJSON string
+	result := &model.RestoreResult{RobloxUserID: robloxUserID}
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, key_account_id, inventory_json, content_hash, synced_at, deleted_at
+		FROM fishit_inventory_tombstones WHERE roblox_user_id = ?
+		ORDER BY deleted_at DESC, id DESC LIMIT 1`, robloxUserID).
+		Scan(&id, &result.KeyAccountID, &rawJSON, &result.ContentHash, &result.SyncedAt, &result.DeletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tombstone: %w", err)
+	}
+	result.ContentHash = contentHash(result.ContentHash, []byte(rawJSON))
+
+	var liveSyncedAt time.Time
+	err = tx.QueryRowContext(ctx, `SELECT synced_at FROM fishit_inventory_raw WHERE roblox_user_id = ?`, robloxUserID).Scan(&liveSyncedAt)
+	switch {
+	case err == nil && !liveSyncedAt.Before(result.SyncedAt):
+		result.LiveSyncedAt = &liveSyncedAt
+		return result, nil
+	case err != nil && err != sql.ErrNoRows:
+		return nil, fmt.Errorf("failed to get live inventory: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO fishit_inventory_raw (key_account_id, roblox_user_id, inventory_json, content_hash, synced_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(roblox_user_id) DO UPDATE SET
+			key_account_id = excluded.key_account_id,
+			inventory_json = excluded.inventory_json,
+			content_hash = excluded.content_hash,
+			synced_at = excluded.synced_at,
+			last_seen_at = excluded.last_seen_at`,
+		result.KeyAccountID, robloxUserID, rawJSON, result.ContentHash, result.SyncedAt, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to restore inventory: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM fishit_inventory_tombstones WHERE id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to delete tombstone: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	result.Restored = true
+	return result, nil
+}
+
+// PruneTombstones permanently deletes tombstones older than grace.
+func (r *SQLiteInventoryRepository) PruneTombstones(ctx context.Context, grace time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-grace).UTC()
+	result, err := r.db.ExecContext(ctx, `DELETE FROM fishit_inventory_tombstones WHERE deleted_at < ?`, cutoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune tombstones: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Printf("[SQLite] Pruned %d inventory tombstones (grace: %v)", deleted, grace)
+	}
+	return deleted, nil
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryTombstoneRepository
+var _ InventoryTombstoneRepository = (*SQLiteInventoryRepository)(nil)
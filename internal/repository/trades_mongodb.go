@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// transferCandidates bounds how many counterpart events are considered per
+// lookup; older candidates are almost always already matched.
+const transferCandidates = 10
+
+// EnsureTradeIndexes creates the indexes used by the TradeLog methods. The
+// unique event indexes keep an event from being part of two transfers.
+func (r *MongoDBLogRepository) EnsureTradeIndexes(ctx context.Context) error {
+	_, err := r.transfers.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "removed_event_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "acquired_event_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "uuid", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "from_roblox_user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "to_roblox_user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create item transfer indexes: %w", err)
+	}
+	return nil
+}
+
+// FindTransferCounterpart returns the latest unmatched event completing a
+// transfer with event, or nil.
+func (r *MongoDBLogRepository) FindTransferCounterpart(ctx context.Context, event *model.InventoryEvent, since time.Time) (*model.InventoryEvent, error) {
+	counterpartType, matchedField := model.EventItemRemoved, "removed_event_id"
+	if event.Type == model.EventItemRemoved {
+		counterpartType, matchedField = model.EventItemAcquired, "acquired_event_id"
+	}
+
+	filter := bson.D{
+		{Key: "uuid", Value: event.UUID},
+		{Key: "type", Value: counterpartType},
+		{Key: "roblox_user_id", Value: bson.D{{Key: "$ne", Value: event.RobloxUserID}}},
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(transferCandidates)
+	cursor, err := r.events.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transfer counterpart: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []model.InventoryEvent
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, fmt.Errorf("failed to decode transfer counterparts: %w", err)
+	}
+	for i := range candidates {
+		matched, err := r.transfers.CountDocuments(ctx, bson.M{matchedField: candidates[i].ID}, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check transfer counterpart: %w", err)
+		}
+		if matched == 0 {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// InsertItemTransfer stores a transfer, reporting false if one of its events
+// is already matched.
+func (r *MongoDBLogRepository) InsertItemTransfer(ctx context.Context, transfer *model.ItemTransfer) (bool, error) {
+	if transfer.ID.IsZero() {
+		transfer.ID = primitive.NewObjectID()
+	}
+	_, err := r.transfers.InsertOne(ctx, transfer)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert item transfer: %w", err)
+	}
+	return true, nil
+}
+
+// ListItemTransfers returns transfers matching q ordered by (created_at, _id)
+// descending, using the same cursors as ListInventoryEvents.
+func (r *MongoDBLogRepository) ListItemTransfers(ctx context.Context, q model.ItemTransferQuery) ([]model.ItemTransfer, string, error) {
+	conds := bson.A{}
+	if q.RobloxUserID != "" {
+		conds = append(conds, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "from_roblox_user_id", Value: q.RobloxUserID}},
+			bson.D{{Key: "to_roblox_user_id", Value: q.RobloxUserID}},
+		}}})
+	}
+	if q.UUID != "" {
+		conds = append(conds, bson.D{{Key: "uuid", Value: q.UUID}})
+	}
+	if q.Cursor != "" {
+		after, err := afterEventCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		conds = append(conds, bson.D{after})
+	}
+	filter := bson.D{}
+	if len(conds) > 0 {
+		filter = bson.D{{Key: "$and", Value: conds}}
+	}
+
+	// One extra transfer tells whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(q.Limit + 1))
+	cursor, err := r.transfers.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list item transfers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	transfers := []model.ItemTransfer{}
+	if err := cursor.All(ctx, &transfers); err != nil {
+		return nil, "", fmt.Errorf("failed to decode item transfers: %w", err)
+	}
+
+	next := ""
+	if len(transfers) > q.Limit {
+		transfers = transfers[:q.Limit]
+		last := transfers[len(transfers)-1]
+		next = encodeEventCursor(last.CreatedAt, last.ID)
+	}
+	return transfers, next, nil
+}
+
+// Ensure MongoDBLogRepository implements TradeLog
+var _ TradeLog = (*MongoDBLogRepository)(nil)
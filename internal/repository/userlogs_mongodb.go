@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListObfuscationLogsByUser returns the obfuscation logs of the given key accounts, newest first.
+func (r *MongoDBLogRepository) ListObfuscationLogsByUser(ctx context.Context, keyAccountIDs []int64) ([]model.ObfuscationLog, error) {
+	logs := []model.ObfuscationLog{}
+	if len(keyAccountIDs) == 0 {
+		return logs, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": bson.M{"$in": keyAccountIDs}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list obfuscation logs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &logs); err != nil {
+		return nil, fmt.Errorf("failed to decode obfuscation logs: %w", err)
+	}
+	return logs, nil
+}
+
+// EraseUserLogs deletes the obfuscation logs of the given key accounts, and
+// the inventory events and item transfers of robloxUserID.
+func (r *MongoDBLogRepository) EraseUserLogs(ctx context.Context, robloxUserID string, keyAccountIDs []int64) (*model.UserLogErasure, error) {
+	erased := &model.UserLogErasure{}
+	if len(keyAccountIDs) > 0 {
+		result, err := r.collection.DeleteMany(ctx, bson.M{"user_id": bson.M{"$in": keyAccountIDs}})
+		if err != nil {
+			return erased, fmt.Errorf("failed to erase obfuscation logs: %w", err)
+		}
+		erased.ObfuscationLogs = result.DeletedCount
+	}
+
+	result, err := r.events.DeleteMany(ctx, bson.M{"roblox_user_id": robloxUserID})
+	if err != nil {
+		return erased, fmt.Errorf("failed to erase inventory events: %w", err)
+	}
+	erased.InventoryEvents = result.DeletedCount
+
+	result, err = r.transfers.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"from_roblox_user_id": robloxUserID},
+		bson.M{"to_roblox_user_id": robloxUserID},
+	}})
+	if err != nil {
+		return erased, fmt.Errorf("failed to erase item transfers: %w", err)
+	}
+	erased.ItemTransfers = result.DeletedCount
+	return erased, nil
+}
+
+// Ensure MongoDBLogRepository implements UserLogStore
+var _ UserLogStore = (*MongoDBLogRepository)(nil)
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// RecordValues appends value points, skipping users whose latest point has the same total.
+func (r *MemoryInventoryRepository) RecordValues(ctx context.Context, points []model.ValuePoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range points {
+		existing := r.values[p.RobloxUserID]
+		if n := len(existing); n > 0 && r.latestValue(existing).Total == p.Total {
+			continue
+		}
+		p.RecordedAt = p.RecordedAt.UTC()
+		r.values[p.RobloxUserID] = append(existing, p)
+		r.changes++
+	}
+	return nil
+}
+
+// latestValue returns the point with the latest recorded_at, the last
+// recorded one among equals, as the SQL backends order them.
+func (r *MemoryInventoryRepository) latestValue(points []model.ValuePoint) model.ValuePoint {
+	latest := points[0]
+	for _, p := range points[1:] {
+		if !p.RecordedAt.Before(latest.RecordedAt) {
+			latest = p
+		}
+	}
+	return latest
+}
+
+// ListValues returns a user's value points in [from, to], oldest first.
+// When there are more than limit points, the most recent ones are returned.
+func (r *MemoryInventoryRepository) ListValues(ctx context.Context, robloxUserID string, from, to time.Time, limit int) ([]model.ValuePoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []model.ValuePoint
+	for _, p := range r.values[robloxUserID] {
+		if !p.RecordedAt.Before(from) && !p.RecordedAt.After(to) {
+			matched = append(matched, p)
+		}
+	}
+	sortValuePoints(matched)
+	if len(matched) > limit {
+		matched = matched[len(matched)-limit:]
+	}
+	points := []model.ValuePoint{}
+	return append(points, matched...), nil
+}
+
+// PruneValues deletes value points older than retention except each user's latest.
+func (r *MemoryInventoryRepository) PruneValues(ctx context.Context, retention time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-retention)
+	var deleted int64
+	for id, points := range r.values {
+		kept := points[:0]
+		for i, p := range points {
+			if p.RecordedAt.Before(cutoffTime) && i < len(points)-1 {
+				deleted++
+				continue
+			}
+			kept = append(kept, p)
+		}
+		r.values[id] = kept
+	}
+
+	if deleted > 0 {
+		r.changes++
+		log.Printf("[Memory] Pruned %d inventory value points (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// sortValuePoints orders points by recorded_at, keeping recording order among equals.
+func sortValuePoints(points []model.ValuePoint) {
+	sort.SliceStable(points, func(i, j int) bool { return points[i].RecordedAt.Before(points[j].RecordedAt) })
+}
+
+// Ensure MemoryInventoryRepository implements InventoryValueRepository
+var _ InventoryValueRepository = (*MemoryInventoryRepository)(nil)
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ValuePointDocument represents an inventory value point in the value collection.
+type ValuePointDocument struct {
+	RobloxUserID string    `bson:"roblox_user_id"`
+	Total        float64   `bson:"total"`
+	ItemCount    int       `bson:"item_count"`
+	RecordedAt   time.Time `bson:"recorded_at"`
+}
+
+// RecordValues appends value points, skipping users whose latest point has the same total.
+func (r *MongoDBInventoryRepository) RecordValues(ctx context.Context, points []model.ValuePoint) error {
+	latestOpts := options.FindOne().
+		SetSort(bson.D{{Key: "recorded_at", Value: -1}}).
+		SetProjection(bson.M{"total": 1})
+
+	docs := make([]interface{}, 0, len(points))
+	for _, p := range points {
+		var latest ValuePointDocument
+		err := r.values.FindOne(ctx, bson.M{"roblox_user_id": p.RobloxUserID}, latestOpts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to read latest value for %s: %w", p.RobloxUserID, err)
+		}
+		if err == nil && latest.Total == p.Total {
+			continue
+		}
+		docs = append(docs, ValuePointDocument{
+			RobloxUserID: p.RobloxUserID,
+			Total:        p.Total,
+			ItemCount:    p.ItemCount,
+			RecordedAt:   p.RecordedAt,
+		})
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	if _, err := r.values.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to record values: %w", err)
+	}
+	return nil
+}
+
+// ListValues returns a user's value points in [from, to], oldest first.
+// When there are more than limit points, the most recent ones are returned.
+func (r *MongoDBInventoryRepository) ListValues(ctx context.Context, robloxUserID string, from, to time.Time, limit int) ([]model.ValuePoint, error) {
+	filter := bson.M{
+		"roblox_user_id": robloxUserID,
+		"recorded_at":    bson.M{"$gte": from, "$lte": to},
+	}
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "recorded_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.values.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list values: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []ValuePointDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode values: %w", err)
+	}
+
+	points := make([]model.ValuePoint, len(docs))
+	for i, d := range docs {
+		points[len(docs)-1-i] = model.ValuePoint{
+			RobloxUserID: d.RobloxUserID,
+			Total:        d.Total,
+			ItemCount:    d.ItemCount,
+			RecordedAt:   d.RecordedAt,
+		}
+	}
+	return points, nil
+}
+
+// PruneValues deletes value points older than retention except each user's latest.
+func (r *MongoDBInventoryRepository) PruneValues(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-retention)
+
+	// Latest point of every user that has points past the cutoff
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "recorded_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$roblox_user_id"},
+			{Key: "latest", Value: bson.D{{Key: "$first", Value: "$_id"}}},
+			{Key: "oldest", Value: bson.D{{Key: "$min", Value: "$recorded_at"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "oldest", Value: bson.D{{Key: "$lt", Value: cutoffTime}}}}}},
+	}
+	cursor, err := r.values.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find prunable values: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var users []struct {
+		RobloxUserID string      `bson:"_id"`
+		Latest       interface{} `bson:"latest"`
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, fmt.Errorf("failed to decode prunable values: %w", err)
+	}
+
+	var deleted int64
+	for _, u := range users {
+		result, err := r.values.DeleteMany(ctx, bson.M{
+			"roblox_user_id": u.RobloxUserID,
+			"recorded_at":    bson.M{"$lt": cutoffTime},
+			"_id":            bson.M{"$ne": u.Latest},
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("failed to prune values for %s: %w", u.RobloxUserID, err)
+		}
+		deleted += result.DeletedCount
+	}
+
+	if deleted > 0 {
+		log.Printf("[MongoDB] Pruned %d inventory value points (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// Ensure MongoDBInventoryRepository implements InventoryValueRepository
+var _ InventoryValueRepository = (*MongoDBInventoryRepository)(nil)
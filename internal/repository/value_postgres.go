@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// RecordValues appends value points, skipping users whose latest point has the same total.
+func (r *PostgresInventoryRepository) RecordValues(ctx context.Context, points []model.ValuePoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fishit_inventory_value (roblox_user_id, total, item_count, recorded_at)
+		SELECT $1::text, $2::float8, $3::int, $4::timestamptz
+		WHERE NOT EXISTS (
+			SELECT 1 FROM (
+				SELECT total FROM fishit_inventory_value
+				WHERE roblox_user_id = $1 ORDER BY recorded_at DESC, id DESC LIMIT 1
+			) latest WHERE latest.total = $2
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range points {
+		if _, err := stmt.ExecContext(ctx, p.RobloxUserID, p.Total, p.ItemCount, p.RecordedAt); err != nil {
+			return fmt.Errorf("failed to record value for %s: %w", p.RobloxUserID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListValues returns a user's value points in [from, to], oldest first.
+// When there are more than limit points, the most recent ones are returned.
+func (r *PostgresInventoryRepository) ListValues(ctx context.Context, robloxUserID string, from, to time.Time, limit int) ([]model.ValuePoint, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT roblox_user_id, total, item_count, recorded_at
+		FROM fishit_inventory_value
+		WHERE roblox_user_id = $1 AND recorded_at >= $2 AND recorded_at <= $3
+		ORDER BY recorded_at DESC, id DESC LIMIT $4`, robloxUserID, from, to, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list values: %w", err)
+	}
+	defer rows.Close()
+
+	points := []model.ValuePoint{}
+	for rows.Next() {
+		var p model.ValuePoint
+		if err := rows.Scan(&p.RobloxUserID, &p.Total, &p.ItemCount, &p.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan value: %w", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverseValuePoints(points)
+	return points, nil
+}
+
+// PruneValues deletes value points older than retention except each user's latest.
+func (r *PostgresInventoryRepository) PruneValues(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoffTime := time.Now().Add(-retention)
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM fishit_inventory_value
+		WHERE recorded_at < $1
+		AND id < (
+			SELECT MAX(v.id) FROM fishit_inventory_value v
+			WHERE v.roblox_user_id = fishit_inventory_value.roblox_user_id
+		)`, cutoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune values: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Printf("[Postgres] Pruned %d inventory value points (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// Ensure PostgresInventoryRepository implements InventoryValueRepository
+var _ InventoryValueRepository = (*PostgresInventoryRepository)(nil)
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// RecordValues appends value points, skipping users whose latest point has the same total.
+func (r *SQLiteInventoryRepository) RecordValues(ctx context.Context, points []model.ValuePoint) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fishit_inventory_value (roblox_user_id, total, item_count, recorded_at)
+		SELECT ?1, ?2, ?3, ?4
+		WHERE NOT EXISTS (
+			SELECT 1 FROM (
+				SELECT total FROM fishit_inventory_value
+				WHERE roblox_user_id = ?1 ORDER BY recorded_at DESC, id DESC LIMIT 1
+			) latest WHERE latest.total = ?2
+		)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, p := range points {
+		if _, err := stmt.ExecContext(ctx, p.RobloxUserID, p.Total, p.ItemCount, p.RecordedAt.UTC()); err != nil {
+			return fmt.Errorf("failed to record value for %s: %w", p.RobloxUserID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListValues returns a user's value points in [from, to], oldest first.
+// When there are more than limit points, the most recent ones are returned.
+func (r *SQLiteInventoryRepository) ListValues(ctx context.Context, robloxUserID string, from, to time.Time, limit int) ([]model.ValuePoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT roblox_user_id, total, item_count, recorded_at
+		FROM fishit_inventory_value
+		WHERE roblox_user_id = ? AND recorded_at >= ? AND recorded_at <= ?
+		ORDER BY recorded_at DESC, id DESC LIMIT ?`, robloxUserID, from.UTC(), to.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list values: %w", err)
+	}
+	defer rows.Close()
+
+	points := []model.ValuePoint{}
+	for rows.Next() {
+		var p model.ValuePoint
+		if err := rows.Scan(&p.RobloxUserID, &p.Total, &p.ItemCount, &p.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan value: %w", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverseValuePoints(points)
+	return points, nil
+}
+
+// PruneValues deletes value points older than retention except each user's latest.
+func (r *SQLiteInventoryRepository) PruneValues(ctx context.Context, retention time.Duration) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoffTime := time.Now().Add(-retention).UTC()
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM fishit_inventory_value
+		WHERE recorded_at < ?
+		AND id < (
+			SELECT MAX(v.id) FROM fishit_inventory_value v
+			WHERE v.roblox_user_id = fishit_inventory_value.roblox_user_id
+		)`, cutoffTime)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune values: %w", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Printf("[SQLite] Pruned %d inventory value points (retention: %v)", deleted, retention)
+	}
+	return deleted, nil
+}
+
+// reverseValuePoints reverses points in place (newest-first queries to oldest-first).
+func reverseValuePoints(points []model.ValuePoint) {
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+}
+
+// Ensure SQLiteInventoryRepository implements InventoryValueRepository
+var _ InventoryValueRepository = (*SQLiteInventoryRepository)(nil)
@@ -0,0 +1,97 @@
+package retention
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// ArchivedInventory is one line of an archive file.
+type ArchivedInventory struct {
+	Game         string          `json:"game"`
+	RobloxUserID string          `json:"roblox_user_id"`
+	KeyAccountID int64           `json:"key_account_id"`
+	ContentHash  string          `json:"content_hash"`
+	SyncedAt     time.Time       `json:"synced_at"`
+	LastSeenAt   *time.Time      `json:"last_seen_at,omitempty"`
+	ArchivedAt   time.Time       `json:"archived_at"`
+	Inventory    json.RawMessage `json:"inventory"`
+}
+
+// NDJSONArchiver writes each cleanup run's purged inventories to a gzipped
+// NDJSON file under Dir/<game>/.
+type NDJSONArchiver struct {
+	Dir string
+}
+
+// NDJSONArchive is the archive file of one cleanup run.
+type NDJSONArchive struct {
+	game string
+	path string
+	file *os.File
+}
+
+// NewArchive creates the archive file for a run starting at the given time.
+func (a *NDJSONArchiver) NewArchive(game string, at time.Time) (*NDJSONArchive, error) {
+	dir := filepath.Join(a.Dir, game)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	path := filepath.Join(dir, "inventories-"+at.UTC().Format("20060102T150405Z")+".ndjson.gz")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	return &NDJSONArchive{game: game, path: path, file: file}, nil
+}
+
+// Name returns the archive's file path.
+func (a *NDJSONArchive) Name() string {
+	return a.path
+}
+
+// Append writes inventories as one gzip member and syncs the file, so they
+// are on disk before it returns. gzip readers read the concatenated members
+// of a file as one stream.
+func (a *NDJSONArchive) Append(inventories []model.RawInventory) error {
+	now := time.Now().UTC()
+	gz := gzip.NewWriter(a.file)
+	buf := bufio.NewWriter(gz)
+	enc := json.NewEncoder(buf)
+	for i := range inventories {
+		inv := &inventories[i]
+		if err := enc.Encode(ArchivedInventory{
+			Game:         a.game,
+			RobloxUserID: inv.RobloxUserID,
+			KeyAccountID: inv.KeyAccountID,
+			ContentHash:  inv.ContentHash,
+			SyncedAt:     inv.SyncedAt,
+			LastSeenAt:   inv.LastSeenAt,
+			ArchivedAt:   now,
+			Inventory:    inv.InventoryJSON,
+		}); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := a.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync archive: %w", err)
+	}
+	return nil
+}
+
+// Close closes the archive file.
+func (a *NDJSONArchive) Close() error {
+	return a.file.Close()
+}
@@ -0,0 +1,168 @@
+// Package retention holds the storage side of inventory retention: the set
+// of pinned users that are never purged, and the archives purged
+// inventories are written to before they are deleted.
+package retention
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// Pin marks a Roblox user whose inventories are never purged.
+type Pin struct {
+	RobloxUserID string    `json:"roblox_user_id"`
+	Reason       string    `json:"reason,omitempty"`
+	PinnedAt     time.Time `json:"pinned_at"`
+}
+
+// Pins is a concurrency-safe set of pinned users saved to a JSON file.
+// A pin covers the user in every game.
+type Pins struct {
+	mu   sync.RWMutex
+	pins map[string]Pin
+	path string // file changes are saved to; empty keeps them in memory only
+}
+
+// NewPins creates an empty pin set that saves changes to path.
+func NewPins(path string) *Pins {
+	return &Pins{
+		pins: make(map[string]Pin),
+		path: path,
+	}
+}
+
+// LoadPins reads pinned users from a JSON file. A missing file yields an
+// empty set that will be created on the first change.
+func LoadPins(path string) (*Pins, error) {
+	p := NewPins(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pinned users: %w", err)
+	}
+
+	var list []Pin
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to load pinned users %s: %w", path, err)
+	}
+	for _, pin := range list {
+		p.pins[pin.RobloxUserID] = pin
+	}
+
+	log.Printf("[Retention] Loaded %d pinned users from %s", len(list), path)
+	return p, nil
+}
+
+// IsPinned reports whether the user is pinned.
+func (p *Pins) IsPinned(robloxUserID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	_, ok := p.pins[robloxUserID]
+	return ok
+}
+
+// List returns all pins ordered by user ID.
+func (p *Pins) List() []Pin {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.sorted(p.pins)
+}
+
+// Pin pins a user, replacing the reason of an existing pin.
+func (p *Pins) Pin(robloxUserID, reason string) (*Pin, error) {
+	if strings.TrimSpace(robloxUserID) == "" {
+		return nil, apierror.BadRequest("roblox_user_id is required")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pin := Pin{RobloxUserID: robloxUserID, Reason: reason, PinnedAt: time.Now().UTC()}
+	if existing, ok := p.pins[robloxUserID]; ok {
+		pin.PinnedAt = existing.PinnedAt
+	}
+	next := p.with(robloxUserID, &pin)
+	if err := p.save(next); err != nil {
+		return nil, err
+	}
+	p.pins = next
+	return &pin, nil
+}
+
+// Unpin removes a user's pin. Reports whether the user was pinned.
+func (p *Pins) Unpin(robloxUserID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pins[robloxUserID]; !ok {
+		return false, nil
+	}
+	next := p.with(robloxUserID, nil)
+	if err := p.save(next); err != nil {
+		return false, err
+	}
+	p.pins = next
+	return true, nil
+}
+
+// with returns the pins with robloxUserID set to pin, or removed if pin is
+// nil. Called with mu held.
+func (p *Pins) with(robloxUserID string, pin *Pin) map[string]Pin {
+	next := make(map[string]Pin, len(p.pins)+1)
+	for id, existing := range p.pins {
+		next[id] = existing
+	}
+	if pin == nil {
+		delete(next, robloxUserID)
+	} else {
+		next[robloxUserID] = *pin
+	}
+	return next
+}
+
+func (p *Pins) sorted(pins map[string]Pin) []Pin {
+	list := make([]Pin, 0, len(pins))
+	for _, pin := range pins {
+		list = append(list, pin)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].RobloxUserID < list[j].RobloxUserID })
+	return list
+}
+
+// save writes pins to the file atomically. Called with mu held.
+func (p *Pins) save(pins map[string]Pin) error {
+	if p.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(p.sorted(pins), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pinned users: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0755); err != nil {
+		return fmt.Errorf("failed to create pinned users directory: %w", err)
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write pinned users: %w", err)
+	}
+	if err := os.Rename(tmp, p.path); err != nil {
+		return fmt.Errorf("failed to replace pinned users: %w", err)
+	}
+	return nil
+}
@@ -18,9 +18,19 @@ type Config struct {
 	AuthHandler         *handler.AuthHandler
 	ObfuscationHandler  *handler.ObfuscationHandler
 	LogHandler          *handler.LogHandler
+	LeaderboardHandler  *handler.LeaderboardHandler
+	CatalogHandler      *handler.CatalogHandler
+	WebhookHandler      *handler.WebhookHandler
+	TradeHandler        *handler.TradeHandler
+	RetentionHandler    *handler.RetentionHandler
+	UserDataHandler     *handler.UserDataHandler
 	AuthMiddleware      func(http.Handler) http.Handler
+	MaxBodySize         int64 // max decompressed request body; 0 uses defaultMaxBodySize
 }
 
+// defaultMaxBodySize bounds decompressed request bodies when Config.MaxBodySize is unset.
+const defaultMaxBodySize = 16 << 20
+
 // New creates and configures the HTTP router.
 func New(cfg Config) *chi.Mux {
 	r := chi.NewRouter()
@@ -31,9 +41,9 @@ func New(cfg Config) *chi.Mux {
 	r.Use(middleware.Logging)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Request-ID", "X-API-Key", "X-Token", "X-Login-Key"},
-		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Content-Encoding", "X-Request-ID", "X-API-Key", "X-Token", "X-Login-Key", "If-Match", "If-None-Match", "If-Modified-Since"},
+		ExposedHeaders:   []string{"X-Request-ID", "ETag", "Last-Modified"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -52,20 +62,23 @@ func New(cfg Config) *chi.Mux {
 		http.Redirect(w, r, "/static/admin.html", http.StatusMovedPermanently)
 	})
 
+	maxBodySize := cfg.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
 	// API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
+		// gzip/zstd request bodies, bounded to guard against decompression bombs
+		r.Use(middleware.Decompress(maxBodySize))
+
 		// PUBLIC ROUTES
 		if cfg.ObfuscationHandler != nil {
 			r.Post("/obfuscate", cfg.ObfuscationHandler.Obfuscate)
 			r.Get("/obfuscate/status/{jobID}", cfg.ObfuscationHandler.GetObfuscationStatus)
 		}
-		
-		// Public Log Routes (for now, or move to Admin if intended for admins only)
 		if cfg.LogHandler != nil {
-			r.Route("/logs", func(r chi.Router) {
-				r.Get("/obfuscation", cfg.LogHandler.GetObfuscationLogs)
-				r.Get("/inventory", cfg.LogHandler.GetInventoryLogs)
-			})
+			r.Get("/logs/obfuscation", cfg.LogHandler.GetObfuscationLogs)
 		}
 
 		// AUTHENTICATED ROUTES
@@ -89,20 +102,92 @@ func New(cfg Config) *chi.Mux {
 				})
 			}
 
-			// Inventory endpoints
+			// Inventory endpoints; /inventory is an alias for /games/fishit/inventory
 			if cfg.InventoryHandler != nil {
-				r.Route("/inventory/{roblox_user_id}", func(r chi.Router) {
-					r.Post("/sync", cfg.InventoryHandler.SyncRawInventory)
-					r.Get("/", cfg.InventoryHandler.GetRawInventory)
+				r.Route("/inventory", func(r chi.Router) {
+					inventoryRoutes(r, cfg)
+				})
+				r.Route("/games/{game}/inventory", func(r chi.Router) {
+					r.Use(cfg.InventoryHandler.RequireGame)
+					inventoryRoutes(r, cfg)
+				})
+			}
+
+			// Leaderboard endpoints
+			if cfg.LeaderboardHandler != nil {
+				r.Route("/leaderboards/{metric}", func(r chi.Router) {
+					r.Get("/", cfg.LeaderboardHandler.GetLeaderboard)
+					r.Get("/users/{roblox_user_id}", cfg.LeaderboardHandler.GetUserRank)
+				})
+			}
+
+			// Item catalog endpoints
+			if cfg.CatalogHandler != nil {
+				r.Get("/catalog/items", cfg.CatalogHandler.ListItems)
+				r.Get("/catalog/items/{id}", cfg.CatalogHandler.GetItem)
+			}
+
+			// Inventory event log; it spans players, so not for session tokens
+			if cfg.LogHandler != nil {
+				r.With(cfg.authorize(middleware.Privileged)).Get("/logs/inventory", cfg.LogHandler.GetInventoryLogs)
+			}
+
+			// Trade investigation endpoints; they span players, so not for session tokens
+			if cfg.TradeHandler != nil {
+				r.Group(func(r chi.Router) {
+					r.Use(cfg.authorize(middleware.Privileged))
+					r.Get("/trades", cfg.TradeHandler.ListTrades)
+					r.Get("/items/{uuid}/history", cfg.TradeHandler.GetItemHistory)
 				})
 			}
 
 			// Admin endpoints
 			if cfg.AdminHandler != nil {
 				r.Route("/admin", func(r chi.Router) {
-					r.Get("/stats", cfg.AdminHandler.GetStats)
-					r.Get("/health", cfg.AdminHandler.GetHealth)
 					r.Post("/login", cfg.AdminHandler.VerifyLogin)
+
+					// Everything else is for API keys and admin credentials only
+					r.Group(func(r chi.Router) {
+						r.Use(cfg.authorize(middleware.Privileged))
+						r.Get("/stats", cfg.AdminHandler.GetStats)
+						r.Get("/health", cfg.AdminHandler.GetHealth)
+						if cfg.LeaderboardHandler != nil {
+							r.Post("/leaderboards/rebuild", cfg.LeaderboardHandler.RebuildLeaderboards)
+						}
+						if cfg.CatalogHandler != nil {
+							r.Put("/catalog/items/{id}", cfg.CatalogHandler.PutItem)
+							r.Delete("/catalog/items/{id}", cfg.CatalogHandler.DeleteItem)
+							r.Post("/catalog/import", cfg.CatalogHandler.ImportItems)
+						}
+						if cfg.WebhookHandler != nil {
+							r.Route("/webhooks", func(r chi.Router) {
+								h := cfg.WebhookHandler
+								r.Get("/", h.ListWebhooks)
+								r.Post("/", h.CreateWebhook)
+								r.Get("/{id}", h.GetWebhook)
+								r.Put("/{id}", h.UpdateWebhook)
+								r.Delete("/{id}", h.DeleteWebhook)
+								r.Post("/{id}/test", h.TestWebhook)
+								r.Get("/{id}/deliveries", h.GetDeliveries)
+							})
+						}
+						if cfg.InventoryHandler != nil {
+							r.Post("/inventory/{roblox_user_id}/restore", cfg.InventoryHandler.RestoreInventory)
+						}
+						if cfg.RetentionHandler != nil {
+							r.Route("/retention", func(r chi.Router) {
+								h := cfg.RetentionHandler
+								r.Post("/run", h.RunRetention)
+								r.Get("/pins", h.ListPins)
+								r.Put("/pins/{roblox_user_id}", h.PinUser)
+								r.Delete("/pins/{roblox_user_id}", h.UnpinUser)
+							})
+						}
+						if cfg.UserDataHandler != nil {
+							r.Get("/users/{roblox_user_id}/export", cfg.UserDataHandler.ExportUser)
+							r.Delete("/users/{roblox_user_id}", cfg.UserDataHandler.DeleteUser)
+						}
+					})
 				})
 			}
 		})
@@ -110,3 +195,33 @@ func New(cfg Config) *chi.Mux {
 
 	return r
 }
+
+// inventoryRoutes registers the inventory endpoints of one game.
+// Session tokens may only touch their own user's inventory.
+func inventoryRoutes(r chi.Router, cfg Config) {
+	h := cfg.InventoryHandler
+	// Search spans players, so not for session tokens
+	r.With(cfg.authorize(middleware.Privileged)).Get("/search", h.SearchItems)
+	r.Route("/{roblox_user_id}", func(r chi.Router) {
+		r.Use(cfg.authorize(middleware.OwnRobloxUser("roblox_user_id")))
+		r.Post("/sync", h.SyncRawInventory)
+		r.Get("/", h.GetRawInventory)
+		r.Patch("/", h.PatchInventory)
+		r.Get("/history", h.GetInventoryHistory)
+		r.Get("/history/{version}", h.GetInventoryVersion)
+		r.Get("/diff", h.GetInventoryDiff)
+		r.Post("/diff", h.DiffInventoryPayloads)
+		r.Get("/value", h.GetInventoryValue)
+		r.Get("/value/history", h.GetInventoryValueHistory)
+		r.Get("/{category}", h.GetInventoryCategory)
+	})
+}
+
+// authorize returns middleware enforcing policy, or a pass-through when
+// authentication is disabled (no AuthMiddleware configured).
+func (cfg Config) authorize(policy middleware.Policy) func(http.Handler) http.Handler {
+	if cfg.AuthMiddleware == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.Authorize(policy)
+}
@@ -2,42 +2,115 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
+	"vinzhub-rest-api-v2/internal/model"
 	"vinzhub-rest-api-v2/internal/repository"
 )
 
+// retentionPageSize is how many inactive inventories are evaluated at a time.
+const retentionPageSize = 200
+
+// maxReportedCandidates bounds the candidates listed in a RetentionReport.
+const maxReportedCandidates = 1000
+
 // CleanupConfig holds configuration for the cleanup scheduler.
 type CleanupConfig struct {
-	// InactiveThreshold is the duration after which inactive users are deleted.
+	// Game names the inventories being cleaned up, for reports and archives.
+	Game string
+
+	// InactiveThreshold is the duration after which inactive users are deleted,
+	// unless a key tier or value threshold applies.
 	// Default: 30 days
 	InactiveThreshold time.Duration
 
+	// KeyTierThresholds replace InactiveThreshold for users whose key has the
+	// given tier (see repository.KeyTierRepository).
+	KeyTierThresholds map[string]time.Duration
+
+	// ValueThresholds keep valuable inventories longer: a user whose inventory
+	// is worth at least MinValue is kept at least Threshold.
+	ValueThresholds []ValueThreshold
+
+	// DryRun makes scheduled runs only log what they would purge.
+	DryRun bool
+
 	// CleanupInterval is how often the cleanup runs.
 	// Default: 24 hours
 	CleanupInterval time.Duration
+
+	// HistoryRetention is how long inventory snapshots are kept.
+	// Each user's latest two snapshots are always kept. Zero disables pruning.
+	HistoryRetention time.Duration
+
+	// ValueRetention is how long inventory value points are kept.
+	// Each user's latest point is always kept. Zero disables pruning.
+	ValueRetention time.Duration
+
+	// TombstoneGrace is how long purged inventories stay restorable on
+	// backends implementing repository.InventoryTombstoneRepository.
+	// Zero keeps them forever.
+	TombstoneGrace time.Duration
+}
+
+// ValueThreshold is the minimum retention of inventories worth at least MinValue.
+type ValueThreshold struct {
+	MinValue  float64
+	Threshold time.Duration
 }
 
 // DefaultCleanupConfig returns default cleanup configuration.
-// Aggressive cleanup for limited MongoDB storage.
 func DefaultCleanupConfig() CleanupConfig {
 	return CleanupConfig{
-		InactiveThreshold: 1 * time.Hour,      // 1 hour - user inactive for 1 hour gets deleted
-		CleanupInterval:   10 * time.Minute,   // Run every 10 minutes
+		InactiveThreshold: 30 * 24 * time.Hour, // players who log off for a while keep their data
+		CleanupInterval:   10 * time.Minute,    // Run every 10 minutes
 	}
 }
 
+// Archive stores the inventories purged by one cleanup run.
+type Archive interface {
+	// Name identifies the archive, e.g. its file path.
+	Name() string
+
+	// Append durably stores inventories; they are deleted once it returns.
+	Append(inventories []model.RawInventory) error
+
+	Close() error
+}
+
+// NewArchiveFunc starts the archive of a cleanup run of game starting at the given time.
+type NewArchiveFunc func(game string, at time.Time) (Archive, error)
+
+// PinChecker reports whether a user is pinned and must never be purged.
+type PinChecker interface {
+	IsPinned(robloxUserID string) bool
+}
+
 // CleanupScheduler runs periodic cleanup of inactive inventory data.
+// With a backend implementing repository.InventoryRetentionRepository, each
+// inactive user is judged on their own threshold (key tier, inventory value),
+// pinned users are skipped and purged inventories are archived first. Backends
+// implementing repository.InventoryTombstoneRepository keep purged inventories
+// restorable for TombstoneGrace.
 type CleanupScheduler struct {
-	repo      repository.InventoryRepository
-	config    CleanupConfig
-	ticker    *time.Ticker
-	stopCh    chan struct{}
-	stopOnce  sync.Once
-	isRunning bool
-	mu        sync.Mutex
+	repo         repository.InventoryRepository
+	config       CleanupConfig
+	pins         PinChecker
+	newArchive   NewArchiveFunc
+	keyTiers     repository.KeyTierRepository
+	valuator     *Valuator
+	leaderboards *LeaderboardService
+	runMu        sync.Mutex // one run at a time, scheduled or manual
+	ticker       *time.Ticker
+	stopCh       chan struct{}
+	stopOnce     sync.Once
+	isRunning    bool
+	mu           sync.Mutex
 }
 
 // NewCleanupScheduler creates a new cleanup scheduler.
@@ -48,6 +121,9 @@ func NewCleanupScheduler(repo repository.InventoryRepository, config CleanupConf
 	if config.CleanupInterval == 0 {
 		config.CleanupInterval = 24 * time.Hour
 	}
+	sort.Slice(config.ValueThresholds, func(i, j int) bool {
+		return config.ValueThresholds[i].MinValue < config.ValueThresholds[j].MinValue
+	})
 
 	return &CleanupScheduler{
 		repo:   repo,
@@ -56,6 +132,33 @@ func NewCleanupScheduler(repo repository.InventoryRepository, config CleanupConf
 	}
 }
 
+// SetPins makes cleanup skip pinned users.
+func (s *CleanupScheduler) SetPins(pins PinChecker) {
+	s.pins = pins
+}
+
+// SetArchiver makes cleanup archive inventories before deleting them.
+// Without one, inventories are deleted outright.
+func (s *CleanupScheduler) SetArchiver(newArchive NewArchiveFunc) {
+	s.newArchive = newArchive
+}
+
+// SetKeyTiers enables KeyTierThresholds.
+func (s *CleanupScheduler) SetKeyTiers(keyTiers repository.KeyTierRepository) {
+	s.keyTiers = keyTiers
+}
+
+// SetValuator enables ValueThresholds. Only inventories in the fishit schema
+// have a value.
+func (s *CleanupScheduler) SetValuator(v *Valuator) {
+	s.valuator = v
+}
+
+// SetLeaderboards makes cleanup remove purged users from the leaderboards.
+func (s *CleanupScheduler) SetLeaderboards(leaderboards *LeaderboardService) {
+	s.leaderboards = leaderboards
+}
+
 // Start begins the cleanup scheduler.
 func (s *CleanupScheduler) Start() {
 	s.mu.Lock()
@@ -97,19 +200,252 @@ func (s *CleanupScheduler) runCleanup() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	log.Printf("[CleanupScheduler] Running cleanup for inactive users (threshold: %v)", s.config.InactiveThreshold)
+	log.Printf("[CleanupScheduler] Running cleanup for inactive users (threshold: %v, dry run: %v)", s.config.InactiveThreshold, s.config.DryRun)
 
-	deleted, err := s.repo.DeleteInactiveUsers(ctx, s.config.InactiveThreshold)
+	report, err := s.Run(ctx, s.config.DryRun)
 	if err != nil {
 		log.Printf("[CleanupScheduler] Error during cleanup: %v", err)
 		return
 	}
 
-	if deleted > 0 {
-		log.Printf("[CleanupScheduler] Cleaned up %d inactive inventory records", deleted)
-	} else {
+	switch {
+	case report.DryRun:
+		for _, c := range report.Candidates {
+			log.Printf("[CleanupScheduler] Dry run: would purge %s (inactive since %s, %s)", c.RobloxUserID, c.LastActiveAt.Format(time.RFC3339), c.Rule)
+		}
+		log.Printf("[CleanupScheduler] Dry run: %d of %d inactive inventories would be purged (%d pinned, %d kept)",
+			report.Evaluated-report.Kept-report.Pinned, report.Evaluated, report.Pinned, report.Kept)
+		return
+	case report.Purged > 0:
+		log.Printf("[CleanupScheduler] Cleaned up %d inactive inventory records (%d pinned, %d kept)", report.Purged, report.Pinned, report.Kept)
+	default:
 		log.Printf("[CleanupScheduler] No inactive records to clean up")
 	}
+
+	s.pruneHistory(ctx)
+	s.pruneValues(ctx)
+	s.pruneTombstones(ctx)
+}
+
+// Run applies the retention policy once. A dry run only reports which
+// inventories would be purged. Backends without
+// repository.InventoryRetentionRepository fall back to DeleteInactiveUsers
+// with InactiveThreshold and cannot dry run.
+func (s *CleanupScheduler) Run(ctx context.Context, dryRun bool) (*model.RetentionReport, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	now := time.Now()
+	report := &model.RetentionReport{
+		Game:       s.config.Game,
+		DryRun:     dryRun,
+		StartedAt:  now.UTC(),
+		Candidates: []model.RetentionCandidate{},
+	}
+
+	retentionRepo, ok := s.repo.(repository.InventoryRetentionRepository)
+	if !ok {
+		if dryRun {
+			return nil, errors.New("dry run is not supported by this storage backend")
+		}
+		deleted, err := s.repo.DeleteInactiveUsers(ctx, s.config.InactiveThreshold)
+		report.Purged = int(deleted)
+		return report, err
+	}
+
+	var archive Archive
+	defer func() {
+		if archive != nil {
+			if err := archive.Close(); err != nil {
+				log.Printf("[CleanupScheduler] Failed to close archive %s: %v", archive.Name(), err)
+			}
+		}
+	}()
+
+	after := ""
+	for {
+		page, err := retentionRepo.ListInactiveInventories(ctx, now.Add(-s.shortestThreshold()), after, retentionPageSize)
+		if err != nil {
+			return report, err
+		}
+
+		tiers := s.keyTiersOf(ctx, page)
+		var purge []model.RawInventory
+		var candidates []model.RetentionCandidate
+		var cutoffs []time.Time
+		for i := range page {
+			inv := &page[i]
+			report.Evaluated++
+			if s.pins != nil && s.pins.IsPinned(inv.RobloxUserID) {
+				report.Pinned++
+				continue
+			}
+
+			value := s.valueOf(inv)
+			threshold, rule := s.threshold(tiers[inv.KeyAccountID], value)
+			if !inv.LastActiveAt().Before(now.Add(-threshold)) {
+				report.Kept++
+				continue
+			}
+			purge = append(purge, *inv)
+			cutoffs = append(cutoffs, now.Add(-threshold))
+			candidates = append(candidates, model.RetentionCandidate{
+				RobloxUserID: inv.RobloxUserID,
+				KeyAccountID: inv.KeyAccountID,
+				KeyTier:      tiers[inv.KeyAccountID],
+				Value:        value,
+				LastActiveAt: inv.LastActiveAt(),
+				Threshold:    threshold.String(),
+				Rule:         rule,
+			})
+		}
+
+		if !dryRun && len(purge) > 0 {
+			// Nothing is deleted unless it is safely archived first
+			if s.newArchive != nil {
+				if archive == nil {
+					if archive, err = s.newArchive(s.config.Game, now); err != nil {
+						return report, fmt.Errorf("failed to start archive: %w", err)
+					}
+					report.ArchiveFile = archive.Name()
+				}
+				if err := archive.Append(purge); err != nil {
+					return report, err
+				}
+			}
+			for i := range candidates {
+				// The user may have synced since the page was read
+				deleted, err := retentionRepo.DeleteInventoryIfInactive(ctx, candidates[i].RobloxUserID, cutoffs[i])
+				if err != nil {
+					log.Printf("[CleanupScheduler] Failed to purge %s: %v", candidates[i].RobloxUserID, err)
+					continue
+				}
+				if deleted {
+					candidates[i].Purged = true
+					report.Purged++
+					s.unrank(ctx, candidates[i].RobloxUserID)
+				}
+			}
+		}
+
+		if room := maxReportedCandidates - len(report.Candidates); room > 0 {
+			if len(candidates) > room {
+				candidates = candidates[:room]
+			}
+			report.Candidates = append(report.Candidates, candidates...)
+		}
+
+		if len(page) < retentionPageSize {
+			return report, nil
+		}
+		after = page[len(page)-1].RobloxUserID
+	}
+}
+
+// unrank removes a purged user from the leaderboards. A restore ranks them again.
+func (s *CleanupScheduler) unrank(ctx context.Context, robloxUserID string) {
+	if s.leaderboards == nil {
+		return
+	}
+	if err := s.leaderboards.Remove(ctx, robloxUserID); err != nil {
+		log.Printf("[CleanupScheduler] Failed to remove %s from the leaderboards: %v", robloxUserID, err)
+	}
+}
+
+// threshold returns how long a user with the given key tier and inventory
+// value is kept after going inactive, and the rule that decided it. A key
+// tier threshold replaces the default; a value threshold can only extend it.
+func (s *CleanupScheduler) threshold(keyTier string, value float64) (time.Duration, string) {
+	threshold, rule := s.config.InactiveThreshold, "default"
+	if t, ok := s.config.KeyTierThresholds[keyTier]; ok && keyTier != "" {
+		threshold, rule = t, "key_tier="+keyTier
+	}
+	for _, vt := range s.config.ValueThresholds {
+		if value >= vt.MinValue && vt.Threshold > threshold {
+			threshold, rule = vt.Threshold, fmt.Sprintf("value>=%g", vt.MinValue)
+		}
+	}
+	return threshold, rule
+}
+
+// shortestThreshold is the threshold below which no user can be purged.
+func (s *CleanupScheduler) shortestThreshold() time.Duration {
+	shortest := s.config.InactiveThreshold
+	for _, t := range s.config.KeyTierThresholds {
+		if t < shortest {
+			shortest = t
+		}
+	}
+	return shortest
+}
+
+// keyTiersOf resolves the key tiers of a page of inventories when tier
+// thresholds are configured. Lookup failures leave every tier unknown.
+func (s *CleanupScheduler) keyTiersOf(ctx context.Context, page []model.RawInventory) map[int64]string {
+	if s.keyTiers == nil || len(s.config.KeyTierThresholds) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(page))
+	for i := range page {
+		if page[i].KeyAccountID != 0 {
+			ids = append(ids, page[i].KeyAccountID)
+		}
+	}
+	tiers, err := s.keyTiers.GetKeyTiers(ctx, ids)
+	if err != nil {
+		log.Printf("[CleanupScheduler] Failed to look up key tiers, using default thresholds: %v", err)
+		return nil
+	}
+	return tiers
+}
+
+// valueOf returns the inventory's value when value thresholds are configured.
+func (s *CleanupScheduler) valueOf(inv *model.RawInventory) float64 {
+	if s.valuator == nil || len(s.config.ValueThresholds) == 0 {
+		return 0
+	}
+	payload, err := decodeStoredPayload(inv.InventoryJSON)
+	if err != nil {
+		return 0
+	}
+	value, _ := s.valuator.Total(payload)
+	return value
+}
+
+// pruneHistory removes inventory snapshots past the retention period.
+func (s *CleanupScheduler) pruneHistory(ctx context.Context) {
+	historyRepo, ok := s.repo.(repository.InventoryHistoryRepository)
+	if !ok || s.config.HistoryRetention <= 0 {
+		return
+	}
+
+	if _, err := historyRepo.PruneSnapshots(ctx, s.config.HistoryRetention); err != nil {
+		log.Printf("[CleanupScheduler] Error pruning inventory history: %v", err)
+	}
+}
+
+// pruneValues removes inventory value points past the retention period.
+func (s *CleanupScheduler) pruneValues(ctx context.Context) {
+	valueRepo, ok := s.repo.(repository.InventoryValueRepository)
+	if !ok || s.config.ValueRetention <= 0 {
+		return
+	}
+
+	if _, err := valueRepo.PruneValues(ctx, s.config.ValueRetention); err != nil {
+		log.Printf("[CleanupScheduler] Error pruning inventory values: %v", err)
+	}
+}
+
+// pruneTombstones permanently deletes purged inventories past the grace period.
+func (s *CleanupScheduler) pruneTombstones(ctx context.Context) {
+	tombstoneRepo, ok := s.repo.(repository.InventoryTombstoneRepository)
+	if !ok || s.config.TombstoneGrace <= 0 {
+		return
+	}
+
+	if _, err := tombstoneRepo.PruneTombstones(ctx, s.config.TombstoneGrace); err != nil {
+		log.Printf("[CleanupScheduler] Error pruning inventory tombstones: %v", err)
+	}
 }
 
 // Stop stops the cleanup scheduler.
@@ -126,10 +462,14 @@ func (s *CleanupScheduler) Stop() {
 	})
 }
 
-// RunNow triggers an immediate cleanup run.
+// RunNow triggers an immediate cleanup run and returns how many inventories were purged.
 func (s *CleanupScheduler) RunNow() (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	return s.repo.DeleteInactiveUsers(ctx, s.config.InactiveThreshold)
+	report, err := s.Run(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	return int64(report.Purged), nil
 }
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/canonjson"
+)
+
+// diffIgnoredFields are entry fields that change without the item changing
+// (icon URLs are re-resolved by the client on every sync).
+var diffIgnoredFields = map[string]bool{"icon": true}
+
+// DiffInventories compares two inventories and returns the added, removed and
+// changed items plus coin and level deltas. Items are matched by category and
+// uuid; items without a uuid are matched by item ID and position.
+// A nil before is treated as an empty inventory.
+func DiffInventories(before, after *model.InventoryPayload) *model.InventoryDiff {
+	if before == nil {
+		before = &model.InventoryPayload{}
+	}
+	if after == nil {
+		after = &model.InventoryPayload{}
+	}
+
+	diff := &model.InventoryDiff{
+		Added:   []model.ItemChange{},
+		Removed: []model.ItemChange{},
+		Changed: []model.ItemChange{},
+		Coins:   statChange(before.Stats.Coins, after.Stats.Coins),
+		Level:   statChange(before.Stats.Level, after.Stats.Level),
+	}
+
+	for _, category := range model.InventoryCategories {
+		oldItems := indexEntries(before.Category(category))
+		newItems := indexEntries(after.Category(category))
+
+		for _, key := range oldItems.order {
+			oldEntry := oldItems.byKey[key]
+			newEntry, ok := newItems.byKey[key]
+			if !ok {
+				diff.Removed = append(diff.Removed, newItemChange(category, oldEntry, nil))
+				continue
+			}
+			if fields := changedFields(oldEntry, newEntry); len(fields) > 0 {
+				change := newItemChange(category, oldEntry, newEntry)
+				change.Fields = fields
+				diff.Changed = append(diff.Changed, change)
+			}
+		}
+		for _, key := range newItems.order {
+			if _, ok := oldItems.byKey[key]; !ok {
+				diff.Added = append(diff.Added, newItemChange(category, nil, newItems.byKey[key]))
+			}
+		}
+	}
+	return diff
+}
+
+// entryIndex holds a category's items keyed for matching, in payload order.
+type entryIndex struct {
+	order []string
+	byKey map[string]*model.InventoryEntry
+}
+
+func indexEntries(entries []model.InventoryEntry) entryIndex {
+	idx := entryIndex{byKey: make(map[string]*model.InventoryEntry, len(entries))}
+	occurrences := make(map[int64]int)
+	for i := range entries {
+		e := &entries[i]
+		key := "uuid:" + e.UUID
+		if e.UUID == "" {
+			occurrences[e.ID()]++
+			key = fmt.Sprintf("id:%d#%d", e.ID(), occurrences[e.ID()])
+		}
+		if _, dup := idx.byKey[key]; dup {
+			continue
+		}
+		idx.order = append(idx.order, key)
+		idx.byKey[key] = e
+	}
+	return idx
+}
+
+func newItemChange(category string, before, after *model.InventoryEntry) model.ItemChange {
+	ref := after
+	if ref == nil {
+		ref = before
+	}
+	return model.ItemChange{
+		Category: category,
+		UUID:     ref.UUID,
+		ItemID:   ref.ID(),
+		Name:     ref.Name,
+		Tier:     ref.Tier,
+		Before:   before,
+		After:    after,
+	}
+}
+
+// changedFields returns the JSON field names whose values differ.
+func changedFields(before, after *model.InventoryEntry) []string {
+	oldFields := entryFields(before)
+	newFields := entryFields(after)
+
+	var fields []string
+	for name, oldValue := range oldFields {
+		if diffIgnoredFields[name] {
+			continue
+		}
+		if newValue, ok := newFields[name]; !ok || !bytes.Equal(oldValue, newValue) {
+			fields = append(fields, name)
+		}
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok && !diffIgnoredFields[name] {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// entryFields returns the canonical JSON of every field set on an entry.
+func entryFields(e *model.InventoryEntry) map[string][]byte {
+	raw, _ := json.Marshal(e)
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(raw, &fields)
+
+	out := make(map[string][]byte, len(fields))
+	for name, value := range fields {
+		if canonical, err := canonjson.Canonicalize(value); err == nil {
+			out[name] = canonical
+		} else {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+func statChange(before, after float64) model.StatChange {
+	return model.StatChange{Before: before, After: after, Delta: after - before}
+}
+
+// decodeStoredPayload decodes a previously stored inventory. Unlike
+// ParseInventoryPayload it is lenient, since stored documents may predate
+// validation or carry values this version does not know (such as a new tier
+// name): such values are left empty and the rest of the document is kept.
+func decodeStoredPayload(rawJSON []byte) (*model.InventoryPayload, error) {
+	payload := &model.InventoryPayload{}
+	if len(rawJSON) == 0 {
+		return payload, nil
+	}
+	err := json.Unmarshal(rawJSON, payload)
+	if err == nil || isTypeError(err) {
+		return payload, nil
+	}
+
+	// A value rejected by a field's own decoder stops json.Unmarshal, so
+	// decode piece by piece, dropping only the values that fail
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rawJSON, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode stored inventory: %w", err)
+	}
+	payload = &model.InventoryPayload{}
+	for name, value := range fields {
+		switch {
+		case model.IsInventoryCategory(name):
+			var entries []json.RawMessage
+			if json.Unmarshal(value, &entries) != nil {
+				continue
+			}
+			items := make([]model.InventoryEntry, len(entries))
+			for i, entry := range entries {
+				decodeLenient(entry, &items[i])
+			}
+			payload.SetCategory(name, items)
+		case name == "player":
+			decodeLenient(value, &payload.Player)
+		case name == "stats":
+			decodeLenient(value, &payload.Stats)
+		case name == "synced_at":
+			_ = json.Unmarshal(value, &payload.SyncedAt)
+		}
+	}
+	return payload, nil
+}
+
+// decodeLenient decodes a JSON object into v, leaving out the fields whose
+// values v rejects. v is left empty if raw is not an object.
+func decodeLenient[T any](raw json.RawMessage, v *T) {
+	err := json.Unmarshal(raw, v)
+	if err == nil || isTypeError(err) {
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		*v = *new(T)
+		return
+	}
+	for name, value := range fields {
+		field, _ := json.Marshal(map[string]json.RawMessage{name: value})
+		var probe T
+		if err := json.Unmarshal(field, &probe); err != nil && !isTypeError(err) {
+			delete(fields, name)
+		}
+	}
+	kept, _ := json.Marshal(fields)
+	*v = *new(T)
+	_ = json.Unmarshal(kept, v)
+}
+
+// isTypeError reports whether err is a JSON value of the wrong type, which
+// json.Unmarshal skips over while decoding the rest.
+func isTypeError(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr)
+}
+
+// DiffLatest compares a user's current inventory with the previous stored version.
+// The current inventory is the buffered copy if one is pending, otherwise the
+// latest snapshot; the previous version comes from the history store.
+func (s *InventoryService) DiffLatest(ctx context.Context, robloxUserID string) (*model.InventoryDiffResult, error) {
+	if s.historyRepo == nil {
+		return nil, apierror.ServiceUnavailable("inventory history is not enabled")
+	}
+
+	latest, err := s.latestSnapshot(ctx, robloxUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A pending buffered copy is newer than anything in history
+	var current *model.InventorySnapshot
+	var currentSide *model.DiffSide
+	if s.buffer != nil {
+		if inv, err := s.buffer.Get(ctx, robloxUserID); err == nil && inv != nil {
+			hash, err := canonjson.Hash(inv.RawJSON)
+			if err == nil && (latest == nil || latest.ContentHash != hash) {
+				updatedAt := inv.UpdatedAt
+				current = &model.InventorySnapshot{InventoryJSON: inv.RawJSON, ContentHash: hash}
+				currentSide = &model.DiffSide{Source: "buffer", ContentHash: hash, CapturedAt: &updatedAt}
+			}
+		}
+	}
+
+	previous := latest
+	if current == nil {
+		if latest == nil {
+			return nil, apierror.NotFound("inventory not found")
+		}
+		current = latest
+		currentSide = snapshotSide(latest)
+		previous = nil
+		if latest.Version > 1 {
+			if previous, err = s.historyRepo.GetSnapshot(ctx, robloxUserID, latest.Version-1); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var previousJSON []byte
+	var previousSide *model.DiffSide
+	if previous != nil {
+		previousJSON = previous.InventoryJSON
+		previousSide = snapshotSide(previous)
+	}
+	return s.diffRaw(robloxUserID, previousJSON, current.InventoryJSON, previousSide, currentSide)
+}
+
+// latestSnapshot returns the newest stored version of a user's inventory, or nil.
+func (s *InventoryService) latestSnapshot(ctx context.Context, robloxUserID string) (*model.InventorySnapshot, error) {
+	versions, _, err := s.historyRepo.ListSnapshots(ctx, robloxUserID, 1, 0)
+	if err != nil || len(versions) == 0 {
+		return nil, err
+	}
+	return s.historyRepo.GetSnapshot(ctx, robloxUserID, versions[0].Version)
+}
+
+// DiffVersions compares two stored versions of a user's inventory.
+func (s *InventoryService) DiffVersions(ctx context.Context, robloxUserID string, from, to int64) (*model.InventoryDiffResult, error) {
+	before, err := s.GetHistoryVersion(ctx, robloxUserID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.GetHistoryVersion(ctx, robloxUserID, to)
+	if err != nil {
+		return nil, err
+	}
+	return s.diffRaw(robloxUserID, before.InventoryJSON, after.InventoryJSON, snapshotSide(before), snapshotSide(after))
+}
+
+// DiffPayloads compares two client-supplied inventory payloads.
+// Both are validated like a sync payload, with the service's payload parser.
+func (s *InventoryService) DiffPayloads(robloxUserID string, beforeJSON, afterJSON []byte) (*model.InventoryDiffResult, error) {
+	before, err := s.parsePayload(robloxUserID, beforeJSON)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.parsePayload(robloxUserID, afterJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &model.InventoryDiffResult{
+		RobloxUserID: robloxUserID,
+		From:         &model.DiffSide{Source: "payload"},
+		To:           &model.DiffSide{Source: "payload"},
+		Diff:         DiffInventories(before, after),
+	}, nil
+}
+
+func (s *InventoryService) diffRaw(robloxUserID string, beforeJSON, afterJSON []byte, from, to *model.DiffSide) (*model.InventoryDiffResult, error) {
+	before, err := decodeStoredPayload(beforeJSON)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeStoredPayload(afterJSON)
+	if err != nil {
+		return nil, err
+	}
+	return &model.InventoryDiffResult{
+		RobloxUserID: robloxUserID,
+		From:         from,
+		To:           to,
+		Diff:         DiffInventories(before, after),
+	}, nil
+}
+
+func snapshotSide(snap *model.InventorySnapshot) *model.DiffSide {
+	capturedAt := snap.CapturedAt
+	return &model.DiffSide{
+		Source:      "history",
+		Version:     snap.Version,
+		ContentHash: snap.ContentHash,
+		CapturedAt:  &capturedAt,
+	}
+}
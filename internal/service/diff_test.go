@@ -0,0 +1,184 @@
+package service
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+)
+
+func mustPayload(t *testing.T, raw string) *model.InventoryPayload {
+	t.Helper()
+	payload, err := decodeStoredPayload([]byte(raw))
+	if err != nil {
+		t.Fatalf("decodeStoredPayload(%s): %v", raw, err)
+	}
+	return payload
+}
+
+// changeKeys summarises changes as category/uuid-or-id for comparison.
+func changeKeys(changes []model.ItemChange) []string {
+	keys := []string{}
+	for _, c := range changes {
+		key := c.Category + "/" + c.UUID
+		if c.UUID == "" {
+			key = c.Category + "/#" + strconv.FormatInt(c.ItemID, 10)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestDiffInventories(t *testing.T) {
+	before := mustPayload(t, `{
+		"stats": {"coins": 100, "level": 5},
+		"fish": [
+			{"uuid": "a", "fish_id": 1, "tier": 3, "icon": "rbxassetid://1"},
+			{"uuid": "b", "fish_id": 2, "tier": 7},
+			{"uuid": "c", "fish_id": 3, "favorited": false}
+		],
+		"baits": [{"bait_id": 10, "quantity": 2}, {"bait_id": 10, "quantity": 5}]
+	}`)
+	after := mustPayload(t, `{
+		"stats": {"coins": 250, "level": 5},
+		"fish": [
+			{"uuid": "a", "fish_id": 1, "tier": 3, "icon": "rbxassetid://2"},
+			{"uuid": "c", "fish_id": 3, "favorited": true},
+			{"uuid": "d", "fish_id": 4, "tier": "Secret"}
+		],
+		"baits": [{"bait_id": 10, "quantity": 2}],
+		"rods": [{"rod_id": 20}]
+	}`)
+
+	diff := DiffInventories(before, after)
+
+	if got, want := changeKeys(diff.Added), []string{"fish/d", "rods/#20"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("added = %v; want %v", got, want)
+	}
+	// Items without a uuid match by item ID and position, so the second stack is gone
+	if got, want := changeKeys(diff.Removed), []string{"fish/b", "baits/#10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("removed = %v; want %v", got, want)
+	}
+	// A changed icon alone is not a change
+	if got, want := changeKeys(diff.Changed), []string{"fish/c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("changed = %v; want %v", got, want)
+	}
+	if got := diff.Changed[0].Fields; !reflect.DeepEqual(got, []string{"favorited"}) {
+		t.Errorf("changed fields = %v; want [favorited]", got)
+	}
+	if diff.Added[0].Tier != model.TierSecret {
+		t.Errorf("added tier = %d; want %d", diff.Added[0].Tier, model.TierSecret)
+	}
+
+	if diff.Coins != (model.StatChange{Before: 100, After: 250, Delta: 150}) {
+		t.Errorf("coins = %+v", diff.Coins)
+	}
+	if diff.Level != (model.StatChange{Before: 5, After: 5, Delta: 0}) {
+		t.Errorf("level = %+v", diff.Level)
+	}
+}
+
+func TestDiffInventoriesNil(t *testing.T) {
+	after := mustPayload(t, `{"stats": {"coins": 10}, "fish": [{"uuid": "a", "fish_id": 1}]}`)
+
+	diff := DiffInventories(nil, after)
+	if got := changeKeys(diff.Added); !reflect.DeepEqual(got, []string{"fish/a"}) {
+		t.Errorf("added = %v; want [fish/a]", got)
+	}
+	if diff.Coins.Delta != 10 {
+		t.Errorf("coins delta = %v; want 10", diff.Coins.Delta)
+	}
+
+	diff = DiffInventories(after, after)
+	if len(diff.Added)+len(diff.Removed)+len(diff.Changed) != 0 {
+		t.Errorf("diff of an inventory with itself = %+v; want empty", diff)
+	}
+}
+
+func TestDecodeStoredPayloadLegacy(t *testing.T) {
+	// Written before validation: an unknown tier name, an object where a
+	// string is expected, a string where a number is expected
+	payload := mustPayload(t, `{
+		"player": {"user_id": {"id": 1}, "username": "legacy"},
+		"stats": {"coins": "lots", "level": 12},
+		"fish": [
+			{"uuid": "a", "fish_id": 1, "tier": "Mythic+", "name": "Old Fish"},
+			{"uuid": "b", "fish_id": 2, "tier": 5, "variant_id": {"v": 1}},
+			{"uuid": "c", "fish_id": 3, "tier": 7}
+		],
+		"rods": {"not": "an array"},
+		"synced_at": 1700000000
+	}`)
+
+	if payload.Player.Username != "legacy" || payload.Player.UserID != "" {
+		t.Errorf("player = %+v; want username kept and user_id dropped", payload.Player)
+	}
+	if payload.Stats.Level != 12 {
+		t.Errorf("level = %v; want 12", payload.Stats.Level)
+	}
+	if len(payload.Fish) != 3 {
+		t.Fatalf("fish = %d items; want 3", len(payload.Fish))
+	}
+	if f := payload.Fish[0]; f.UUID != "a" || f.Name != "Old Fish" || f.Tier != 0 {
+		t.Errorf("fish[0] = %+v; want the unknown tier dropped and the rest kept", f)
+	}
+	if f := payload.Fish[1]; f.Tier != 5 || f.VariantID != "" {
+		t.Errorf("fish[1] = %+v; want tier 5 and no variant", f)
+	}
+	if payload.Fish[2].Tier != model.TierSecret {
+		t.Errorf("fish[2] tier = %d; want %d", payload.Fish[2].Tier, model.TierSecret)
+	}
+	if payload.Rods != nil {
+		t.Errorf("rods = %+v; want none", payload.Rods)
+	}
+	if payload.SyncedAt != 1700000000 {
+		t.Errorf("synced_at = %d", payload.SyncedAt)
+	}
+
+	// A legacy document diffs like any other
+	current := mustPayload(t, `{"fish": [{"uuid": "a", "fish_id": 1, "name": "Old Fish"}, {"uuid": "c", "fish_id": 3, "tier": 7}]}`)
+	diff := DiffInventories(payload, current)
+	if got := changeKeys(diff.Removed); !reflect.DeepEqual(got, []string{"fish/b"}) {
+		t.Errorf("removed = %v; want [fish/b]", got)
+	}
+	if len(diff.Changed) != 0 {
+		t.Errorf("changed = %+v; want none", diff.Changed)
+	}
+}
+
+func TestDecodeStoredPayloadInvalid(t *testing.T) {
+	if _, err := decodeStoredPayload([]byte(`{"fish":`)); err == nil {
+		t.Error("decodeStoredPayload of invalid JSON succeeded")
+	}
+	payload, err := decodeStoredPayload(nil)
+	if err != nil || payload == nil {
+		t.Errorf("decodeStoredPayload(nil) = %v, %v; want an empty payload", payload, err)
+	}
+}
+
+func TestDiffPayloadsParser(t *testing.T) {
+	// Valid for a game without a schema, not for Fish It
+	before := []byte(`{"stats": {"coins": "lots"}}`)
+	after := []byte(`{"stats": {"coins": "more"}}`)
+
+	repo, err := repository.NewMemoryInventoryRepository("", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryInventoryRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	svc := NewInventoryService(repo, nil)
+	if _, err := svc.DiffPayloads("100", before, after); err == nil {
+		t.Error("DiffPayloads with the Fish It parser succeeded; want a validation error")
+	}
+
+	svc.SetPayloadParser(ParseGenericPayload)
+	result, err := svc.DiffPayloads("100", before, after)
+	if err != nil {
+		t.Fatalf("DiffPayloads with the generic parser: %v", err)
+	}
+	if result.From.Source != "payload" || result.To.Source != "payload" {
+		t.Errorf("sides = %+v, %+v; want payloads", result.From, result.To)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+)
+
+// EventHook is called with the events derived from an accepted sync or patch,
+// after they have been stored in the event log (if any).
+type EventHook func(ctx context.Context, events []model.InventoryEvent)
+
+// SetEventLog enables the inventory event log. Every accepted sync or patch
+// is compared with the inventory it replaces and the differences are stored
+// as events.
+func (s *InventoryService) SetEventLog(eventLog repository.InventoryEventLog) {
+	s.eventLog = eventLog
+}
+
+// AddEventHook registers a hook run with the events of every accepted write.
+// Hooks run on the request path and should hand slow work off.
+func (s *InventoryService) AddEventHook(hook EventHook) {
+	s.eventHooks = append(s.eventHooks, hook)
+}
+
+// SetRareTier makes acquired items of at least the given tier also emit a
+// rare_catch event. Zero disables rare catch detection.
+func (s *InventoryService) SetRareTier(tier model.Tier) {
+	s.rareTier = tier
+}
+
+// eventsEnabled reports whether writes need to be diffed for events.
+func (s *InventoryService) eventsEnabled() bool {
+	return s.eventLog != nil || len(s.eventHooks) > 0
+}
+
+// previousForEvents returns the inventory a write is about to replace, or
+// nil if events are disabled or the user has none.
+func (s *InventoryService) previousForEvents(ctx context.Context, robloxUserID string) *model.RawInventory {
+	if !s.eventsEnabled() {
+		return nil
+	}
+	previous, _, err := s.currentInventory(ctx, robloxUserID)
+	if err != nil {
+		log.Printf("[InventoryService] Failed to load previous inventory of %s for events: %v", robloxUserID, err)
+		return nil
+	}
+	return previous
+}
+
+// recordEvents stores the events between previous and the accepted payload
+// and passes them to the event hooks. A user's first sync has no previous
+// version and records nothing, so a new user's whole inventory is not logged
+// as acquired. Failures are logged only; the sync itself has already succeeded.
+func (s *InventoryService) recordEvents(ctx context.Context, robloxUserID string, previous *model.RawInventory, payload *model.InventoryPayload, hash string) {
+	if !s.eventsEnabled() || previous == nil || previous.ContentHash == hash {
+		return
+	}
+	before, err := decodeStoredPayload(previous.InventoryJSON)
+	if err != nil {
+		log.Printf("[InventoryService] Skipping events for %s: %v", robloxUserID, err)
+		return
+	}
+	events := InventoryEvents(robloxUserID, DiffInventories(before, payload), hash, time.Now().UTC())
+	events = append(events, RareCatches(events, s.rareTier)...)
+	if len(events) == 0 {
+		return
+	}
+	if s.eventLog != nil {
+		if err := s.eventLog.InsertInventoryEvents(ctx, events); err != nil {
+			log.Printf("[InventoryService] Failed to record %d events for %s: %v", len(events), robloxUserID, err)
+		}
+	}
+	for _, hook := range s.eventHooks {
+		hook(ctx, events)
+	}
+}
+
+// RareCatches returns a rare_catch event for every item_acquired event of at
+// least minTier. A minTier of zero returns none.
+func RareCatches(events []model.InventoryEvent, minTier model.Tier) []model.InventoryEvent {
+	if minTier <= 0 {
+		return nil
+	}
+	var rare []model.InventoryEvent
+	for _, event := range events {
+		if event.Type == model.EventItemAcquired && event.Tier >= int(minTier) {
+			event.Type = model.EventRareCatch
+			rare = append(rare, event)
+		}
+	}
+	return rare
+}
+
+// InventoryEvents turns a diff into events: one per acquired or removed item,
+// one per item whose favorited flag flipped, and one each for a coin change
+// and a level increase.
+func InventoryEvents(robloxUserID string, diff *model.InventoryDiff, hash string, at time.Time) []model.InventoryEvent {
+	var events []model.InventoryEvent
+	itemEvent := func(eventType string, change model.ItemChange, entry *model.InventoryEntry) model.InventoryEvent {
+		return model.InventoryEvent{
+			RobloxUserID: robloxUserID,
+			Type:         eventType,
+			Category:     change.Category,
+			UUID:         change.UUID,
+			ItemID:       change.ItemID,
+			Name:         change.Name,
+			Tier:         int(change.Tier),
+			Quantity:     entry.Count(),
+			ContentHash:  hash,
+			CreatedAt:    at,
+		}
+	}
+	statEvent := func(eventType string, change model.StatChange) model.InventoryEvent {
+		before, after := change.Before, change.After
+		return model.InventoryEvent{
+			RobloxUserID: robloxUserID,
+			Type:         eventType,
+			Before:       &before,
+			After:        &after,
+			ContentHash:  hash,
+			CreatedAt:    at,
+		}
+	}
+
+	for _, change := range diff.Added {
+		events = append(events, itemEvent(model.EventItemAcquired, change, change.After))
+	}
+	for _, change := range diff.Removed {
+		events = append(events, itemEvent(model.EventItemRemoved, change, change.Before))
+	}
+	for _, change := range diff.Changed {
+		if change.Before.Favorited != change.After.Favorited {
+			event := itemEvent(model.EventFavoritedToggled, change, change.After)
+			favorited := change.After.Favorited
+			event.Favorited = &favorited
+			events = append(events, event)
+		}
+	}
+	if diff.Coins.Delta != 0 {
+		events = append(events, statEvent(model.EventCoinsChanged, diff.Coins))
+	}
+	if diff.Level.Delta > 0 {
+		events = append(events, statEvent(model.EventLevelUp, diff.Level))
+	}
+	return events
+}
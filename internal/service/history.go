@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/canonjson"
+)
+
+// NewHistoryRecorder returns a PersistHook that appends a snapshot for every
+// persisted inventory. The repository skips snapshots whose content hash
+// matches the user's latest version, so unchanged syncs add nothing.
+func NewHistoryRecorder(repo repository.InventoryHistoryRepository) PersistHook {
+	return func(ctx context.Context, items []model.InventoryItem) {
+		snapshots := make([]model.InventorySnapshot, 0, len(items))
+		for _, item := range items {
+			hash, err := canonjson.Hash(item.RawJSON)
+			if err != nil {
+				log.Printf("[HistoryRecorder] Skipping snapshot for %s: %v", item.RobloxUserID, err)
+				continue
+			}
+			snapshots = append(snapshots, model.InventorySnapshot{
+				RobloxUserID:  item.RobloxUserID,
+				KeyAccountID:  item.KeyAccountID,
+				ContentHash:   hash,
+				InventoryJSON: item.RawJSON,
+				CapturedAt:    item.SyncedAt,
+			})
+		}
+
+		appended, err := repo.AppendSnapshots(ctx, snapshots)
+		if err != nil {
+			log.Printf("[HistoryRecorder] Failed to append snapshots: %v", err)
+			return
+		}
+		if appended > 0 {
+			log.Printf("[HistoryRecorder] Appended %d/%d inventory snapshots", appended, len(snapshots))
+		}
+	}
+}
+
+// SetHistoryRepository enables the inventory history endpoints.
+func (s *InventoryService) SetHistoryRepository(repo repository.InventoryHistoryRepository) {
+	s.historyRepo = repo
+}
+
+// ListHistory returns snapshot metadata for a user, newest first.
+func (s *InventoryService) ListHistory(ctx context.Context, robloxUserID string, limit, offset int) ([]model.InventorySnapshot, int64, error) {
+	if s.historyRepo == nil {
+		return nil, 0, apierror.ServiceUnavailable("inventory history is not enabled")
+	}
+	return s.historyRepo.ListSnapshots(ctx, robloxUserID, limit, offset)
+}
+
+// GetHistoryVersion returns a specific version of a user's inventory.
+func (s *InventoryService) GetHistoryVersion(ctx context.Context, robloxUserID string, version int64) (*model.InventorySnapshot, error) {
+	if s.historyRepo == nil {
+		return nil, apierror.ServiceUnavailable("inventory history is not enabled")
+	}
+	snap, err := s.historyRepo.GetSnapshot(ctx, robloxUserID, version)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, apierror.NotFound("inventory version not found")
+	}
+	return snap, nil
+}
+
+// GetHistoryAt returns the version of a user's inventory that was current at t.
+func (s *InventoryService) GetHistoryAt(ctx context.Context, robloxUserID string, t time.Time) (*model.InventorySnapshot, error) {
+	if s.historyRepo == nil {
+		return nil, apierror.ServiceUnavailable("inventory history is not enabled")
+	}
+	snap, err := s.historyRepo.GetSnapshotAt(ctx, robloxUserID, t)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, apierror.NotFound("no inventory version at the requested time")
+	}
+	return snap, nil
+}
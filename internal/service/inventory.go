@@ -2,18 +2,42 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
 	"time"
 
 	"vinzhub-rest-api-v2/internal/cache"
+	"vinzhub-rest-api-v2/internal/catalog"
 	"vinzhub-rest-api-v2/internal/model"
 	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/canonjson"
 )
 
+// PersistHook is called after inventories have been written to the repository,
+// either by a buffer flush or by a direct (unbuffered) sync.
+type PersistHook func(ctx context.Context, items []model.InventoryItem)
+
+// PayloadParser decodes and validates a sync payload for robloxUserID.
+type PayloadParser func(robloxUserID string, rawJSON []byte) (*model.InventoryPayload, error)
+
 // InventoryService handles inventory business logic.
 type InventoryService struct {
 	inventoryRepo  repository.InventoryRepository
 	keyAccountRepo repository.KeyAccountRepository
 	buffer         *cache.RedisInventoryBuffer
+	historyRepo    repository.InventoryHistoryRepository
+	catalog        *catalog.Catalog
+	valuator       *Valuator
+	persistHooks   []PersistHook
+	parsePayload   PayloadParser
+	eventLog       repository.InventoryEventLog
+	eventHooks     []EventHook
+	rareTier       model.Tier
+
+	syncsTotal     atomic.Int64
+	syncsUnchanged atomic.Int64
 }
 
 // NewInventoryService creates a new inventory service.
@@ -28,6 +52,7 @@ func NewInventoryService(
 	return &InventoryService{
 		inventoryRepo:  inventoryRepo,
 		keyAccountRepo: keyAccountRepo,
+		parsePayload:   ParseInventoryPayload,
 	}
 }
 
@@ -45,6 +70,7 @@ func NewInventoryServiceWithBuffer(
 		inventoryRepo:  inventoryRepo,
 		keyAccountRepo: keyAccountRepo,
 		buffer:         buffer,
+		parsePayload:   ParseInventoryPayload,
 	}
 }
 
@@ -53,22 +79,228 @@ func (s *InventoryService) SetBuffer(buffer *cache.RedisInventoryBuffer) {
 	s.buffer = buffer
 }
 
-// SyncRawInventory stores raw JSON inventory data.
+// SetCatalog sets the item catalog used to enrich inventory reads.
+func (s *InventoryService) SetCatalog(c *catalog.Catalog) {
+	s.catalog = c
+}
+
+// SetPayloadParser replaces the payload validation used by syncs, patches and
+// payload diffs.
+// The default is ParseInventoryPayload (the fishit schema).
+func (s *InventoryService) SetPayloadParser(parse PayloadParser) {
+	s.parsePayload = parse
+}
+
+// AddPersistHook registers a hook run after inventories are written to the
+// repository: directly, or by a flush of the buffer when the buffer was
+// created with FlushFunc.
+func (s *InventoryService) AddPersistHook(hook PersistHook) {
+	s.persistHooks = append(s.persistHooks, hook)
+}
+
+// SyncRawInventory validates and stores raw JSON inventory data.
 // If buffer is set, writes to Redis first (fast), otherwise direct to DB.
-func (s *InventoryService) SyncRawInventory(ctx context.Context, robloxUserID string, rawJSON []byte) error {
-	// Get key account ID (optional - can be 0 if not linked or repo unavailable)
-	var keyAccountID int64
-	if s.keyAccountRepo != nil {
-		keyAccountID, _ = s.keyAccountRepo.GetKeyAccountByRobloxUser(ctx, robloxUserID)
+// The raw bytes are stored as sent, including any unknown top-level keys.
+// A non-empty ifMatch makes the write conditional on the stored content hash.
+// If the content is identical to the stored inventory, nothing is rewritten;
+// only the last seen time is bumped and the status is "unchanged".
+func (s *InventoryService) SyncRawInventory(ctx context.Context, robloxUserID string, rawJSON []byte, ifMatch string) (*model.SyncResult, error) {
+	payload, err := s.parsePayload(robloxUserID, rawJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload.UnknownFields) > 0 {
+		log.Printf("[InventoryService] Sync for %s carries unknown fields: %v", robloxUserID, payload.UnknownFields)
+	}
+
+	hash, _ := canonjson.Hash(rawJSON)
+	keyAccountID := s.lookupKeyAccount(ctx, robloxUserID)
+	previous := s.previousForEvents(ctx, robloxUserID)
+
+	unchanged := false
+	switch {
+	case ifMatch != "":
+		unchanged, err = s.storeIfMatch(ctx, keyAccountID, robloxUserID, rawJSON, hash, ifMatch)
+	case s.touchIfUnchanged(ctx, robloxUserID, hash):
+		unchanged = true
+	case s.buffer != nil:
+		// If buffer is available, use write-behind caching
+		err = s.buffer.Add(ctx, keyAccountID, robloxUserID, rawJSON, hash)
+	default:
+		// Fallback to direct DB write
+		err = s.persistDirect(ctx, keyAccountID, robloxUserID, rawJSON, hash)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.syncsTotal.Add(1)
+	status := model.SyncStatusSynced
+	if unchanged {
+		s.syncsUnchanged.Add(1)
+		status = model.SyncStatusUnchanged
+	} else {
+		s.recordEvents(ctx, robloxUserID, previous, payload, hash)
+	}
+	return &model.SyncResult{
+		Status:        status,
+		Size:          len(rawJSON),
+		ContentHash:   hash,
+		UnknownFields: payload.UnknownFields,
+	}, nil
+}
+
+// SyncStats returns sync counts since startup, for the admin dashboard.
+func (s *InventoryService) SyncStats() model.SyncStats {
+	stats := model.SyncStats{
+		Total:     s.syncsTotal.Load(),
+		Unchanged: s.syncsUnchanged.Load(),
+	}
+	if stats.Total > 0 {
+		stats.DedupeRatio = float64(stats.Unchanged) / float64(stats.Total)
+	}
+	return stats
+}
+
+// touchIfUnchanged reports whether hash matches the user's last synced
+// inventory, bumping its last seen time if so. The last hash comes from the
+// buffer's hash cache, falling back to the repository. Errors are logged and
+// treated as changed, so the sync is written normally.
+func (s *InventoryService) touchIfUnchanged(ctx context.Context, robloxUserID, hash string) bool {
+	if hash == "" {
+		return false
 	}
+	lastSeenRepo, _ := s.inventoryRepo.(repository.InventoryLastSeenRepository)
 
-	// If buffer is available, use write-behind caching
+	last := ""
 	if s.buffer != nil {
-		return s.buffer.Add(ctx, keyAccountID, robloxUserID, rawJSON)
+		cached, err := s.buffer.GetContentHash(ctx, robloxUserID)
+		if err != nil {
+			log.Printf("[InventoryService] Failed to read cached content hash for %s: %v", robloxUserID, err)
+		}
+		last = cached
+	}
+	if last == "" && lastSeenRepo != nil {
+		stored, err := lastSeenRepo.GetContentHash(ctx, robloxUserID)
+		if err != nil {
+			log.Printf("[InventoryService] Failed to read content hash for %s: %v", robloxUserID, err)
+			return false
+		}
+		last = stored
+		if last != "" && s.buffer != nil {
+			_ = s.buffer.RememberContentHash(ctx, robloxUserID, last)
+		}
+	}
+	if last != hash {
+		return false
+	}
+
+	// The cached hash can outlive the stored row (e.g. after inactive cleanup),
+	// so only report unchanged if the inventory still exists somewhere.
+	if lastSeenRepo != nil {
+		touched, err := lastSeenRepo.TouchInventory(ctx, robloxUserID, time.Now())
+		if err != nil {
+			log.Printf("[InventoryService] Failed to touch inventory for %s: %v", robloxUserID, err)
+			return false
+		}
+		if touched {
+			return true
+		}
+	}
+	if s.buffer != nil {
+		pending, err := s.buffer.IsPending(ctx, robloxUserID)
+		return err == nil && pending
+	}
+	return false
+}
+
+// storeIfMatch writes an inventory only if the stored one's content hash
+// matches ifMatch. Reports true if the content was unchanged.
+func (s *InventoryService) storeIfMatch(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, hash, ifMatch string) (bool, error) {
+	current, token, err := s.currentInventory(ctx, robloxUserID)
+	if err != nil {
+		return false, err
+	}
+	if current == nil || !etagMatches(ifMatch, current.ContentHash) {
+		return false, apierror.PreconditionFailed("inventory does not match If-Match")
+	}
+	if current.ContentHash == hash && s.touchIfUnchanged(ctx, robloxUserID, hash) {
+		return true, nil
+	}
+
+	written, err := s.writeIfUnchanged(ctx, keyAccountID, robloxUserID, rawJSON, hash, current.ContentHash, token)
+	if err != nil {
+		return false, err
+	}
+	if !written {
+		return false, apierror.PreconditionFailed("inventory does not match If-Match")
+	}
+	return false, nil
+}
+
+// writeIfUnchanged stores an inventory only if the user's latest one is still
+// the one currentInventory returned, with content hash baseHash and token.
+// The check is atomic with the write: the buffer compares the token in Redis,
+// and without a buffer the repository compares the hash as it writes.
+func (s *InventoryService) writeIfUnchanged(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, hash, baseHash, token string) (bool, error) {
+	if s.buffer != nil {
+		return s.buffer.AddIfUnchanged(ctx, keyAccountID, robloxUserID, rawJSON, hash, token)
+	}
+
+	conditional, ok := s.inventoryRepo.(repository.InventoryConditionalRepository)
+	if !ok {
+		return false, fmt.Errorf("conditional writes are not supported by this storage backend")
+	}
+	written, err := conditional.ReplaceInventoryIfHash(ctx, keyAccountID, robloxUserID, rawJSON, baseHash)
+	if err != nil || !written {
+		return false, err
+	}
+	s.persisted(ctx, keyAccountID, robloxUserID, rawJSON, hash)
+	return true, nil
+}
+
+// keyAccountContextKey carries the key account a request's writes are attributed to.
+type keyAccountContextKey struct{}
+
+// WithKeyAccount returns a context whose inventory writes are attributed to
+// keyAccountID instead of the key account looked up for the Roblox user.
+// Session tokens are bound to one key account, so auth sets this for them.
+func WithKeyAccount(ctx context.Context, keyAccountID int64) context.Context {
+	return context.WithValue(ctx, keyAccountContextKey{}, keyAccountID)
+}
+
+// lookupKeyAccount returns the key account linked to a Roblox user, or the
+// one set by WithKeyAccount. It is optional and 0 if not linked or the repo
+// is unavailable.
+func (s *InventoryService) lookupKeyAccount(ctx context.Context, robloxUserID string) int64 {
+	if keyAccountID, ok := ctx.Value(keyAccountContextKey{}).(int64); ok && keyAccountID != 0 {
+		return keyAccountID
+	}
+	if s.keyAccountRepo == nil {
+		return 0
+	}
+	keyAccountID, _ := s.keyAccountRepo.GetKeyAccountByRobloxUser(ctx, robloxUserID)
+	return keyAccountID
+}
+
+// persistDirect writes an inventory straight to the repository and runs the persist hooks.
+func (s *InventoryService) persistDirect(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, hash string) error {
+	if err := s.inventoryRepo.UpsertRawInventory(ctx, keyAccountID, robloxUserID, rawJSON); err != nil {
+		return err
 	}
+	s.persisted(ctx, keyAccountID, robloxUserID, rawJSON, hash)
+	return nil
+}
 
-	// Fallback to direct DB write
-	return s.inventoryRepo.UpsertRawInventory(ctx, keyAccountID, robloxUserID, rawJSON)
+// persisted runs the persist hooks for an inventory written outside the buffer.
+func (s *InventoryService) persisted(ctx context.Context, keyAccountID int64, robloxUserID string, rawJSON []byte, hash string) {
+	runPersistHooks(ctx, s.persistHooks, []model.InventoryItem{{
+		KeyAccountID: keyAccountID,
+		RobloxUserID: robloxUserID,
+		RawJSON:      rawJSON,
+		ContentHash:  hash,
+		SyncedAt:     time.Now(),
+	}})
 }
 
 // GetRawInventory retrieves raw JSON inventory data.
@@ -85,18 +317,129 @@ func (s *InventoryService) GetRawInventory(ctx context.Context, robloxUserID str
 	return s.inventoryRepo.GetRawInventory(ctx, robloxUserID)
 }
 
+// GetInventory retrieves a user's inventory with its content hash, buffered
+// copy first. Returns nil if the user has no inventory.
+func (s *InventoryService) GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error) {
+	inv, _, err := s.currentInventory(ctx, robloxUserID)
+	return inv, err
+}
+
+// currentInventory returns the user's latest inventory (buffered copy first,
+// then the repository) and, with a buffer, its token for AddIfUnchanged.
+// Returns nil if the user has no inventory.
+func (s *InventoryService) currentInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, string, error) {
+	buffered, token, err := s.bufferedInventory(ctx, robloxUserID)
+	if err != nil || buffered != nil {
+		return buffered, token, err
+	}
+
+	if s.inventoryRepo == nil {
+		return nil, "", nil
+	}
+	inv, err := s.inventoryRepo.GetInventory(ctx, robloxUserID)
+	if err != nil || inv == nil || s.buffer == nil {
+		return inv, "", err
+	}
+	return inv, cache.StoredToken(inv.ContentHash), nil
+}
+
+// bufferedInventory returns the user's pending buffered inventory and its
+// token, or nil if nothing is buffered.
+func (s *InventoryService) bufferedInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, string, error) {
+	if s.buffer == nil {
+		return nil, "", nil
+	}
+	buffered, token, err := s.buffer.GetVersioned(ctx, robloxUserID)
+	if err != nil || buffered == nil {
+		return nil, "", err
+	}
+	hash := buffered.ContentHash
+	if hash == "" {
+		// Entries buffered before hashes were stored
+		hash, _ = canonjson.Hash(buffered.RawJSON)
+	}
+	return &model.RawInventory{
+		KeyAccountID:  buffered.KeyAccountID,
+		RobloxUserID:  buffered.RobloxUserID,
+		InventoryJSON: buffered.RawJSON,
+		ContentHash:   hash,
+		SyncedAt:      buffered.UpdatedAt,
+	}, token, nil
+}
+
+// EnrichInventory fills in catalog data (name, tier, icon, price) for items
+// that were synced with only an ID.
+func (s *InventoryService) EnrichInventory(rawJSON []byte) ([]byte, error) {
+	if s.catalog == nil {
+		return nil, apierror.ServiceUnavailable("item catalog is not enabled")
+	}
+	enriched, err := s.catalog.Enrich(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enrich inventory: %w", err)
+	}
+	return enriched, nil
+}
+
 // CreateFlushFunc creates a flush function for the Redis buffer.
-func CreateFlushFunc(repo repository.InventoryRepository) cache.FlushFunc {
-	return func(ctx context.Context, items []*model.BufferedInventory) error {
-		inventoryItems := make([]model.InventoryItem, len(items))
-		for i, item := range items {
-			inventoryItems[i] = model.InventoryItem{
-				KeyAccountID: item.KeyAccountID,
-				RobloxUserID: item.RobloxUserID,
-				RawJSON:      item.RawJSON,
-				SyncedAt:     item.UpdatedAt,
-			}
+// hooks run after each batch has been written, for the items written.
+func CreateFlushFunc(repo repository.InventoryRepository, hooks ...PersistHook) cache.FlushFunc {
+	return func(ctx context.Context, items []*model.BufferedInventory) (*model.BatchResult, error) {
+		return flushItems(ctx, repo, hooks, items)
+	}
+}
+
+// FlushFunc creates a flush function for the service's Redis buffer that
+// runs the service's persist hooks, so buffered writes and writes made
+// outside the buffer (such as restores) run the same hooks.
+func (s *InventoryService) FlushFunc() cache.FlushFunc {
+	return func(ctx context.Context, items []*model.BufferedInventory) (*model.BatchResult, error) {
+		return flushItems(ctx, s.inventoryRepo, s.persistHooks, items)
+	}
+}
+
+// flushItems writes buffered items to repo and runs hooks for those written.
+func flushItems(ctx context.Context, repo repository.InventoryRepository, hooks []PersistHook, items []*model.BufferedInventory) (*model.BatchResult, error) {
+	inventoryItems := make([]model.InventoryItem, len(items))
+	for i, item := range items {
+		inventoryItems[i] = model.InventoryItem{
+			KeyAccountID: item.KeyAccountID,
+			RobloxUserID: item.RobloxUserID,
+			RawJSON:      item.RawJSON,
+			ContentHash:  item.ContentHash,
+			SyncedAt:     item.UpdatedAt,
 		}
-		return repo.BatchUpsertRawInventory(ctx, inventoryItems)
+	}
+	result, err := repo.BatchUpsertRawInventory(ctx, inventoryItems)
+	if err != nil {
+		return nil, err
+	}
+	if written := writtenItems(inventoryItems, result); len(written) > 0 {
+		runPersistHooks(ctx, hooks, written)
+	}
+	return result, nil
+}
+
+// writtenItems returns the items result reports written.
+func writtenItems(items []model.InventoryItem, result *model.BatchResult) []model.InventoryItem {
+	if len(result.Succeeded) == len(items) {
+		return items
+	}
+	ok := make(map[string]bool, len(result.Succeeded))
+	for _, userID := range result.Succeeded {
+		ok[userID] = true
+	}
+	written := make([]model.InventoryItem, 0, len(result.Succeeded))
+	for _, item := range items {
+		if ok[item.RobloxUserID] {
+			written = append(written, item)
+		}
+	}
+	return written
+}
+
+// runPersistHooks runs hooks in registration order.
+func runPersistHooks(ctx context.Context, hooks []PersistHook, items []model.InventoryItem) {
+	for _, hook := range hooks {
+		hook(ctx, items)
 	}
 }
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// maxValidationErrors caps the number of field errors reported for one payload.
+const maxValidationErrors = 50
+
+// ParseInventoryPayload decodes and validates a sync payload for robloxUserID.
+// Malformed payloads are rejected with apierror.ValidationError listing the
+// offending fields. Unknown top-level keys are not an error: they are recorded
+// in UnknownFields so newer clients keep working against older servers.
+func ParseInventoryPayload(robloxUserID string, rawJSON []byte) (*model.InventoryPayload, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(rawJSON, &top); err != nil || top == nil {
+		return nil, apierror.ValidationError("invalid inventory payload",
+			apierror.FieldError{Field: "body", Message: "must be a JSON object"})
+	}
+
+	v := &payloadValidator{}
+	payload := &model.InventoryPayload{}
+
+	keys := make([]string, 0, len(top))
+	for key := range top {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		raw := top[key]
+		switch {
+		case key == "player":
+			v.decode(key, raw, &payload.Player)
+		case key == "stats":
+			v.decode(key, raw, &payload.Stats)
+		case key == "synced_at":
+			v.decode(key, raw, &payload.SyncedAt)
+		case model.IsInventoryCategory(key):
+			payload.SetCategory(key, v.decodeCategory(key, raw))
+		default:
+			payload.UnknownFields = append(payload.UnknownFields, key)
+		}
+	}
+
+	if uid := string(payload.Player.UserID); uid != "" && uid != robloxUserID {
+		v.add("player.user_id", fmt.Sprintf("does not match roblox_user_id %s", robloxUserID))
+	}
+	if payload.Stats.Coins < 0 {
+		v.add("stats.coins", "must not be negative")
+	}
+	if payload.Stats.Level < 0 {
+		v.add("stats.level", "must not be negative")
+	}
+
+	if len(v.errors) > 0 {
+		return nil, apierror.ValidationError("invalid inventory payload", v.errors...)
+	}
+	return payload, nil
+}
+
+// ParseGenericPayload accepts any JSON object as an inventory. It is the
+// payload parser for games without a server-side schema; the returned payload
+// carries no categories, so catalog features see an empty inventory.
+func ParseGenericPayload(robloxUserID string, rawJSON []byte) (*model.InventoryPayload, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(rawJSON, &top); err != nil || top == nil {
+		return nil, apierror.ValidationError("invalid inventory payload",
+			apierror.FieldError{Field: "body", Message: "must be a JSON object"})
+	}
+	return &model.InventoryPayload{}, nil
+}
+
+// payloadValidator accumulates field errors while decoding a payload.
+type payloadValidator struct {
+	errors []apierror.FieldError
+}
+
+func (v *payloadValidator) add(field, message string) {
+	if len(v.errors) < maxValidationErrors {
+		v.errors = append(v.errors, apierror.FieldError{Field: field, Message: message})
+	}
+}
+
+// decode unmarshals raw into target, recording a field error on failure.
+func (v *payloadValidator) decode(field string, raw json.RawMessage, target interface{}) bool {
+	if err := json.Unmarshal(raw, target); err != nil {
+		v.add(fieldPath(field, err), describeDecodeError(err))
+		return false
+	}
+	return true
+}
+
+// decodeCategory decodes and checks the items of one category.
+func (v *payloadValidator) decodeCategory(category string, raw json.RawMessage) []model.InventoryEntry {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil
+	}
+
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err != nil {
+		v.add(category, "must be an array of items")
+		return nil
+	}
+
+	items := make([]model.InventoryEntry, 0, len(elems))
+	seen := make(map[string]int, len(elems))
+	for i, elem := range elems {
+		path := fmt.Sprintf("%s[%d]", category, i)
+
+		trimmed := bytes.TrimSpace(elem)
+		if len(trimmed) == 0 || trimmed[0] != '{' {
+			v.add(path, "must be an object")
+			continue
+		}
+
+		var item model.InventoryEntry
+		if err := json.Unmarshal(elem, &item); err != nil {
+			v.add(path+"."+locateItemField(elem, err), describeDecodeError(err))
+			continue
+		}
+
+		if item.Quantity < 0 {
+			v.add(path+".quantity", "must not be negative")
+		}
+		if item.Price < 0 {
+			v.add(path+".price", "must not be negative")
+		}
+		if item.Tier < 0 || item.Tier > model.MaxTier {
+			v.add(path+".tier", fmt.Sprintf("must be between 1 and %d", model.MaxTier))
+		}
+		if item.UUID != "" {
+			if first, dup := seen[item.UUID]; dup {
+				v.add(path+".uuid", fmt.Sprintf("duplicates %s[%d].uuid", category, first))
+			} else {
+				seen[item.UUID] = i
+			}
+		}
+
+		items = append(items, item)
+	}
+	return items
+}
+
+// locateItemField finds which key of an item object failed to decode.
+// encoding/json only reports the field for type mismatches, so custom
+// unmarshalers (tier, variant_id) are located by decoding keys one at a time.
+func locateItemField(elem json.RawMessage, err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return typeErr.Field
+	}
+
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(elem, &fields) != nil {
+		return "?"
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		single, _ := json.Marshal(map[string]json.RawMessage{k: fields[k]})
+		var probe model.InventoryEntry
+		if json.Unmarshal(single, &probe) != nil {
+			return k
+		}
+	}
+	return "?"
+}
+
+// fieldPath extends field with the nested field reported by encoding/json, if any.
+func fieldPath(field string, err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return field + "." + typeErr.Field
+	}
+	return field
+}
+
+// describeDecodeError turns a decoding error into a client-facing message.
+func describeDecodeError(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Sprintf("must be of type %s, got %s", jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)
+	}
+	return err.Error()
+}
+
+// jsonTypeName maps a Go kind to the JSON type a client would send.
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "slice", "array":
+		return "array"
+	case "struct", "map":
+		return "object"
+	default:
+		return "number"
+	}
+}
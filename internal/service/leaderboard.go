@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/uid"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LeaderboardRedisKeyPrefix is the Redis key prefix for leaderboard sorted sets.
+// Each metric is one sorted set keyed by roblox_user_id.
+const LeaderboardRedisKeyPrefix = "vinzhub:fishit:leaderboard:"
+
+// leaderboardRebuildBatch is how many users are written per pipeline during a rebuild.
+const leaderboardRebuildBatch = 500
+
+// leaderboardRebuildTTL bounds how long a rebuild that died keeps scores
+// being written to its staging keys. Every batch of a running rebuild renews it.
+const leaderboardRebuildTTL = 10 * time.Minute
+
+// While a rebuild is running (its marker key exists), score updates and
+// removals also go to the staging keys, so the scan cannot lose them. Removed
+// users are remembered until the swap, since the scan may already have read them.
+// KEYS are the live keys, the staging keys in the same order, the marker and
+// the removed set.
+
+// scoreScript sets ARGV[1]'s score on every board to ARGV[2...].
+var scoreScript = redis.NewScript(`
+	local n = #ARGV - 1
+	local rebuilding = redis.call("EXISTS", KEYS[2*n+1]) == 1
+	for i = 1, n do
+		redis.call("ZADD", KEYS[i], ARGV[i+1], ARGV[1])
+		if rebuilding then
+			redis.call("ZADD", KEYS[n+i], ARGV[i+1], ARGV[1])
+		end
+	end
+	if rebuilding then
+		redis.call("SREM", KEYS[2*n+2], ARGV[1])
+	end
+	return 1
+`)
+
+// removeScript drops ARGV[1] from every board.
+var removeScript = redis.NewScript(`
+	local n = (#KEYS - 2) / 2
+	local rebuilding = redis.call("EXISTS", KEYS[2*n+1]) == 1
+	for i = 1, n do
+		redis.call("ZREM", KEYS[i], ARGV[1])
+		if rebuilding then
+			redis.call("ZREM", KEYS[n+i], ARGV[1])
+		end
+	end
+	if rebuilding then
+		redis.call("SADD", KEYS[2*n+2], ARGV[1])
+	end
+	return 1
+`)
+
+// swapScript replaces the live boards with the staging ones, less the users
+// removed during the rebuild, and ends the rebuild. An empty staging board was
+// never created, so its live board is dropped.
+var swapScript = redis.NewScript(`
+	local n = (#KEYS - 2) / 2
+	local removed = redis.call("SMEMBERS", KEYS[2*n+2])
+	for i = 1, n do
+		for _, member in ipairs(removed) do
+			redis.call("ZREM", KEYS[n+i], member)
+		end
+		if redis.call("EXISTS", KEYS[n+i]) == 1 then
+			redis.call("RENAME", KEYS[n+i], KEYS[i])
+		else
+			redis.call("DEL", KEYS[i])
+		end
+	end
+	redis.call("DEL", KEYS[2*n+1], KEYS[2*n+2])
+	return 1
+`)
+
+// unlockScript releases the rebuild lock KEYS[1] if it is still held with token ARGV[1].
+var unlockScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+// LeaderboardService maintains per-metric rankings in Redis sorted sets.
+// Scores are updated incrementally as inventories are persisted; Rebuild
+// recomputes everything from the inventory repository.
+type LeaderboardService struct {
+	redis         *redis.Client
+	inventoryRepo repository.InventoryRepository
+	valuator      *Valuator
+}
+
+// NewLeaderboardService creates a new leaderboard service. The valuator prices
+// the inventory_value board; if nil, client-reported prices are used.
+func NewLeaderboardService(redisClient *redis.Client, inventoryRepo repository.InventoryRepository, valuator *Valuator) *LeaderboardService {
+	if valuator == nil {
+		valuator = NewValuator(nil, ValuationConfig{})
+	}
+	return &LeaderboardService{
+		redis:         redisClient,
+		inventoryRepo: inventoryRepo,
+		valuator:      valuator,
+	}
+}
+
+func leaderboardKey(metric string) string {
+	return LeaderboardRedisKeyPrefix + metric
+}
+
+func leaderboardStagingKey(metric string) string {
+	return leaderboardKey(metric) + ":rebuild"
+}
+
+// leaderboardScriptKeys returns the KEYS of the leaderboard scripts.
+func leaderboardScriptKeys() []string {
+	keys := make([]string, 0, 2*len(model.LeaderboardMetrics)+2)
+	for _, metric := range model.LeaderboardMetrics {
+		keys = append(keys, leaderboardKey(metric))
+	}
+	for _, metric := range model.LeaderboardMetrics {
+		keys = append(keys, leaderboardStagingKey(metric))
+	}
+	return append(keys, LeaderboardRedisKeyPrefix+"rebuilding", LeaderboardRedisKeyPrefix+"rebuild:removed")
+}
+
+// LeaderboardScores computes every leaderboard metric for an inventory.
+func LeaderboardScores(payload *model.InventoryPayload, valuator *Valuator) map[string]float64 {
+	var secretFish float64
+	for _, fish := range payload.Fish {
+		if fish.Tier == model.TierSecret {
+			secretFish += fish.Count()
+		}
+	}
+	value, _ := valuator.Total(payload)
+
+	return map[string]float64{
+		model.MetricCoins:           payload.Stats.Coins,
+		model.MetricLevel:           payload.Stats.Level,
+		model.MetricSecretFishCount: secretFish,
+		model.MetricInventoryValue:  value,
+	}
+}
+
+// PersistHook returns a hook that updates the leaderboards for persisted inventories.
+func (s *LeaderboardService) PersistHook() PersistHook {
+	return func(ctx context.Context, items []model.InventoryItem) {
+		if _, err := s.update(ctx, items); err != nil {
+			log.Printf("[LeaderboardService] Failed to update leaderboards: %v", err)
+		}
+	}
+}
+
+// update writes the scores of items to the leaderboards, and to the staging
+// boards of a running rebuild. Returns how many users were scored.
+func (s *LeaderboardService) update(ctx context.Context, items []model.InventoryItem) (int, error) {
+	keys := leaderboardScriptKeys()
+	// Eval, not Run: a pipeline cannot fall back when Redis lacks the script
+	pipe := s.redis.Pipeline()
+	scored := s.score(items, func(robloxUserID string, scores []float64) {
+		args := []interface{}{robloxUserID}
+		for _, score := range scores {
+			args = append(args, score)
+		}
+		scoreScript.Eval(ctx, pipe, keys, args...)
+	})
+	if scored == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return scored, nil
+}
+
+// score computes the scores of items, in LeaderboardMetrics order, and passes
+// them to write. Returns how many users were scored.
+func (s *LeaderboardService) score(items []model.InventoryItem, write func(robloxUserID string, scores []float64)) int {
+	scored := 0
+	for _, item := range items {
+		payload, err := decodeStoredPayload(item.RawJSON)
+		if err != nil {
+			log.Printf("[LeaderboardService] Skipping %s: %v", item.RobloxUserID, err)
+			continue
+		}
+		byMetric := LeaderboardScores(payload, s.valuator)
+		scores := make([]float64, len(model.LeaderboardMetrics))
+		for i, metric := range model.LeaderboardMetrics {
+			scores[i] = byMetric[metric]
+		}
+		write(item.RobloxUserID, scores)
+		scored++
+	}
+	return scored
+}
+
+// Top returns a page of a leaderboard, highest score first, and its size.
+func (s *LeaderboardService) Top(ctx context.Context, metric string, offset, limit int) ([]model.LeaderboardEntry, int64, error) {
+	if !model.IsLeaderboardMetric(metric) {
+		return nil, 0, apierror.NotFound("unknown leaderboard: " + metric)
+	}
+
+	key := leaderboardKey(metric)
+	total, err := s.redis.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count leaderboard: %w", err)
+	}
+
+	members, err := s.redis.ZRevRangeWithScores(ctx, key, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read leaderboard: %w", err)
+	}
+
+	entries := make([]model.LeaderboardEntry, len(members))
+	for i, m := range members {
+		entries[i] = model.LeaderboardEntry{
+			Rank:         int64(offset + i + 1),
+			RobloxUserID: fmt.Sprint(m.Member),
+			Score:        m.Score,
+		}
+	}
+	return entries, total, nil
+}
+
+// Rank returns a user's position on a leaderboard.
+func (s *LeaderboardService) Rank(ctx context.Context, metric, robloxUserID string) (*model.LeaderboardEntry, error) {
+	if !model.IsLeaderboardMetric(metric) {
+		return nil, apierror.NotFound("unknown leaderboard: " + metric)
+	}
+
+	key := leaderboardKey(metric)
+	rank, err := s.redis.ZRevRank(ctx, key, robloxUserID).Result()
+	if err == redis.Nil {
+		return nil, apierror.NotFound("user is not ranked on this leaderboard")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rank: %w", err)
+	}
+	score, err := s.redis.ZScore(ctx, key, robloxUserID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read score: %w", err)
+	}
+
+	return &model.LeaderboardEntry{Rank: rank + 1, RobloxUserID: robloxUserID, Score: score}, nil
+}
+
+// Remove drops a user from every leaderboard, including the staging boards
+// of a running rebuild.
+func (s *LeaderboardService) Remove(ctx context.Context, robloxUserID string) error {
+	return removeScript.Run(ctx, s.redis, leaderboardScriptKeys(), robloxUserID).Err()
+}
+
+// Rebuild recomputes all leaderboards from the inventory repository.
+// Scores are written to staging keys and swapped in atomically at the end,
+// so readers never see a partially rebuilt board. Updates and removals made
+// while it runs also go to the staging keys and take precedence over the
+// scan, which may have read an older inventory. One rebuild runs at a time,
+// across processes; others fail with 409. Returns the number of users ranked.
+func (s *LeaderboardService) Rebuild(ctx context.Context) (int, error) {
+	scanner, ok := s.inventoryRepo.(repository.InventoryScanner)
+	if !ok || s.inventoryRepo == nil {
+		return 0, apierror.ServiceUnavailable("leaderboard rebuild is not supported by this storage backend")
+	}
+
+	// The lock expires like the marker if its holder dies, and is renewed with it
+	lockKey, token := LeaderboardRedisKeyPrefix+"rebuild:lock", uid.New()
+	locked, err := s.redis.SetNX(ctx, lockKey, token, leaderboardRebuildTTL).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock leaderboard rebuild: %w", err)
+	}
+	if !locked {
+		return 0, apierror.Conflict("a leaderboard rebuild is already running")
+	}
+	defer unlockScript.Run(context.WithoutCancel(ctx), s.redis, []string{lockKey}, token)
+
+	keys := leaderboardScriptKeys()
+	n := len(model.LeaderboardMetrics)
+	staging, marker := keys[n:2*n], keys[2*n]
+	abort := func() {
+		s.redis.Del(ctx, keys[n:]...)
+	}
+
+	// Staging is cleared before the marker starts updates going to it
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, keys[n:]...)
+	pipe.Set(ctx, marker, time.Now().UTC().Format(time.RFC3339), leaderboardRebuildTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to start leaderboard rebuild: %w", err)
+	}
+
+	ranked := 0
+	batch := make([]model.InventoryItem, 0, leaderboardRebuildBatch)
+	flush := func() error {
+		// NX: a score written since the rebuild started is newer than the scan's
+		pipe := s.redis.Pipeline()
+		scored := s.score(batch, func(robloxUserID string, scores []float64) {
+			for i, score := range scores {
+				pipe.ZAddNX(ctx, staging[i], redis.Z{Score: score, Member: robloxUserID})
+			}
+		})
+		pipe.Expire(ctx, marker, leaderboardRebuildTTL)
+		pipe.Expire(ctx, lockKey, leaderboardRebuildTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to write leaderboard batch: %w", err)
+		}
+		ranked += scored
+		batch = batch[:0]
+		return nil
+	}
+
+	err = scanner.ScanInventories(ctx, func(item model.InventoryItem) error {
+		batch = append(batch, item)
+		if len(batch) < leaderboardRebuildBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		abort()
+		return 0, err
+	}
+
+	if err := swapScript.Run(ctx, s.redis, keys).Err(); err != nil {
+		abort()
+		return 0, fmt.Errorf("failed to swap in rebuilt leaderboards: %w", err)
+	}
+
+	log.Printf("[LeaderboardService] Rebuilt leaderboards for %d users", ranked)
+	return ranked, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/cache"
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisAddrEnv names a scratch Redis server for tests that need one. The
+// leaderboard tests write to the live leaderboard keys of its database.
+const redisAddrEnv = "INVENTORY_TEST_REDIS_ADDR" // e.g. localhost:6379
+
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	addr := os.Getenv(redisAddrEnv)
+	if addr == "" {
+		t.Skipf("%s not set", redisAddrEnv)
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("Redis at %s: %v", addr, err)
+	}
+	return client
+}
+
+// rankOf returns the user's coins rank, or nil if they are not ranked.
+func rankOf(t *testing.T, lb *LeaderboardService, robloxUserID string) *model.LeaderboardEntry {
+	t.Helper()
+	entry, err := lb.Rank(context.Background(), model.MetricCoins, robloxUserID)
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("Rank: %v", err)
+	}
+	return entry
+}
+
+func TestRestoreRanksPurgedUser(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	user := fmt.Sprintf("%d", time.Now().UnixNano())
+
+	repo, err := repository.NewMemoryInventoryRepository("", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryInventoryRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	// Wired like cmd/api: hooks on the service, run by the buffer's flushes
+	lb := NewLeaderboardService(client, repo, nil)
+	t.Cleanup(func() { lb.Remove(ctx, user) })
+	svc := NewInventoryService(repo, nil)
+	svc.AddPersistHook(lb.PersistHook())
+	buffer, err := cache.NewRedisInventoryBuffer(cache.RedisBufferConfig{
+		Addr:          client.Options().Addr,
+		FlushInterval: time.Hour,
+		KeyPrefix:     "vinzhub:test:" + user + ":inventory",
+	}, svc.FlushFunc())
+	if err != nil {
+		t.Fatalf("NewRedisInventoryBuffer: %v", err)
+	}
+	t.Cleanup(func() {
+		buffer.Delete(ctx, user)
+		buffer.Close()
+	})
+	svc.SetBuffer(buffer)
+
+	if _, err := svc.SyncRawInventory(ctx, user, []byte(`{"stats":{"coins":1234}}`), ""); err != nil {
+		t.Fatalf("SyncRawInventory: %v", err)
+	}
+	if err := buffer.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if entry := rankOf(t, lb, user); entry == nil || entry.Score != 1234 {
+		t.Fatalf("rank after sync = %+v; want a score of 1234", entry)
+	}
+
+	cleanup := NewCleanupScheduler(repo, CleanupConfig{InactiveThreshold: time.Millisecond})
+	cleanup.SetLeaderboards(lb)
+	time.Sleep(10 * time.Millisecond)
+	report, err := cleanup.Run(ctx, false)
+	if err != nil || report.Purged != 1 {
+		t.Fatalf("Run = %+v, %v; want 1 purged", report, err)
+	}
+	if entry := rankOf(t, lb, user); entry != nil {
+		t.Fatalf("rank after purge = %+v; want none", entry)
+	}
+
+	result, err := svc.RestoreInventory(ctx, user)
+	if err != nil || result == nil || !result.Restored {
+		t.Fatalf("RestoreInventory = %+v, %v; want restored", result, err)
+	}
+	if entry := rankOf(t, lb, user); entry == nil || entry.Score != 1234 {
+		t.Errorf("rank after restore = %+v; want a score of 1234", entry)
+	}
+}
+
+func TestRebuildWhileRunning(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	repo, err := repository.NewMemoryInventoryRepository("", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryInventoryRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	lb := NewLeaderboardService(client, repo, nil)
+
+	// Held by a rebuild elsewhere
+	lockKey := LeaderboardRedisKeyPrefix + "rebuild:lock"
+	if ok, err := client.SetNX(ctx, lockKey, "other", time.Minute).Result(); err != nil || !ok {
+		t.Skipf("rebuild lock is taken (%v): another rebuild is running", err)
+	}
+	t.Cleanup(func() { client.Del(ctx, lockKey) })
+
+	_, err = lb.Rebuild(ctx)
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("Rebuild = %v; want 409", err)
+	}
+	if holder, _ := client.Get(ctx, lockKey).Result(); holder != "other" {
+		t.Errorf("lock held by %q after a refused rebuild; want the other rebuild", holder)
+	}
+}
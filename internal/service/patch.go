@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/canonjson"
+	"vinzhub-rest-api-v2/pkg/jsonpatch"
+)
+
+// Patch document media types accepted by PatchInventory.
+const (
+	PatchTypeMerge     = "application/merge-patch+json" // RFC 7396
+	PatchTypeJSONPatch = "application/json-patch+json"  // RFC 6902
+)
+
+// PatchInventory applies a merge patch or JSON Patch to a user's latest stored
+// inventory (buffered copy first, then the repository) and stores the result
+// like a full sync. ifMatch must name the content hash of the base the client
+// patched; a stale base is rejected with 409 so the client can fall back to a
+// full sync.
+func (s *InventoryService) PatchInventory(ctx context.Context, robloxUserID, patchType string, patch []byte, ifMatch string) (*model.SyncResult, error) {
+	if patchType != PatchTypeMerge && patchType != PatchTypeJSONPatch {
+		return nil, apierror.UnsupportedMediaType("Content-Type must be " + PatchTypeMerge + " or " + PatchTypeJSONPatch)
+	}
+	if ifMatch == "" {
+		return nil, apierror.PreconditionRequired("If-Match header with the base content hash is required")
+	}
+
+	current, token, err := s.currentInventory(ctx, robloxUserID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, apierror.NotFound("inventory not found; send a full sync first")
+	}
+	if !etagMatches(ifMatch, current.ContentHash) {
+		return nil, apierror.Conflict("inventory has changed since the base version; send a full sync")
+	}
+	base := current.InventoryJSON
+
+	var patched []byte
+	if patchType == PatchTypeMerge {
+		patched, err = jsonpatch.MergePatch(base, patch)
+	} else {
+		patched, err = jsonpatch.Apply(base, patch)
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, apierror.Conflict(err.Error())
+		}
+		return nil, apierror.BadRequest(err.Error())
+	}
+
+	payload, err := s.parsePayload(robloxUserID, patched)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, _ := canonjson.Hash(patched)
+	keyAccountID := s.lookupKeyAccount(ctx, robloxUserID)
+	written, err := s.writeIfUnchanged(ctx, keyAccountID, robloxUserID, patched, hash, current.ContentHash, token)
+	if err != nil {
+		return nil, err
+	}
+	if !written {
+		return nil, apierror.Conflict("inventory has changed since the base version; send a full sync")
+	}
+	s.recordEvents(ctx, robloxUserID, current, payload, hash)
+
+	return &model.SyncResult{
+		Status:        model.SyncStatusPatched,
+		Size:          len(patched),
+		ContentHash:   hash,
+		UnknownFields: payload.UnknownFields,
+	}, nil
+}
+
+// etagMatches reports whether an If-Match header value names hash.
+// Weak and strong entity tags are compared by value; "*" matches anything.
+func etagMatches(header, hash string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		tag = strings.TrimPrefix(tag, "W/")
+		if strings.Trim(tag, `"`) == hash {
+			return true
+		}
+	}
+	return false
+}
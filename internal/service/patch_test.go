@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+const patchBase = `{"stats":{"coins":10},"fish":[{"uuid":"a","fish_id":1}]}`
+
+// statusOf returns the HTTP status an error is answered with.
+func statusOf(err error) int {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	}
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return http.StatusOK
+}
+
+// racingRepository is a memory repository where another writer replaces
+// the inventory right after every read, so a write based on it is stale.
+type racingRepository struct {
+	*repository.MemoryInventoryRepository
+}
+
+func (r *racingRepository) GetInventory(ctx context.Context, robloxUserID string) (*model.RawInventory, error) {
+	inv, err := r.MemoryInventoryRepository.GetInventory(ctx, robloxUserID)
+	if err == nil && inv != nil {
+		err = r.UpsertRawInventory(ctx, 0, robloxUserID, []byte(`{"stats":{"coins":99}}`))
+	}
+	return inv, err
+}
+
+// patchService returns a service without a buffer holding patchBase for
+// user 100, and the base's content hash.
+func patchService(t *testing.T, racing bool) (*InventoryService, string) {
+	t.Helper()
+	memory, err := repository.NewMemoryInventoryRepository("", 0)
+	if err != nil {
+		t.Fatalf("NewMemoryInventoryRepository: %v", err)
+	}
+	t.Cleanup(func() { memory.Close() })
+
+	var repo repository.InventoryRepository = memory
+	if racing {
+		repo = &racingRepository{memory}
+	}
+	svc := NewInventoryService(repo, nil)
+	result, err := svc.SyncRawInventory(context.Background(), "100", []byte(patchBase), "")
+	if err != nil {
+		t.Fatalf("SyncRawInventory: %v", err)
+	}
+	return svc, result.ContentHash
+}
+
+func TestPatchInventory(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		ifMatch   string // the base's hash if empty
+		racing    bool
+		want      int
+	}{
+		{"merge patch", PatchTypeMerge, `{"stats":{"coins":20}}`, "", false, http.StatusOK},
+		{"json patch", PatchTypeJSONPatch, `[{"op":"test","path":"/stats/coins","value":10},{"op":"replace","path":"/stats/coins","value":20}]`, "", false, http.StatusOK},
+		{"wrong content type", "application/json", `{"stats":{"coins":20}}`, "", false, http.StatusUnsupportedMediaType},
+		{"stale base", PatchTypeMerge, `{"stats":{"coins":20}}`, `"0000"`, false, http.StatusConflict},
+		{"base replaced while patching", PatchTypeMerge, `{"stats":{"coins":20}}`, "", true, http.StatusConflict},
+		{"failed test op", PatchTypeJSONPatch, `[{"op":"test","path":"/stats/coins","value":11}]`, "", false, http.StatusConflict},
+		{"invalid result", PatchTypeMerge, `{"stats":{"coins":-1}}`, "", false, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, base := patchService(t, tt.racing)
+			ifMatch := tt.ifMatch
+			if ifMatch == "" {
+				ifMatch = `"` + base + `"`
+			}
+
+			result, err := svc.PatchInventory(context.Background(), "100", tt.patchType, []byte(tt.patch), ifMatch)
+			if got := statusOf(err); got != tt.want {
+				t.Fatalf("PatchInventory = %v; want status %d, got %d", err, tt.want, got)
+			}
+
+			stored, err := svc.inventoryRepo.GetInventory(context.Background(), "100")
+			if err != nil || stored == nil {
+				t.Fatalf("GetInventory = %+v, %v", stored, err)
+			}
+			if tt.want == http.StatusOK {
+				if result.Status != model.SyncStatusPatched || stored.ContentHash != result.ContentHash {
+					t.Errorf("result = %+v, stored hash %s; want the patch stored", result, stored.ContentHash)
+				}
+			} else if !tt.racing && stored.ContentHash != base {
+				t.Errorf("stored hash = %s after a refused patch; want the base %s", stored.ContentHash, base)
+			}
+		})
+	}
+}
+
+func TestPatchInventoryPreconditions(t *testing.T) {
+	svc, _ := patchService(t, false)
+	ctx := context.Background()
+
+	_, err := svc.PatchInventory(ctx, "100", PatchTypeMerge, []byte(`{}`), "")
+	if got := statusOf(err); got != http.StatusPreconditionRequired {
+		t.Errorf("PatchInventory without If-Match = %v; want status %d, got %d", err, http.StatusPreconditionRequired, got)
+	}
+	_, err = svc.PatchInventory(ctx, "200", PatchTypeMerge, []byte(`{}`), "*")
+	if got := statusOf(err); got != http.StatusNotFound {
+		t.Errorf("PatchInventory of a missing inventory = %v; want status %d, got %d", err, http.StatusNotFound, got)
+	}
+
+	// A full sync with a mismatched If-Match is refused outright
+	_, err = svc.SyncRawInventory(ctx, "100", []byte(`{"stats":{"coins":30}}`), `"0000"`)
+	if got := statusOf(err); got != http.StatusPreconditionFailed {
+		t.Errorf("SyncRawInventory with a mismatched If-Match = %v; want status %d, got %d", err, http.StatusPreconditionFailed, got)
+	}
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// fieldNamePattern restricts projected field names to plain top-level keys,
+// so they can be used as database paths as they are.
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// GetInventoryFields returns the user's latest inventory cut down to the
+// given top-level fields. Backends implementing InventoryProjectionRepository
+// do the projection themselves unless a newer copy is buffered.
+// Returns nil if the user has no inventory.
+func (s *InventoryService) GetInventoryFields(ctx context.Context, robloxUserID string, fields []string) (*model.RawInventory, error) {
+	for _, field := range fields {
+		if !fieldNamePattern.MatchString(field) {
+			return nil, apierror.BadRequest("invalid field name: " + field)
+		}
+	}
+
+	inv, _, err := s.bufferedInventory(ctx, robloxUserID)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil && s.inventoryRepo != nil {
+		if projector, ok := s.inventoryRepo.(repository.InventoryProjectionRepository); ok {
+			projected, err := projector.GetInventoryFields(ctx, robloxUserID, fields)
+			// Rows stored before content hashes need the whole document to compute one
+			if err != nil || projected == nil || projected.ContentHash != "" {
+				return projected, err
+			}
+		}
+		inv, err = s.inventoryRepo.GetInventory(ctx, robloxUserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if inv == nil {
+		return nil, nil
+	}
+
+	projected, err := ProjectFields(inv.InventoryJSON, fields)
+	if err != nil {
+		return nil, err
+	}
+	inv.InventoryJSON = projected
+	return inv, nil
+}
+
+// GetCategoryItems returns a page of one category's items of the user's
+// latest inventory, filtered and sorted as q asks. Backends implementing
+// InventoryProjectionRepository run the query themselves unless a newer copy
+// is buffered. Returns nil if the user has no inventory.
+func (s *InventoryService) GetCategoryItems(ctx context.Context, robloxUserID string, q model.CategoryQuery) (*model.CategoryItems, error) {
+	if !model.IsInventoryCategory(q.Category) {
+		return nil, apierror.NotFound("unknown category: " + q.Category)
+	}
+	if q.Sort != "" && !model.IsItemSortKey(q.Sort) {
+		return nil, apierror.BadRequest("sort must be one of tier, name, price or quantity")
+	}
+	if q.Tiers.Max > 0 && q.Tiers.Min > q.Tiers.Max {
+		return nil, apierror.BadRequest("minimum tier is above maximum tier")
+	}
+
+	inv, _, err := s.bufferedInventory(ctx, robloxUserID)
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil && s.inventoryRepo != nil {
+		if projector, ok := s.inventoryRepo.(repository.InventoryProjectionRepository); ok {
+			page, err := projector.GetCategoryItems(ctx, robloxUserID, q)
+			if err != nil || page == nil || page.ContentHash != "" {
+				return page, err
+			}
+		}
+		inv, err = s.inventoryRepo.GetInventory(ctx, robloxUserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if inv == nil {
+		return nil, nil
+	}
+
+	items, total, err := QueryCategory(inv.InventoryJSON, q)
+	if err != nil {
+		return nil, err
+	}
+	return &model.CategoryItems{
+		Items:       items,
+		Total:       total,
+		ContentHash: inv.ContentHash,
+		SyncedAt:    inv.SyncedAt,
+	}, nil
+}
+
+// ProjectFields returns the JSON object rawJSON with only the given top-level
+// fields. Fields the document does not have are left out.
+func ProjectFields(rawJSON []byte, fields []string) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(rawJSON, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode stored inventory: %w", err)
+	}
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := doc[field]; ok {
+			projected[field] = value
+		}
+	}
+	return json.Marshal(projected)
+}
+
+// QueryCategory applies q to the category array of the inventory rawJSON.
+// It returns the requested page of items, as stored, and the number of items
+// matching the filters. A missing or malformed category has no items.
+// This is the in-memory counterpart of InventoryProjectionRepository.GetCategoryItems.
+func QueryCategory(rawJSON []byte, q model.CategoryQuery) ([]json.RawMessage, int64, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(rawJSON, &doc); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode stored inventory: %w", err)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(doc[q.Category], &raw); err != nil {
+		raw = nil
+	}
+
+	type match struct {
+		raw   json.RawMessage
+		entry model.InventoryEntry
+	}
+	matches := make([]match, 0, len(raw))
+	for _, item := range raw {
+		var entry model.InventoryEntry
+		// Stored items may predate validation; mismatched fields are left empty
+		_ = json.Unmarshal(item, &entry)
+		if q.Matches(&entry) {
+			matches = append(matches, match{raw: item, entry: entry})
+		}
+	}
+
+	if q.Sort != "" {
+		less := func(a, b *model.InventoryEntry) bool {
+			switch q.Sort {
+			case model.ItemSortTier:
+				return a.SortTier() < b.SortTier()
+			case model.ItemSortName:
+				return a.Name < b.Name
+			case model.ItemSortPrice:
+				return a.Price < b.Price
+			default:
+				return a.Count() < b.Count()
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			if q.Desc {
+				return less(&matches[j].entry, &matches[i].entry)
+			}
+			return less(&matches[i].entry, &matches[j].entry)
+		})
+	}
+
+	total := int64(len(matches))
+	start, end := q.Offset, q.Offset+q.Limit
+	if start > len(matches) {
+		start = len(matches)
+	}
+	if q.Limit <= 0 || end > len(matches) {
+		end = len(matches)
+	}
+	items := make([]json.RawMessage, 0, end-start)
+	for _, m := range matches[start:end] {
+		items = append(items, m.raw)
+	}
+	return items, total, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// RestoreInventory brings back the user's latest soft-deleted inventory.
+// A live inventory synced at or after it is kept and the result reports
+// Restored false. Returns nil if the user has no deleted inventory.
+func (s *InventoryService) RestoreInventory(ctx context.Context, robloxUserID string) (*model.RestoreResult, error) {
+	tombstones, ok := s.inventoryRepo.(repository.InventoryTombstoneRepository)
+	if !ok {
+		return nil, apierror.ServiceUnavailable("restore is not supported by this storage backend")
+	}
+
+	// A buffered inventory is newer than anything deleted, and would replace
+	// the restored one when flushed
+	buffered, _, err := s.bufferedInventory(ctx, robloxUserID)
+	if err != nil {
+		return nil, err
+	}
+	if buffered != nil {
+		return nil, apierror.Conflict("a newer inventory is waiting to be saved")
+	}
+
+	result, err := tombstones.RestoreInventory(ctx, robloxUserID)
+	if err != nil || result == nil {
+		return result, err
+	}
+	if result.Restored {
+		log.Printf("[InventoryService] Restored inventory of %s deleted at %s", robloxUserID, result.DeletedAt.Format("2006-01-02T15:04:05Z07:00"))
+		// Purging dropped the user from derived data such as the leaderboards
+		if inv, err := s.inventoryRepo.GetInventory(ctx, robloxUserID); err != nil {
+			log.Printf("[InventoryService] Failed to read restored inventory of %s: %v", robloxUserID, err)
+		} else if inv != nil {
+			s.persisted(ctx, inv.KeyAccountID, robloxUserID, inv.InventoryJSON, inv.ContentHash)
+		}
+	}
+	return result, nil
+}
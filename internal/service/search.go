@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// SearchItems finds the users holding items that match q.
+// Searches stored inventories only; buffered syncs appear after the next flush.
+func (s *InventoryService) SearchItems(ctx context.Context, q model.ItemSearchQuery) ([]model.ItemOwnership, int64, error) {
+	searchRepo, ok := s.inventoryRepo.(repository.InventorySearchRepository)
+	if !ok || s.inventoryRepo == nil {
+		return nil, 0, apierror.ServiceUnavailable("item search is not supported by this storage backend")
+	}
+
+	if q.Category != "" && !model.IsInventoryCategory(q.Category) {
+		return nil, 0, apierror.BadRequest("unknown category: " + q.Category)
+	}
+	if q.ItemID == 0 && !q.HasTierFilter() && q.Shiny == nil && q.VariantID == "" {
+		return nil, 0, apierror.BadRequest("at least one of item_id, tier, shiny or variant_id is required")
+	}
+	if q.MaxTier > 0 && q.MinTier > q.MaxTier {
+		return nil, 0, apierror.BadRequest("minimum tier is above maximum tier")
+	}
+
+	return searchRepo.SearchItems(ctx, q)
+}
@@ -123,3 +123,61 @@ func (s *TokenService) RefreshToken(ctx context.Context, token string) error {
 	newJSON, _ := json.Marshal(data)
 	return s.redis.Set(ctx, key, newJSON, TokenTTL).Err()
 }
+
+// tokenHintLength is how much of a token ListSessions reveals.
+const tokenHintLength = len(TokenPrefix) + 8
+
+// ListSessions returns the live session tokens of a Roblox user. Tokens are
+// only indexed by value, so this scans every token key; it is meant for rare
+// admin requests.
+func (s *TokenService) ListSessions(ctx context.Context, robloxUserID string) ([]model.TokenSession, error) {
+	sessions := []model.TokenSession{}
+	err := s.scanUserTokens(ctx, robloxUserID, func(token string, data *model.TokenData) {
+		sessions = append(sessions, model.TokenSession{TokenHint: token[:tokenHintLength] + "…", TokenData: *data})
+	})
+	return sessions, err
+}
+
+// RevokeSessions deletes every session token of a Roblox user and returns how many there were.
+func (s *TokenService) RevokeSessions(ctx context.Context, robloxUserID string) (int, error) {
+	var keys []string
+	err := s.scanUserTokens(ctx, robloxUserID, func(token string, _ *model.TokenData) {
+		keys = append(keys, TokenRedisKeyPrefix+token)
+	})
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+	if err := s.redis.Del(ctx, keys...).Err(); err != nil {
+		return 0, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	return len(keys), nil
+}
+
+// scanUserTokens calls fn for each stored token belonging to robloxUserID.
+func (s *TokenService) scanUserTokens(ctx context.Context, robloxUserID string, fn func(token string, data *model.TokenData)) error {
+	iter := s.redis.Scan(ctx, 0, TokenRedisKeyPrefix+TokenPrefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		jsonData, err := s.redis.Get(ctx, key).Bytes()
+		if err == redis.Nil {
+			continue // expired since the scan saw it
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get token: %w", err)
+		}
+
+		var data model.TokenData
+		if err := json.Unmarshal(jsonData, &data); err != nil || data.RobloxUserID != robloxUserID {
+			continue
+		}
+		token := key[len(TokenRedisKeyPrefix):]
+		if len(token) < tokenHintLength {
+			continue
+		}
+		fn(token, &data)
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+)
+
+// tradeQueueSize bounds the batches of events waiting for the analyzer.
+const tradeQueueSize = 1000
+
+// TradeAnalyzer detects items changing hands between players. Items keep
+// their uuid when traded, so an item_removed event of one player and an
+// item_acquired event of another for the same uuid, within the match window,
+// are recorded as a transfer. Either side may be synced first.
+type TradeAnalyzer struct {
+	trades    repository.TradeLog
+	events    repository.InventoryEventLog
+	window    time.Duration
+	queue     chan []model.InventoryEvent
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTradeAnalyzer creates an analyzer matching events up to window apart
+// and starts its background worker. The events it is fed must already be
+// stored in events, the log trades looks counterparts up in.
+func NewTradeAnalyzer(trades repository.TradeLog, events repository.InventoryEventLog, window time.Duration) *TradeAnalyzer {
+	a := &TradeAnalyzer{
+		trades: trades,
+		events: events,
+		window: window,
+		queue:  make(chan []model.InventoryEvent, tradeQueueSize),
+		done:   make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// EventHook returns the hook feeding sync events to the analyzer. It never
+// blocks; events are dropped and logged if the analyzer falls behind.
+func (a *TradeAnalyzer) EventHook() EventHook {
+	return func(ctx context.Context, events []model.InventoryEvent) {
+		var items []model.InventoryEvent
+		for _, event := range events {
+			// Events without an ID were not stored and cannot be matched later
+			if event.UUID != "" && !event.ID.IsZero() && (event.Type == model.EventItemAcquired || event.Type == model.EventItemRemoved) {
+				items = append(items, event)
+			}
+		}
+		if len(items) == 0 {
+			return
+		}
+		select {
+		case a.queue <- items:
+		default:
+			log.Printf("[TradeAnalyzer] Queue full, dropping %d item events of %s", len(items), items[0].RobloxUserID)
+		}
+	}
+}
+
+// Close stops the worker after the queued events have been analyzed.
+func (a *TradeAnalyzer) Close() {
+	a.closeOnce.Do(func() { close(a.queue) })
+	<-a.done
+}
+
+func (a *TradeAnalyzer) run() {
+	defer close(a.done)
+	for events := range a.queue {
+		for i := range events {
+			if err := a.analyze(context.Background(), &events[i]); err != nil {
+				log.Printf("[TradeAnalyzer] Failed to analyze %s of %s by %s: %v",
+					events[i].Type, events[i].UUID, events[i].RobloxUserID, err)
+			}
+		}
+	}
+}
+
+// analyze records the transfer event completes, if any.
+func (a *TradeAnalyzer) analyze(ctx context.Context, event *model.InventoryEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	counterpart, err := a.trades.FindTransferCounterpart(ctx, event, event.CreatedAt.Add(-a.window))
+	if err != nil || counterpart == nil {
+		return err
+	}
+
+	removed, acquired := counterpart, event
+	if event.Type == model.EventItemRemoved {
+		removed, acquired = event, counterpart
+	}
+	transfer := &model.ItemTransfer{
+		UUID:             acquired.UUID,
+		ItemID:           acquired.ItemID,
+		Name:             acquired.Name,
+		Category:         acquired.Category,
+		Tier:             acquired.Tier,
+		Quantity:         acquired.Quantity,
+		FromRobloxUserID: removed.RobloxUserID,
+		ToRobloxUserID:   acquired.RobloxUserID,
+		RemovedAt:        removed.CreatedAt,
+		AcquiredAt:       acquired.CreatedAt,
+		RemovedEventID:   removed.ID,
+		AcquiredEventID:  acquired.ID,
+		CreatedAt:        time.Now().UTC(),
+	}
+	inserted, err := a.trades.InsertItemTransfer(ctx, transfer)
+	if err != nil {
+		return err
+	}
+	if inserted {
+		log.Printf("[TradeAnalyzer] %s (%s) moved from %s to %s", transfer.UUID, transfer.Name, transfer.FromRobloxUserID, transfer.ToRobloxUserID)
+	}
+	return nil
+}
+
+// ListTransfers returns a page of transfers matching q, newest first, and the
+// cursor of the next page.
+func (a *TradeAnalyzer) ListTransfers(ctx context.Context, q model.ItemTransferQuery) ([]model.ItemTransfer, string, error) {
+	return a.trades.ListItemTransfers(ctx, q)
+}
+
+// ItemHistory returns the provenance of an item: its transfers and the
+// inventory events of its uuid, oldest first, up to limit of each.
+func (a *TradeAnalyzer) ItemHistory(ctx context.Context, uuid string, limit int) (*model.ItemHistory, error) {
+	transfers, _, err := a.trades.ListItemTransfers(ctx, model.ItemTransferQuery{UUID: uuid, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	events, _, err := a.events.ListInventoryEvents(ctx, model.InventoryEventQuery{UUID: uuid, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+
+	// Both lists come newest first; keep the latest ones, in time order
+	for i, j := 0, len(transfers)-1; i < j; i, j = i+1, j-1 {
+		transfers[i], transfers[j] = transfers[j], transfers[i]
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return &model.ItemHistory{UUID: uuid, Transfers: transfers, Events: events}, nil
+}
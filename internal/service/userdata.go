@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+)
+
+// UserDataService answers data export and deletion requests for a Roblox
+// user, across every game's inventory, session tokens, key accounts, logs
+// and leaderboards. Sources left unset are skipped.
+type UserDataService struct {
+	games        []userDataGame
+	tokens       *TokenService
+	keyAccounts  repository.KeyAccountLister
+	logs         repository.UserLogStore
+	leaderboards *LeaderboardService
+}
+
+type userDataGame struct {
+	name    string
+	service *InventoryService
+}
+
+// NewUserDataService creates a user data service with no sources.
+func NewUserDataService() *UserDataService {
+	return &UserDataService{}
+}
+
+// AddGame adds a game's inventory service, stored and buffered inventories included.
+func (s *UserDataService) AddGame(game string, inventoryService *InventoryService) {
+	s.games = append(s.games, userDataGame{name: game, service: inventoryService})
+}
+
+// SetTokenService includes session tokens.
+func (s *UserDataService) SetTokenService(tokens *TokenService) {
+	s.tokens = tokens
+}
+
+// SetKeyAccounts includes key accounts. Their IDs also select the user's obfuscation logs.
+func (s *UserDataService) SetKeyAccounts(keyAccounts repository.KeyAccountLister) {
+	s.keyAccounts = keyAccounts
+}
+
+// SetLogStore includes obfuscation logs and inventory events.
+func (s *UserDataService) SetLogStore(logs repository.UserLogStore) {
+	s.logs = logs
+}
+
+// SetLeaderboards makes erasure remove the user from the leaderboards.
+func (s *UserDataService) SetLeaderboards(leaderboards *LeaderboardService) {
+	s.leaderboards = leaderboards
+}
+
+// Export returns everything stored about a user. Sources that are not
+// configured are listed in Unavailable; a source failing fails the export.
+func (s *UserDataService) Export(ctx context.Context, robloxUserID string) (*model.UserDataExport, error) {
+	export := &model.UserDataExport{
+		RobloxUserID:    robloxUserID,
+		ExportedAt:      time.Now().UTC(),
+		Games:           []model.UserGameData{},
+		Sessions:        []model.TokenSession{},
+		KeyAccounts:     []model.KeyAccount{},
+		ObfuscationLogs: []model.ObfuscationLog{},
+	}
+
+	for _, game := range s.games {
+		data, err := game.service.exportUser(ctx, robloxUserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s inventory: %w", game.name, err)
+		}
+		if data.Inventory != nil || data.Buffered != nil {
+			data.Game = game.name
+			export.Games = append(export.Games, data)
+		}
+	}
+
+	if s.tokens != nil {
+		sessions, err := s.tokens.ListSessions(ctx, robloxUserID)
+		if err != nil {
+			return nil, err
+		}
+		export.Sessions = sessions
+	} else {
+		export.Unavailable = append(export.Unavailable, "sessions: Redis is not configured")
+	}
+
+	keyAccountIDs, err := s.keyAccountIDs(ctx, robloxUserID, export)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.logs != nil {
+		logs, err := s.logs.ListObfuscationLogsByUser(ctx, keyAccountIDs)
+		if err != nil {
+			return nil, err
+		}
+		export.ObfuscationLogs = logs
+	} else {
+		export.Unavailable = append(export.Unavailable, "obfuscation_logs: MongoDB is not configured")
+	}
+
+	return export, nil
+}
+
+// keyAccountIDs returns the IDs of the user's key accounts, from MySQL and
+// from their inventories. With an export, the accounts are added to it.
+func (s *UserDataService) keyAccountIDs(ctx context.Context, robloxUserID string, export *model.UserDataExport) ([]int64, error) {
+	seen := make(map[int64]bool)
+	var ids []int64
+	add := func(id int64) {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if s.keyAccounts != nil {
+		accounts, err := s.keyAccounts.ListKeyAccounts(ctx, robloxUserID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range accounts {
+			add(a.ID)
+		}
+		if export != nil {
+			export.KeyAccounts = accounts
+		}
+	} else if export != nil {
+		export.Unavailable = append(export.Unavailable, "key_accounts: MySQL is not configured")
+	}
+
+	for _, game := range s.games {
+		inv, err := game.service.GetInventory(ctx, robloxUserID)
+		if err != nil {
+			return nil, err
+		}
+		if inv != nil {
+			add(inv.KeyAccountID)
+		}
+	}
+	return ids, nil
+}
+
+// Erase deletes everything stored about a user: inventories (stored,
+// buffered, tombstoned, history and values) in every game, session tokens,
+// obfuscation logs, inventory events, item transfers and leaderboard entries. It carries on
+// past failures and reports them; the erasure can be repeated.
+func (s *UserDataService) Erase(ctx context.Context, robloxUserID, requestedBy string) *model.UserErasure {
+	erasure := &model.UserErasure{
+		RobloxUserID: robloxUserID,
+		ErasedAt:     time.Now().UTC(),
+		RequestedBy:  requestedBy,
+		Games:        []model.UserGameErasure{},
+	}
+	fail := func(source string, err error) {
+		erasure.Errors = append(erasure.Errors, source+": "+err.Error())
+	}
+
+	// Resolved before the inventories, which also record key accounts, are gone
+	keyAccountIDs, err := s.keyAccountIDs(ctx, robloxUserID, nil)
+	if err != nil {
+		fail("key_accounts", err)
+	}
+
+	for _, game := range s.games {
+		result, err := game.service.eraseUser(ctx, robloxUserID)
+		result.Game = game.name
+		if err != nil {
+			fail("inventory "+game.name, err)
+		}
+		erasure.Games = append(erasure.Games, result)
+	}
+
+	if s.tokens != nil {
+		if erasure.Sessions, err = s.tokens.RevokeSessions(ctx, robloxUserID); err != nil {
+			fail("sessions", err)
+		}
+	}
+
+	if s.logs != nil {
+		erased, err := s.logs.EraseUserLogs(ctx, robloxUserID, keyAccountIDs)
+		if erased != nil {
+			erasure.UserLogErasure = *erased
+		}
+		if err != nil {
+			fail("logs", err)
+		}
+	}
+
+	if s.leaderboards != nil {
+		if err := s.leaderboards.Remove(ctx, robloxUserID); err != nil {
+			fail("leaderboards", err)
+		}
+	}
+
+	erasure.Complete = len(erasure.Errors) == 0
+	if erasure.Complete {
+		log.Printf("[UserData] Erased data of %s, requested by %s", robloxUserID, requestedBy)
+	} else {
+		log.Printf("[UserData] Partially erased data of %s, requested by %s: %v", robloxUserID, requestedBy, erasure.Errors)
+	}
+	return erasure
+}
+
+// exportUser returns the user's inventory as stored and as buffered.
+func (s *InventoryService) exportUser(ctx context.Context, robloxUserID string) (model.UserGameData, error) {
+	var data model.UserGameData
+
+	if s.inventoryRepo != nil {
+		inv, err := s.inventoryRepo.GetInventory(ctx, robloxUserID)
+		if err != nil {
+			return data, err
+		}
+		if inv != nil {
+			data.Inventory = exportedInventory(inv)
+		}
+	}
+
+	buffered, _, err := s.bufferedInventory(ctx, robloxUserID)
+	if err != nil {
+		return data, err
+	}
+	if buffered != nil {
+		data.Buffered = exportedInventory(buffered)
+	}
+	return data, nil
+}
+
+// eraseUser deletes everything the repository holds about the user, then
+// their buffered inventory, then erases the repository again: a flush that
+// read the buffer before it was deleted may have written the user back in
+// between. A flush whose database write is still running after the second
+// erase is not caught; repeating the erasure removes what it wrote.
+func (s *InventoryService) eraseUser(ctx context.Context, robloxUserID string) (model.UserGameErasure, error) {
+	var result model.UserGameErasure
+
+	eraser, supported := s.inventoryRepo.(repository.InventoryEraser)
+	erase := func() error {
+		if s.inventoryRepo == nil {
+			return nil
+		}
+		if !supported {
+			return fmt.Errorf("erasure is not supported by this storage backend")
+		}
+		erased, err := eraser.EraseUser(ctx, robloxUserID)
+		result.Records += erased
+		return err
+	}
+
+	err := erase()
+	if s.buffer != nil {
+		removed, bufErr := s.buffer.Delete(ctx, robloxUserID)
+		if bufErr != nil {
+			return result, fmt.Errorf("failed to delete buffered inventory: %w", bufErr)
+		}
+		result.Buffered = removed
+		if err == nil {
+			err = erase()
+		}
+	}
+	return result, err
+}
+
+func exportedInventory(inv *model.RawInventory) *model.ExportedInventory {
+	return &model.ExportedInventory{
+		KeyAccountID: inv.KeyAccountID,
+		ContentHash:  inv.ContentHash,
+		SyncedAt:     inv.SyncedAt,
+		LastSeenAt:   inv.LastSeenAt,
+		Inventory:    inv.InventoryJSON,
+	}
+}
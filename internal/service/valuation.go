@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/catalog"
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/internal/repository"
+	"vinzhub-rest-api-v2/pkg/apierror"
+)
+
+// ValuationConfig holds price multipliers applied on top of sell prices.
+type ValuationConfig struct {
+	ShinyMultiplier    float64            // applied to shiny items; 0 or 1 disables
+	VariantMultipliers map[string]float64 // keyed by variant_id
+}
+
+// Valuator prices inventories. Sell prices come from the item catalog, falling
+// back to the price the client reported for items the catalog does not know.
+type Valuator struct {
+	catalog *catalog.Catalog
+	config  ValuationConfig
+}
+
+// NewValuator creates a valuator. c may be nil, in which case only client prices are used.
+func NewValuator(c *catalog.Catalog, cfg ValuationConfig) *Valuator {
+	return &Valuator{
+		catalog: c,
+		config:  cfg,
+	}
+}
+
+// Value computes the value of an inventory, with the topN most valuable items.
+func (v *Valuator) Value(payload *model.InventoryPayload, topN int) *model.InventoryValuation {
+	valuation := &model.InventoryValuation{
+		ByCategory: make(map[string]float64),
+		ByTier:     make(map[model.Tier]float64),
+		TopItems:   []model.ValuedItem{},
+		ComputedAt: time.Now(),
+	}
+
+	var items []model.ValuedItem
+	for _, category := range model.InventoryCategories {
+		for i := range payload.Category(category) {
+			item, priced := v.valueEntry(category, &payload.Category(category)[i])
+			valuation.ItemCount++
+			if !priced {
+				valuation.UnpricedItems++
+				continue
+			}
+			valuation.Total += item.Value
+			valuation.ByCategory[category] += item.Value
+			valuation.ByTier[item.Tier] += item.Value
+			items = append(items, item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Value > items[j].Value })
+	if len(items) > topN {
+		items = items[:topN]
+	}
+	valuation.TopItems = append(valuation.TopItems, items...)
+	return valuation
+}
+
+// Total returns the value of an inventory and the number of items in it.
+func (v *Valuator) Total(payload *model.InventoryPayload) (float64, int) {
+	valuation := v.Value(payload, 0)
+	return valuation.Total, valuation.ItemCount
+}
+
+// valueEntry prices a single entry. Reports false if no price is known.
+func (v *Valuator) valueEntry(category string, e *model.InventoryEntry) (model.ValuedItem, bool) {
+	item := model.ValuedItem{
+		Category:  category,
+		UUID:      e.UUID,
+		ItemID:    e.ID(),
+		Name:      e.Name,
+		Tier:      e.Tier,
+		Quantity:  e.Count(),
+		IsShiny:   e.IsShiny,
+		VariantID: string(e.VariantID),
+	}
+
+	price, source := e.Price, model.PriceSourcePayload
+	if v.catalog != nil {
+		if it := v.catalog.Get(item.ItemID); it != nil && it.SellPrice > 0 {
+			price, source = it.SellPrice, model.PriceSourceCatalog
+			if item.Name == "" {
+				item.Name = it.Name
+			}
+			if item.Tier == 0 {
+				item.Tier = it.Tier
+			}
+		}
+	}
+	if price <= 0 {
+		return item, false
+	}
+
+	if e.IsShiny && v.config.ShinyMultiplier > 0 {
+		price *= v.config.ShinyMultiplier
+	}
+	if m, ok := v.config.VariantMultipliers[item.VariantID]; ok && item.VariantID != "" && m > 0 {
+		price *= m
+	}
+
+	item.UnitPrice = price
+	item.Value = price * item.Quantity
+	item.PriceSource = source
+	return item, true
+}
+
+// NewValueRecorder returns a PersistHook that records each persisted
+// inventory's value, so value can be charted over time.
+func NewValueRecorder(repo repository.InventoryValueRepository, valuator *Valuator) PersistHook {
+	return func(ctx context.Context, items []model.InventoryItem) {
+		points := make([]model.ValuePoint, 0, len(items))
+		for _, item := range items {
+			payload, err := decodeStoredPayload(item.RawJSON)
+			if err != nil {
+				log.Printf("[ValueRecorder] Skipping %s: %v", item.RobloxUserID, err)
+				continue
+			}
+			total, count := valuator.Total(payload)
+			points = append(points, model.ValuePoint{
+				RobloxUserID: item.RobloxUserID,
+				Total:        total,
+				ItemCount:    count,
+				RecordedAt:   item.SyncedAt,
+			})
+		}
+
+		if err := repo.RecordValues(ctx, points); err != nil {
+			log.Printf("[ValueRecorder] Failed to record values: %v", err)
+		}
+	}
+}
+
+// SetValuator enables the inventory valuation endpoints.
+func (s *InventoryService) SetValuator(v *Valuator) {
+	s.valuator = v
+}
+
+// GetInventoryValue values a user's current inventory.
+func (s *InventoryService) GetInventoryValue(ctx context.Context, robloxUserID string, topN int) (*model.InventoryValuation, error) {
+	if s.valuator == nil {
+		return nil, apierror.ServiceUnavailable("inventory valuation is not enabled")
+	}
+
+	rawJSON, _, err := s.GetRawInventory(ctx, robloxUserID)
+	if err != nil {
+		return nil, err
+	}
+	if rawJSON == nil {
+		return nil, apierror.NotFound("inventory not found")
+	}
+
+	payload, err := decodeStoredPayload(rawJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := s.valuator.Value(payload, topN)
+	valuation.RobloxUserID = robloxUserID
+	return valuation, nil
+}
+
+// GetValueHistory returns a user's recorded inventory values in [from, to].
+func (s *InventoryService) GetValueHistory(ctx context.Context, robloxUserID string, from, to time.Time, limit int) ([]model.ValuePoint, error) {
+	valueRepo, ok := s.inventoryRepo.(repository.InventoryValueRepository)
+	if !ok || s.inventoryRepo == nil {
+		return nil, apierror.ServiceUnavailable("inventory value history is not supported by this storage backend")
+	}
+	return valueRepo.ListValues(ctx, robloxUserID, from, to, limit)
+}
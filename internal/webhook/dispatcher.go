@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/uid"
+)
+
+// Request headers sent with every delivery. The signature is the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook's secret.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxBackoff caps the delay between retries.
+const maxBackoff = 5 * time.Minute
+
+// Config holds delivery settings.
+type Config struct {
+	MaxAttempts int           // attempts per delivery, including the first
+	Backoff     time.Duration // delay before the first retry; doubles after each attempt
+	Timeout     time.Duration // per-attempt HTTP timeout
+	Workers     int
+	QueueSize   int
+	LogSize     int // attempts kept in the delivery log
+}
+
+// Attempt is one entry of the delivery log.
+type Attempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	WebhookID  string    `json:"webhook_id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	WillRetry  bool      `json:"will_retry"`
+	DurationMs int64     `json:"duration_ms"`
+	At         time.Time `json:"at"`
+}
+
+// delivery is one event on its way to one webhook.
+type delivery struct {
+	id      string
+	webhook Webhook
+	event   string
+	body    []byte
+	attempt int
+}
+
+// Dispatcher delivers events to the webhooks of a Store in the background,
+// retrying failed attempts with exponential backoff.
+type Dispatcher struct {
+	store  *Store
+	client *http.Client
+	cfg    Config
+	queue  chan *delivery
+
+	mu       sync.Mutex
+	attempts []Attempt // ring buffer, next points at the oldest entry once full
+	next     int
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher and starts its workers.
+func NewDispatcher(store *Store, cfg Config) *Dispatcher {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.LogSize < 1 {
+		cfg.LogSize = 500
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: cfg.Timeout},
+		cfg:    cfg,
+		queue:  make(chan *delivery, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	return d
+}
+
+// Notify queues the events for every enabled webhook subscribed to their
+// type. It never blocks; deliveries are dropped and logged if the queue is full.
+// Its signature matches service.EventHook.
+func (d *Dispatcher) Notify(ctx context.Context, events []model.InventoryEvent) {
+	webhooks := d.store.List()
+	for i := range events {
+		var body []byte
+		for _, w := range webhooks {
+			if !w.Wants(events[i].Type) {
+				continue
+			}
+			if body == nil {
+				var err error
+				if body, err = DiscordPayload(&events[i]); err != nil {
+					log.Printf("[Webhook] Failed to encode %s event: %v", events[i].Type, err)
+					break
+				}
+			}
+			d.enqueue(&delivery{id: uid.New(), webhook: w, event: events[i].Type, body: body})
+		}
+	}
+}
+
+// Test sends a sample event to a webhook once, without retries, and returns
+// the logged attempt. Returns nil if there is no such webhook.
+func (d *Dispatcher) Test(ctx context.Context, id string) (*Attempt, error) {
+	w := d.store.Get(id)
+	if w == nil {
+		return nil, nil
+	}
+	body, err := DiscordPayload(SampleEvent())
+	if err != nil {
+		return nil, err
+	}
+	attempt, _ := d.send(ctx, &delivery{id: uid.New(), webhook: *w, event: model.EventRareCatch, body: body, attempt: 1}, false)
+	return &attempt, nil
+}
+
+// Attempts returns up to limit logged attempts, newest first. A non-empty
+// webhookID limits them to one webhook.
+func (d *Dispatcher) Attempts(webhookID string, limit int) []Attempt {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]Attempt, 0, limit)
+	n := len(d.attempts)
+	for i := 0; i < n && len(result) < limit; i++ {
+		// Walk backwards from the newest entry
+		a := d.attempts[(d.next-1-i+n)%n]
+		if webhookID == "" || a.WebhookID == webhookID {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// Close stops the workers. Queued deliveries and pending retries are dropped.
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) enqueue(del *delivery) {
+	select {
+	case <-d.ctx.Done():
+	case d.queue <- del:
+	default:
+		log.Printf("[Webhook] Queue full, dropping %s delivery %s to %s", del.event, del.id, del.webhook.ID)
+	}
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case del := <-d.queue:
+			del.attempt++
+			_, retryAfter := d.send(d.ctx, del, del.attempt < d.cfg.MaxAttempts)
+			if retryAfter > 0 {
+				time.AfterFunc(retryAfter, func() { d.enqueue(del) })
+			}
+		}
+	}
+}
+
+// send makes one delivery attempt and logs it. If retry is set and the
+// attempt failed in a way worth retrying, it returns the delay before the
+// next attempt.
+func (d *Dispatcher) send(ctx context.Context, del *delivery, retry bool) (Attempt, time.Duration) {
+	attempt := Attempt{
+		DeliveryID: del.id,
+		WebhookID:  del.webhook.ID,
+		Event:      del.event,
+		Attempt:    del.attempt,
+		At:         time.Now().UTC(),
+	}
+
+	retryable := true
+	var retryAfter time.Duration
+	req, err := newRequest(ctx, del)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = d.client.Do(req); err == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			attempt.StatusCode = resp.StatusCode
+			attempt.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+			if !attempt.Success {
+				err = fmt.Errorf("receiver responded %s", resp.Status)
+				// Client errors other than rate limiting will not go away on their own
+				retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+				if secs, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && secs > 0 {
+					retryAfter = time.Duration(secs) * time.Second
+				}
+			}
+		}
+	} else {
+		retryable = false
+	}
+	attempt.DurationMs = time.Since(attempt.At).Milliseconds()
+
+	var next time.Duration
+	if err != nil {
+		attempt.Error = err.Error()
+		if retry && retryable {
+			next = d.cfg.Backoff << (del.attempt - 1)
+			if next <= 0 || next > maxBackoff {
+				next = maxBackoff
+			}
+			if retryAfter > next {
+				next = retryAfter
+			}
+			attempt.WillRetry = true
+		}
+		log.Printf("[Webhook] Delivery %s of %s to %s failed (attempt %d, retry=%v): %v",
+			del.id, del.event, del.webhook.ID, del.attempt, attempt.WillRetry, err)
+	}
+
+	d.record(attempt)
+	return attempt, next
+}
+
+func (d *Dispatcher) record(a Attempt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.attempts) < d.cfg.LogSize {
+		d.attempts = append(d.attempts, a)
+		d.next = len(d.attempts) % d.cfg.LogSize
+		return
+	}
+	d.attempts[d.next] = a
+	d.next = (d.next + 1) % d.cfg.LogSize
+}
+
+// newRequest builds the signed POST request of a delivery attempt.
+func newRequest(ctx context.Context, del *delivery) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.webhook.URL, bytes.NewReader(del.body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vinzhub-webhooks/1")
+	req.Header.Set(HeaderEvent, del.event)
+	req.Header.Set(HeaderDelivery, del.id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(del.webhook.Secret, timestamp, del.body))
+	return req, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret.
+// Receivers compare it with the X-Webhook-Signature header.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
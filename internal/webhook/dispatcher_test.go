@@ -0,0 +1,157 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+func TestSign(t *testing.T) {
+	// Computed independently: HMAC-SHA256 keyed with the secret over "<timestamp>.<body>"
+	tests := []struct {
+		secret, timestamp, body, want string
+	}{
+		{"whsec_test", "1700000000", `{"content":"Secret catch!"}`, "bfd02589f01c475ed1abc14c39e274f6556dd245d46fe9049bf22b30cb84977c"},
+		{"whsec_test", "1700000000", "", "5967f3c560522fa40cf2876ebc3c3a08551dd6959aaade3b413460591895bdcc"},
+	}
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+			t.Errorf("Sign(%q, %q, %q) = %s; want %s", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+	}
+}
+
+// received is a request seen by a test receiver.
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver starts a server answering deliveries with the given status codes
+// in turn, repeating the last one.
+func receiver(t *testing.T, codes ...int) (*httptest.Server, <-chan received) {
+	t.Helper()
+	requests := make(chan received, 10)
+	var mu sync.Mutex
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		code := codes[min(n, len(codes)-1)]
+		n++
+		mu.Unlock()
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+// notify registers a webhook for url and sends it a rare catch through a
+// dispatcher making up to maxAttempts attempts.
+func notify(t *testing.T, url string, maxAttempts int) (*Dispatcher, *Webhook) {
+	t.Helper()
+	store := NewStore("")
+	hook, err := store.Create(Webhook{URL: url, Secret: "whsec_test"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	d := NewDispatcher(store, Config{MaxAttempts: maxAttempts, Backoff: 10 * time.Millisecond, Timeout: time.Second})
+	t.Cleanup(d.Close)
+	d.Notify(context.Background(), []model.InventoryEvent{*SampleEvent()})
+	return d, hook
+}
+
+func waitRequest(t *testing.T, requests <-chan received) received {
+	t.Helper()
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a delivery")
+		return received{}
+	}
+}
+
+// waitAttempts waits until the dispatcher has logged n attempts and returns them, newest first.
+func waitAttempts(t *testing.T, d *Dispatcher, n int) []Attempt {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		attempts := d.Attempts("", 100)
+		if len(attempts) >= n || time.Now().After(deadline) {
+			return attempts
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeliveryRetriesServerErrors(t *testing.T) {
+	srv, requests := receiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	d, hook := notify(t, srv.URL, 3)
+
+	var deliveryID string
+	for i := 0; i < 3; i++ {
+		r := waitRequest(t, requests)
+		if got := r.header.Get(HeaderEvent); got != model.EventRareCatch {
+			t.Errorf("attempt %d: event = %q; want %q", i+1, got, model.EventRareCatch)
+		}
+		want := "sha256=" + Sign(hook.Secret, r.header.Get(HeaderTimestamp), r.body)
+		if got := r.header.Get(HeaderSignature); got != want {
+			t.Errorf("attempt %d: signature = %q; want %q", i+1, got, want)
+		}
+		if i == 0 {
+			deliveryID = r.header.Get(HeaderDelivery)
+		} else if got := r.header.Get(HeaderDelivery); got != deliveryID {
+			t.Errorf("attempt %d: delivery = %q; want the first attempt's %q", i+1, got, deliveryID)
+		}
+	}
+
+	attempts := waitAttempts(t, d, 3)
+	if len(attempts) != 3 {
+		t.Fatalf("logged %d attempts; want 3", len(attempts))
+	}
+	wantCodes := []int{http.StatusOK, http.StatusBadGateway, http.StatusInternalServerError}
+	for i, a := range attempts {
+		if a.Attempt != 3-i || a.StatusCode != wantCodes[i] {
+			t.Errorf("attempts[%d] = attempt %d, status %d; want attempt %d, status %d", i, a.Attempt, a.StatusCode, 3-i, wantCodes[i])
+		}
+		if last := i == 0; a.Success != last || a.WillRetry == last {
+			t.Errorf("attempts[%d]: success %v, will retry %v", i, a.Success, a.WillRetry)
+		}
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        int
+		maxAttempts int
+		want        int
+	}{
+		{"client error is not retried", http.StatusBadRequest, 3, 1},
+		{"server error up to max attempts", http.StatusServiceUnavailable, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := receiver(t, tt.code)
+			d, _ := notify(t, srv.URL, tt.maxAttempts)
+
+			attempts := waitAttempts(t, d, tt.want)
+			// Room for a retry that should not happen
+			time.Sleep(100 * time.Millisecond)
+			if n := len(requests); n != tt.want || len(attempts) != tt.want {
+				t.Fatalf("receiver got %d requests, %d logged; want %d", n, len(attempts), tt.want)
+			}
+			if a := attempts[0]; a.Success || a.WillRetry || a.StatusCode != tt.code {
+				t.Errorf("last attempt = %+v; want a failure with status %d and no retry", a, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+)
+
+// Embed colors by tier; tiers without one use defaultColor.
+var tierColors = map[model.Tier]int{
+	6:                0xE91E63, // Mythical
+	model.TierSecret: 0x1ABC9C,
+	model.MaxTier:    0xF1C40F, // Exotic
+}
+
+const defaultColor = 0x5865F2
+
+// discordMessage is the subset of Discord's webhook message format sent.
+// Other receivers get the same JSON and can read the event from the embed
+// fields and the X-Webhook-Event header.
+type discordMessage struct {
+	Username string         `json:"username"`
+	Content  string         `json:"content"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title     string         `json:"title"`
+	Color     int            `json:"color"`
+	Fields    []discordField `json:"fields"`
+	Timestamp string         `json:"timestamp"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordPayload encodes an event as a Discord webhook message.
+func DiscordPayload(event *model.InventoryEvent) ([]byte, error) {
+	tier := model.Tier(event.Tier)
+	tierName := tier.Name()
+	if tierName == "" {
+		tierName = "Tier " + strconv.Itoa(event.Tier)
+	}
+	name := event.Name
+	if name == "" {
+		name = "item " + strconv.FormatInt(event.ItemID, 10)
+	}
+
+	var content, title string
+	switch event.Type {
+	case model.EventRareCatch:
+		content = fmt.Sprintf("Player %s caught a %s %s!", event.RobloxUserID, tierName, name)
+		title = "Rare catch: " + name
+	case model.EventCoinsChanged, model.EventLevelUp:
+		content = fmt.Sprintf("Player %s: %s", event.RobloxUserID, event.Type)
+		title = event.Type
+	default:
+		content = fmt.Sprintf("Player %s: %s %s", event.RobloxUserID, event.Type, name)
+		title = event.Type + ": " + name
+	}
+
+	fields := []discordField{
+		{Name: "Event", Value: event.Type, Inline: true},
+		{Name: "Player", Value: event.RobloxUserID, Inline: true},
+	}
+	if event.Before != nil && event.After != nil {
+		fields = append(fields,
+			discordField{Name: "Before", Value: strconv.FormatFloat(*event.Before, 'f', -1, 64), Inline: true},
+			discordField{Name: "After", Value: strconv.FormatFloat(*event.After, 'f', -1, 64), Inline: true},
+		)
+	} else {
+		fields = append(fields,
+			discordField{Name: "Item", Value: name, Inline: true},
+			discordField{Name: "Tier", Value: fmt.Sprintf("%s (%d)", tierName, event.Tier), Inline: true},
+			discordField{Name: "Category", Value: event.Category, Inline: true},
+		)
+		if event.UUID != "" {
+			fields = append(fields, discordField{Name: "UUID", Value: event.UUID})
+		}
+	}
+
+	color, ok := tierColors[tier]
+	if !ok {
+		color = defaultColor
+	}
+	return json.Marshal(discordMessage{
+		Username: "VinzHub",
+		Content:  content,
+		Embeds: []discordEmbed{{
+			Title:     title,
+			Color:     color,
+			Fields:    fields,
+			Timestamp: event.CreatedAt.UTC().Format(time.RFC3339),
+		}},
+	})
+}
+
+// SampleEvent returns the rare_catch event sent by webhook tests.
+func SampleEvent() *model.InventoryEvent {
+	return &model.InventoryEvent{
+		RobloxUserID: "1",
+		Type:         model.EventRareCatch,
+		Category:     "fish",
+		UUID:         "00000000-0000-0000-0000-000000000000",
+		Name:         "Test Fish",
+		Tier:         int(model.TierSecret),
+		Quantity:     1,
+		CreatedAt:    time.Now().UTC(),
+	}
+}
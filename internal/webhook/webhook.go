@@ -0,0 +1,273 @@
+// Package webhook delivers inventory events to registered HTTP endpoints.
+// Webhooks are kept in memory and saved to a JSON file on every admin
+// change; payloads are Discord-compatible and signed with each webhook's
+// secret.
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"vinzhub-rest-api-v2/internal/model"
+	"vinzhub-rest-api-v2/pkg/apierror"
+	"vinzhub-rest-api-v2/pkg/uid"
+)
+
+// Webhook is a registered event receiver.
+type Webhook struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // HMAC key; generated if not given
+	Events    []string  `json:"events"`           // event types delivered; defaults to rare_catch
+	Disabled  bool      `json:"disabled,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks a webhook before it is saved and fills in default events.
+func (w *Webhook) Validate() error {
+	var details []apierror.FieldError
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		details = append(details, apierror.FieldError{Field: "url", Message: "must be an absolute http or https URL"})
+	}
+	if len(w.Events) == 0 {
+		w.Events = []string{model.EventRareCatch}
+	}
+	for _, event := range w.Events {
+		if !model.IsInventoryEventType(event) {
+			details = append(details, apierror.FieldError{Field: "events", Message: "unknown event type: " + event})
+		}
+	}
+	if len(details) > 0 {
+		return apierror.ValidationError("invalid webhook", details...)
+	}
+	return nil
+}
+
+// Wants reports whether the webhook receives events of the given type.
+func (w *Webhook) Wants(eventType string) bool {
+	if w.Disabled {
+		return false
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy of the webhook without its secret, for listings.
+func (w Webhook) Redacted() Webhook {
+	if w.Secret != "" {
+		w.Secret = "********"
+	}
+	return w
+}
+
+// newSecret returns a random signing secret.
+func newSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Store is a concurrency-safe set of webhooks saved to a JSON file.
+type Store struct {
+	mu       sync.RWMutex
+	webhooks map[string]*Webhook
+	path     string // file changes are saved to; empty keeps them in memory only
+}
+
+// NewStore creates an empty store that saves changes to path.
+func NewStore(path string) *Store {
+	return &Store{
+		webhooks: make(map[string]*Webhook),
+		path:     path,
+	}
+}
+
+// LoadStore reads webhooks from a JSON file. A missing file yields an empty
+// store that will be created on the first change.
+func LoadStore(path string) (*Store, error) {
+	s := NewStore(path)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+
+	var list []Webhook
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to load webhooks %s: %w", path, err)
+	}
+	for i := range list {
+		s.webhooks[list[i].ID] = &list[i]
+	}
+
+	log.Printf("[Webhook] Loaded %d webhooks from %s", len(list), path)
+	return s, nil
+}
+
+// List returns copies of all webhooks ordered by creation time.
+func (s *Store) List() []Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Webhook, 0, len(s.webhooks))
+	for _, w := range s.webhooks {
+		list = append(list, *w)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].CreatedAt.Before(list[j].CreatedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Get returns a copy of the webhook with the given ID, or nil.
+func (s *Store) Get(id string) *Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, ok := s.webhooks[id]
+	if !ok {
+		return nil
+	}
+	copied := *w
+	return &copied
+}
+
+// Create validates and adds a webhook, assigning its ID and, if empty, its secret.
+func (s *Store) Create(w Webhook) (*Webhook, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	w.ID = uid.New()
+	if strings.TrimSpace(w.Secret) == "" {
+		w.Secret = newSecret()
+	}
+	w.CreatedAt = time.Now().UTC()
+	w.UpdatedAt = w.CreatedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.save(s.with(w.ID, &w)); err != nil {
+		return nil, err
+	}
+	s.webhooks[w.ID] = &w
+	copied := w
+	return &copied, nil
+}
+
+// Update replaces the webhook with the given ID, keeping its secret if w has
+// none. Returns nil if there is no such webhook.
+func (s *Store) Update(id string, w Webhook) (*Webhook, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.webhooks[id]
+	if !ok {
+		return nil, nil
+	}
+	w.ID = id
+	if strings.TrimSpace(w.Secret) == "" {
+		w.Secret = current.Secret
+	}
+	w.CreatedAt = current.CreatedAt
+	w.UpdatedAt = time.Now().UTC()
+
+	if err := s.save(s.with(id, &w)); err != nil {
+		return nil, err
+	}
+	s.webhooks[id] = &w
+	copied := w
+	return &copied, nil
+}
+
+// Delete removes a webhook. Reports whether it existed.
+func (s *Store) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return false, nil
+	}
+	if err := s.save(s.with(id, nil)); err != nil {
+		return false, err
+	}
+	delete(s.webhooks, id)
+	return true, nil
+}
+
+// with returns the webhooks with id set to w, or removed if w is nil.
+// Called with mu held.
+func (s *Store) with(id string, w *Webhook) map[string]*Webhook {
+	next := make(map[string]*Webhook, len(s.webhooks)+1)
+	for existing, hook := range s.webhooks {
+		next[existing] = hook
+	}
+	if w == nil {
+		delete(next, id)
+	} else {
+		next[id] = w
+	}
+	return next
+}
+
+// save writes webhooks to the store file atomically. Called with mu held.
+func (s *Store) save(webhooks map[string]*Webhook) error {
+	if s.path == "" {
+		return nil
+	}
+
+	list := make([]Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		list = append(list, *w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(list); err != nil {
+		return fmt.Errorf("failed to encode webhooks: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create webhook directory: %w", err)
+	}
+	// Secrets are stored in the file, so keep it private
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write webhooks: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace webhooks: %w", err)
+	}
+	return nil
+}
@@ -112,6 +112,42 @@ func Conflict(message string) *Error {
 	}
 }
 
+// PreconditionFailed creates a 412 Precondition Failed error.
+func PreconditionFailed(message string) *Error {
+	return &Error{
+		StatusCode: http.StatusPreconditionFailed,
+		Code:       "PRECONDITION_FAILED",
+		Message:    message,
+	}
+}
+
+// PreconditionRequired creates a 428 Precondition Required error.
+func PreconditionRequired(message string) *Error {
+	return &Error{
+		StatusCode: http.StatusPreconditionRequired,
+		Code:       "PRECONDITION_REQUIRED",
+		Message:    message,
+	}
+}
+
+// PayloadTooLarge creates a 413 Payload Too Large error.
+func PayloadTooLarge(message string) *Error {
+	return &Error{
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Code:       "PAYLOAD_TOO_LARGE",
+		Message:    message,
+	}
+}
+
+// UnsupportedMediaType creates a 415 Unsupported Media Type error.
+func UnsupportedMediaType(message string) *Error {
+	return &Error{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Code:       "UNSUPPORTED_MEDIA_TYPE",
+		Message:    message,
+	}
+}
+
 // InternalError creates a 500 Internal Server Error.
 func InternalError(message string) *Error {
 	if message == "" {
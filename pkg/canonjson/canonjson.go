@@ -0,0 +1,40 @@
+// Package canonjson produces a canonical form of JSON documents so that
+// semantically equal documents can be compared and hashed byte for byte.
+package canonjson
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Canonicalize re-encodes a JSON document with sorted object keys, no
+// insignificant whitespace and numbers kept exactly as written.
+func Canonicalize(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// Hash returns the hex SHA-256 of the canonical form of raw.
+func Hash(raw []byte) (string, error) {
+	canonical, err := Canonicalize(raw)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,183 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "add appends with -",
+			doc:   `{"fish":[1,2]}`,
+			patch: `[{"op":"add","path":"/fish/-","value":3}]`,
+			want:  `{"fish":[1,2,3]}`,
+		},
+		{
+			name:  "add inserts at index",
+			doc:   `{"fish":[1,3]}`,
+			patch: `[{"op":"add","path":"/fish/1","value":2}]`,
+			want:  `{"fish":[1,2,3]}`,
+		},
+		{
+			name:    "add past the end",
+			doc:     `{"fish":[1]}`,
+			patch:   `[{"op":"add","path":"/fish/2","value":2}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:  "escaped ~1 is a slash",
+			doc:   `{"a/b":1}`,
+			patch: `[{"op":"replace","path":"/a~1b","value":2}]`,
+			want:  `{"a/b":2}`,
+		},
+		{
+			name:  "escaped ~0 is a tilde",
+			doc:   `{"a~b":1}`,
+			patch: `[{"op":"remove","path":"/a~0b"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "~01 is a literal ~1",
+			doc:   `{"~1":1,"/":2}`,
+			patch: `[{"op":"remove","path":"/~01"}]`,
+			want:  `{"/":2}`,
+		},
+		{
+			name:  "remove from array",
+			doc:   `{"fish":[1,2,3]}`,
+			patch: `[{"op":"remove","path":"/fish/0"}]`,
+			want:  `{"fish":[2,3]}`,
+		},
+		{
+			name:    "replace missing path",
+			doc:     `{}`,
+			patch:   `[{"op":"replace","path":"/coins","value":1}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:  "move",
+			doc:   `{"a":{"x":1},"b":{}}`,
+			patch: `[{"op":"move","from":"/a/x","path":"/b/y"}]`,
+			want:  `{"a":{},"b":{"y":1}}`,
+		},
+		{
+			name:    "move into own child",
+			doc:     `{"a":{"b":{}}}`,
+			patch:   `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:  "copy does not alias the source",
+			doc:   `{"a":{"x":1}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"/b/x","value":2}]`,
+			want:  `{"a":{"x":1},"b":{"x":2}}`,
+		},
+		{
+			name:  "passing test",
+			doc:   `{"coins":100,"fish":[{"id":1}]}`,
+			patch: `[{"op":"test","path":"/fish/0","value":{"id":1}},{"op":"replace","path":"/coins","value":1e2}]`,
+			want:  `{"coins":1e2,"fish":[{"id":1}]}`,
+		},
+		{
+			name:    "failing test",
+			doc:     `{"coins":100}`,
+			patch:   `[{"op":"test","path":"/coins","value":99}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:    "failing test after writes applies nothing",
+			doc:     `{"coins":100}`,
+			patch:   `[{"op":"replace","path":"/coins","value":1},{"op":"test","path":"/coins","value":100}]`,
+			wantErr: ErrTestFailed,
+		},
+		{
+			name:  "numbers kept as written",
+			doc:   `{"big":12345678901234567890,"f":1.50}`,
+			patch: `[{"op":"add","path":"/n","value":0.10}]`,
+			want:  `{"big":12345678901234567890,"f":1.50,"n":0.10}`,
+		},
+		{
+			name:    "unknown op",
+			doc:     `{}`,
+			patch:   `[{"op":"frob","path":"/a"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "patch not an array",
+			doc:     `{}`,
+			patch:   `{"op":"add","path":"/a","value":1}`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "path without leading slash",
+			doc:     `{}`,
+			patch:   `[{"op":"add","path":"a","value":1}]`,
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name:    "leading zero index",
+			doc:     `{"fish":[1,2]}`,
+			patch:   `[{"op":"remove","path":"/fish/01"}]`,
+			wantErr: ErrInvalidPatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Apply = %s, %v; want %v", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Apply = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{"null deletes", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{"null deletes nested", `{"a":{"x":1,"y":2}}`, `{"a":{"x":null}}`, `{"a":{"y":2}}`},
+		{"null for a missing key", `{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{"replace value", `{"a":1}`, `{"a":2}`, `{"a":2}`},
+		{"arrays are replaced whole", `{"fish":[1,2,3]}`, `{"fish":[4]}`, `{"fish":[4]}`},
+		{"object replaces scalar", `{"a":1}`, `{"a":{"b":null,"c":1}}`, `{"a":{"c":1}}`},
+		{"non-object patch replaces the document", `{"a":1}`, `[1]`, `[1]`},
+		{"empty patch", `{"a":1}`, `{}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("MergePatch: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MergePatch = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	if _, err := MergePatch([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("MergePatch of invalid JSON = %v; want ErrInvalidPatch", err)
+	}
+}